@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"EchoAuth/models"
+	"encoding/json"
+)
+
+// Store is the persistence surface DBLogger needs;
+// repositories.AuditLogRepository satisfies it.
+type Store interface {
+	Create(log *models.AuditLog) error
+}
+
+// DBLogger persists each Event as a row in the durable audit_logs table, so
+// a user's own history can be read back out via GET /me/audit regardless of
+// which other sink (stdout/file/redis) is also configured for live tailing
+// or shipping to a log collector.
+type DBLogger struct {
+	store Store
+}
+
+func NewDBLogger(store Store) *DBLogger {
+	return &DBLogger{store: store}
+}
+
+func (l *DBLogger) Log(event Event) error {
+	var userID *uint
+	if event.UserID != 0 {
+		id := event.UserID
+		userID = &id
+	}
+
+	var metadata string
+	if event.Reason != "" {
+		encoded, err := json.Marshal(map[string]string{"reason": event.Reason})
+		if err != nil {
+			return err
+		}
+		metadata = string(encoded)
+	}
+
+	return l.store.Create(&models.AuditLog{
+		EventType: string(event.Type),
+		UserID:    userID,
+		Email:     event.Email,
+		IP:        event.IP,
+		UserAgent: event.DeviceInfo,
+		Success:   event.Outcome == OutcomeSuccess,
+		Metadata:  metadata,
+	})
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"testing"
+
+	"EchoAuth/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	logs []*models.AuditLog
+}
+
+func (s *fakeStore) Create(log *models.AuditLog) error {
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+func TestDBLoggerPersistsEvent(t *testing.T) {
+	store := &fakeStore{}
+	logger := NewDBLogger(store)
+
+	event := NewEvent(EventLogin, OutcomeSuccess, 7, "127.0.0.1", "test-device", "", "corr-1")
+	event.Email = "user@example.com"
+
+	require.NoError(t, logger.Log(event))
+	require.Len(t, store.logs, 1)
+
+	got := store.logs[0]
+	assert.Equal(t, string(EventLogin), got.EventType)
+	require.NotNil(t, got.UserID)
+	assert.Equal(t, uint(7), *got.UserID)
+	assert.Equal(t, "user@example.com", got.Email)
+	assert.True(t, got.Success)
+	assert.Empty(t, got.Metadata)
+}
+
+func TestDBLoggerEncodesReasonAsMetadata(t *testing.T) {
+	store := &fakeStore{}
+	logger := NewDBLogger(store)
+
+	event := NewEvent(EventLogin, OutcomeFailure, 0, "127.0.0.1", "test-device", "invalid credentials", "corr-2")
+
+	require.NoError(t, logger.Log(event))
+	require.Len(t, store.logs, 1)
+
+	got := store.logs[0]
+	assert.Nil(t, got.UserID)
+	assert.False(t, got.Success)
+	assert.JSONEq(t, `{"reason":"invalid credentials"}`, got.Metadata)
+}
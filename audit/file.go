@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileLogger appends each Event as a line of JSON to a file, rotating it to
+// a ".1" suffix once it grows past maxSizeBytes so a long-running process
+// doesn't grow one audit log file without bound.
+type FileLogger struct {
+	mu           sync.Mutex
+	path         string
+	maxSizeBytes int64
+	file         *os.File
+}
+
+// NewFileLogger opens (creating if necessary) the audit log at path.
+func NewFileLogger(path string, maxSizeBytes int64) (*FileLogger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log file: %w", err)
+	}
+	return &FileLogger{path: path, maxSizeBytes: maxSizeBytes, file: file}, nil
+}
+
+func (l *FileLogger) Log(event Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.rotateIfNeeded(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// rotateIfNeeded renames the current file to path+".1" and reopens path
+// fresh once it's grown past maxSizeBytes. maxSizeBytes <= 0 disables
+// rotation.
+func (l *FileLogger) rotateIfNeeded() error {
+	if l.maxSizeBytes <= 0 {
+		return nil
+	}
+
+	info, err := l.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < l.maxSizeBytes {
+		return nil
+	}
+
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	return nil
+}
+
+// Close closes the underlying file.
+func (l *FileLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
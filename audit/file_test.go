@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileLoggerAppendsEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := NewFileLogger(path, 0)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	require.NoError(t, logger.Log(NewEvent(EventLogin, OutcomeSuccess, 1, "127.0.0.1", "test-device", "", "corr-1")))
+	require.NoError(t, logger.Log(NewEvent(EventLogout, OutcomeSuccess, 1, "127.0.0.1", "test-device", "", "corr-2")))
+
+	lines := readLines(t, path)
+	assert.Len(t, lines, 2)
+}
+
+func TestFileLoggerRotatesPastMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := NewFileLogger(path, 1)
+	require.NoError(t, err)
+	defer logger.Close()
+
+	require.NoError(t, logger.Log(NewEvent(EventLogin, OutcomeSuccess, 1, "127.0.0.1", "test-device", "", "corr-1")))
+	require.NoError(t, logger.Log(NewEvent(EventLogin, OutcomeSuccess, 1, "127.0.0.1", "test-device", "", "corr-2")))
+
+	_, err = os.Stat(path + ".1")
+	assert.NoError(t, err, "expected the first file to have been rotated to a .1 suffix")
+
+	lines := readLines(t, path)
+	assert.Len(t, lines, 1, "expected only the event written after rotation in the active file")
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
@@ -0,0 +1,34 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamKey is the Redis stream RedisLogger XADDs events to, and that
+// controllers.AuditController XREADs for live tailing.
+const StreamKey = "auth:audit:events"
+
+// RedisLogger XADDs each Event, JSON-encoded, to the StreamKey stream.
+type RedisLogger struct {
+	client *redis.Client
+}
+
+func NewRedisLogger(client *redis.Client) *RedisLogger {
+	return &RedisLogger{client: client}
+}
+
+func (l *RedisLogger) Log(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	return l.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: StreamKey,
+		Values: map[string]interface{}{"event": string(payload)},
+	}).Err()
+}
@@ -0,0 +1,22 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// StdoutLogger writes each Event as a single line of JSON to an io.Writer
+// (os.Stdout by default), so it can be picked up by whatever log collector
+// already tails the process's stdout.
+type StdoutLogger struct {
+	out io.Writer
+}
+
+func NewStdoutLogger() *StdoutLogger {
+	return &StdoutLogger{out: os.Stdout}
+}
+
+func (l *StdoutLogger) Log(event Event) error {
+	return json.NewEncoder(l.out).Encode(event)
+}
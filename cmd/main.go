@@ -1,55 +1,299 @@
 package main
 
 import (
+	"EchoAuth/audit"
 	"EchoAuth/config"
 	"EchoAuth/controllers"
 	"EchoAuth/database"
+	"EchoAuth/grpcserver"
+	"EchoAuth/keys"
+	"EchoAuth/lifecycle"
+	"EchoAuth/mailer"
 	"EchoAuth/middlewares"
 	"EchoAuth/models"
+	"EchoAuth/password"
+	echoauthv1 "EchoAuth/proto/echoauth/v1"
 	"EchoAuth/repositories"
 	"EchoAuth/services"
+	echotls "EchoAuth/tls"
 	"EchoAuth/utils/logger"
+	"EchoAuth/utils/metrics"
 	"context"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 	"gorm.io/gorm"
 )
 
 // Dependencies holds all service dependencies
 type Dependencies struct {
-	UserRepo    repositories.UserRepository
-	TokenRepo   *repositories.TokenRepository
-	LockoutSvc  *services.AccountLockoutService
-	AuthService controllers.AuthService
-	RedisClient *redis.Client
-	DB          *gorm.DB
+	UserRepo            repositories.UserRepository
+	TokenRepo           *repositories.TokenRepository
+	ActionTokenRepo     repositories.ActionTokenRepository
+	LockoutSvc          *services.AccountLockoutService
+	AuthService         controllers.AuthService
+	AuthServiceImpl     *services.AuthService
+	SessionService      controllers.SessionService
+	SchemeRegistry      *services.SchemeRegistry
+	ConnectorManager    *services.ConnectorManager
+	TokenIssuer         controllers.TokenIssuer
+	ResetService        controllers.PasswordResetServiceInterface
+	VerificationService controllers.EmailVerificationServiceInterface
+	InvitationService   controllers.InvitationServiceInterface
+	MFAService          controllers.MFAServiceInterface
+	KeyManager          keys.KeyManager
+	AuditLogger         audit.Logger
+	AuditLogRepo        repositories.AuditLogRepository
+	RedisClient         *redis.Client
+	DB                  *gorm.DB
+	RawDB               *database.DB
+	Config              *config.Config
 }
 
 // NewDependencies creates a new Dependencies instance
-func NewDependencies(db *gorm.DB, redisClient *redis.Client, cfg *config.Config) *Dependencies {
+func NewDependencies(db *gorm.DB, rawDB *database.DB, redisClient *redis.Client, cfg *config.Config) *Dependencies {
+	passwordPolicy, err := buildPasswordPolicy(cfg)
+	if err != nil {
+		logger.GetLogger("main").Fatal().Err(err).Msg("Failed to configure password hashing policy")
+	}
+	models.SetPasswordHasher(passwordPolicy)
+
+	keyRepo := repositories.NewKeySetRepository(rawDB, []byte(cfg.KeyEncryptionKey))
+	keyManager, err := keys.NewKeyManager(keyRepo, cfg.JWTExpiry, cfg.SigningKeyAlgorithm)
+	if err != nil {
+		logger.GetLogger("main").Fatal().Err(err).Msg("Failed to initialize signing key manager")
+	}
+	keyManager.StartRotation(cfg.JWTKeyRotation, make(chan struct{}), func(rotErr error) {
+		logger.GetLogger("main").Error().Err(rotErr).Msg("Failed to rotate signing keys")
+	})
+
 	userRepo := repositories.NewUserRepository(db)
 	tokenRepo := repositories.NewTokenRepository(db)
+	tokenRepo.SetMaxSimultaneousSessions(cfg.MaxSimultaneousSessions)
 	lockoutSvc := services.NewAccountLockoutService(redisClient)
-	authService := services.NewAuthService(userRepo, tokenRepo, cfg, lockoutSvc)
+	authService := services.NewAuthService(userRepo, tokenRepo, cfg, lockoutSvc, redisClient, keyManager)
+	authService.SetPasswordHistoryRepo(repositories.NewPasswordHistoryRepository(rawDB))
+	authService.SetTokenRevocationService(services.NewTokenRevocationService(redisClient))
+
+	remoteIdentityRepo := repositories.NewRemoteIdentityRepository(rawDB, []byte(cfg.KeyEncryptionKey))
+	connectorManager := services.NewConnectorManager(redisClient)
+	schemeRegistry := buildSchemeRegistry(cfg, userRepo, remoteIdentityRepo, connectorManager)
+
+	actionTokenRepo := repositories.NewActionTokenRepository(rawDB)
+	authService.SetActionTokenRepo(actionTokenRepo)
+
+	mfaService := services.NewMFAService(userRepo, []byte(cfg.KeyEncryptionKey))
+	authService.SetMFAService(mfaService)
+
+	auditLogRepo := repositories.NewAuditLogRepository(rawDB)
+	auditLogger := buildAuditLogger(cfg, redisClient, auditLogRepo, logger.GetLogger("audit"))
+
+	mailerInstance := mailer.NewAsyncMailer(buildMailer(cfg), redisClient,
+		cfg.Mailer.Workers, cfg.Mailer.QueueSize, cfg.Mailer.MaxAttempts, cfg.Mailer.BaseBackoff)
+	resetService := services.NewPasswordResetService(userRepo, actionTokenRepo, mailerInstance, cfg.PublicURL)
+	resetService.SetPasswordPolicy(repositories.NewPasswordHistoryRepository(rawDB), cfg.PasswordMaxAge)
+	verificationService := services.NewEmailVerificationService(userRepo, actionTokenRepo, mailerInstance, cfg.PublicURL)
+	authService.SetEmailVerificationService(verificationService)
+	invitationService := services.NewInvitationService(userRepo, actionTokenRepo, mailerInstance, cfg.PublicURL)
 
 	return &Dependencies{
-		UserRepo:    userRepo,
-		TokenRepo:   tokenRepo,
-		LockoutSvc:  lockoutSvc,
-		AuthService: authService,
-		RedisClient: redisClient,
-		DB:          db,
+		UserRepo:            userRepo,
+		TokenRepo:           tokenRepo,
+		ActionTokenRepo:     actionTokenRepo,
+		LockoutSvc:          lockoutSvc,
+		AuthService:         authService,
+		AuthServiceImpl:     authService,
+		SessionService:      services.NewSessionService(tokenRepo),
+		SchemeRegistry:      schemeRegistry,
+		ConnectorManager:    connectorManager,
+		TokenIssuer:         authService,
+		ResetService:        resetService,
+		VerificationService: verificationService,
+		InvitationService:   invitationService,
+		MFAService:          authService,
+		KeyManager:          keyManager,
+		AuditLogger:         auditLogger,
+		AuditLogRepo:        auditLogRepo,
+		RedisClient:         redisClient,
+		DB:                  db,
+		RawDB:               rawDB,
+		Config:              cfg,
+	}
+}
+
+// buildMailer picks a mailer.Transport for cfg.EmailTransport and wraps it
+// in a TemplatedMailer, falling back to a NoopMailer (which just logs) when
+// the selected transport isn't configured, so local/dev environments keep
+// working without a mail relay.
+func buildMailer(cfg *config.Config) mailer.Mailer {
+	switch cfg.EmailTransport {
+	case config.EmailTransportSendGrid:
+		if cfg.SendGridAPIKey == "" {
+			return mailer.NewNoopMailer()
+		}
+		return mailer.NewTemplatedMailer(mailer.NewSendGridTransport(cfg.SendGridAPIKey), cfg.SMTP.From, "")
+	case config.EmailTransportSES:
+		awsCfg, err := awsconfig.LoadDefaultConfig(context.Background())
+		if err != nil {
+			logger.GetLogger("main").Error().Err(err).Msg("Failed to load AWS config for SES; falling back to NoopMailer")
+			return mailer.NewNoopMailer()
+		}
+		return mailer.NewTemplatedMailer(mailer.NewSESTransport(sesv2.NewFromConfig(awsCfg)), cfg.SMTP.From, "")
+	default:
+		if cfg.SMTP.Host == "" {
+			return mailer.NewNoopMailer()
+		}
+		return mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+			TLSMode:  mailer.SMTPTLSMode(cfg.SMTP.TLSMode),
+		})
+	}
+}
+
+// buildAuditLogger picks an audit.Logger for cfg.AuditSink, falling back to
+// a NoopLogger for an unrecognized value or a file sink that fails to open,
+// so a misconfigured audit trail never blocks startup. auditLogRepo backs
+// AuditSinkDB; it's still built (and used by GET /me/audit) regardless of
+// which sink is active, since that endpoint reads the durable table
+// directly rather than through the Logger interface.
+func buildAuditLogger(cfg *config.Config, redisClient *redis.Client, auditLogRepo repositories.AuditLogRepository, log zerolog.Logger) audit.Logger {
+	switch cfg.AuditSink {
+	case config.AuditSinkStdout:
+		return audit.NewStdoutLogger()
+	case config.AuditSinkFile:
+		fileLogger, err := audit.NewFileLogger(cfg.AuditLogFile, 100*1024*1024)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to open audit log file; falling back to NoopLogger")
+			return audit.NewNoopLogger()
+		}
+		return fileLogger
+	case config.AuditSinkRedis:
+		return audit.NewRedisLogger(redisClient)
+	case config.AuditSinkDB:
+		return audit.NewDBLogger(auditLogRepo)
+	case config.AuditSinkNoop:
+		return audit.NewNoopLogger()
+	default:
+		log.Warn().Str("audit_sink", string(cfg.AuditSink)).Msg("Unrecognized AUDIT_SINK; falling back to StdoutLogger")
+		return audit.NewStdoutLogger()
 	}
 }
 
+// buildPasswordPolicy registers every supported algorithm so existing
+// hashes keep verifying regardless of which one created them, with
+// cfg.PasswordHash.Algorithm selected as the default for new hashes.
+func buildPasswordPolicy(cfg *config.Config) (*password.Policy, error) {
+	hashers := map[string]password.Hasher{
+		"argon2id": password.NewArgon2idHasher(password.Argon2idParams{
+			Memory:      cfg.PasswordHash.Argon2Memory,
+			Time:        cfg.PasswordHash.Argon2Time,
+			Parallelism: cfg.PasswordHash.Argon2Parallelism,
+			SaltLength:  cfg.PasswordHash.Argon2SaltLength,
+			KeyLength:   cfg.PasswordHash.Argon2KeyLength,
+		}),
+		"bcrypt": password.NewBcryptHasher(cfg.PasswordHash.BcryptCost),
+		"scrypt": password.NewScryptHasher(password.ScryptParams{
+			N:          cfg.PasswordHash.ScryptN,
+			R:          cfg.PasswordHash.ScryptR,
+			P:          cfg.PasswordHash.ScryptP,
+			SaltLength: cfg.PasswordHash.ScryptSaltLength,
+			KeyLength:  cfg.PasswordHash.ScryptKeyLength,
+		}),
+	}
+	return password.NewPolicy(cfg.PasswordHash.Algorithm, hashers)
+}
+
+// buildSchemeRegistry registers the native scheme plus any federated scheme
+// whose provider credentials are configured, so an unconfigured provider is
+// simply absent from the registry rather than registered half-working.
+// connectors additionally learns every registered OAuthScheme, so it can
+// start PKCE-protected authorization-code flows for the same providers.
+func buildSchemeRegistry(cfg *config.Config, userRepo repositories.UserRepository, remoteIdentityRepo repositories.RemoteIdentityRepository, connectors *services.ConnectorManager) *services.SchemeRegistry {
+	registry := services.NewSchemeRegistry()
+	registry.Register(services.NewNativeScheme(userRepo, cfg.PasswordMaxAge))
+	registry.Register(services.NewSAMLScheme(userRepo, remoteIdentityRepo))
+
+	if cfg.GoogleOAuth.ClientID != "" {
+		googleScheme := services.NewOAuthScheme("google", &oauth2.Config{
+			ClientID:     cfg.GoogleOAuth.ClientID,
+			ClientSecret: cfg.GoogleOAuth.ClientSecret,
+			RedirectURL:  cfg.GoogleOAuth.RedirectURL,
+			Scopes:       []string{"openid", "email", "profile"},
+			Endpoint:     google.Endpoint,
+		}, services.NewGoogleUserInfoFetcher(), userRepo, remoteIdentityRepo)
+		registry.Register(googleScheme)
+		connectors.Register(googleScheme)
+	}
+
+	if cfg.GitHubOAuth.ClientID != "" {
+		githubScheme := services.NewOAuthScheme("github", &oauth2.Config{
+			ClientID:     cfg.GitHubOAuth.ClientID,
+			ClientSecret: cfg.GitHubOAuth.ClientSecret,
+			RedirectURL:  cfg.GitHubOAuth.RedirectURL,
+			Scopes:       []string{"user:email"},
+			Endpoint:     github.Endpoint,
+		}, services.NewGitHubUserInfoFetcher(), userRepo, remoteIdentityRepo)
+		registry.Register(githubScheme)
+		connectors.Register(githubScheme)
+	}
+
+	if cfg.GenericOIDC.IssuerURL != "" {
+		oidcConfig, oidcProvider, err := services.NewOIDCConfig(context.Background(),
+			cfg.GenericOIDC.IssuerURL, cfg.GenericOIDC.ClientID, cfg.GenericOIDC.ClientSecret,
+			cfg.GenericOIDC.RedirectURL, []string{"openid", "email", "profile"})
+		if err != nil {
+			logger.GetLogger("main").Error().Err(err).Str("issuer", cfg.GenericOIDC.IssuerURL).Msg("Failed to discover OIDC provider, generic OIDC login disabled")
+		} else {
+			oidcScheme := services.NewOAuthScheme(cfg.GenericOIDC.Name, oidcConfig,
+				services.NewOIDCUserInfoFetcher(oidcProvider), userRepo, remoteIdentityRepo)
+			oidcScheme.SetIDTokenVerifier(services.NewOIDCIDTokenVerifier(oidcProvider, cfg.GenericOIDC.ClientID))
+			registry.Register(oidcScheme)
+			connectors.Register(oidcScheme)
+		}
+	}
+
+	if cfg.GenericOAuth.ClientID != "" {
+		genericScheme := services.NewOAuthScheme(cfg.GenericOAuth.Name, &oauth2.Config{
+			ClientID:     cfg.GenericOAuth.ClientID,
+			ClientSecret: cfg.GenericOAuth.ClientSecret,
+			RedirectURL:  cfg.GenericOAuth.RedirectURL,
+			Scopes:       cfg.GenericOAuth.Scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  cfg.GenericOAuth.AuthURL,
+				TokenURL: cfg.GenericOAuth.TokenURL,
+			},
+		}, services.NewGenericUserInfoFetcher(cfg.GenericOAuth.UserInfoURL, cfg.GenericOAuth.SubjectField, cfg.GenericOAuth.EmailField, cfg.GenericOAuth.NameField), userRepo, remoteIdentityRepo)
+		registry.Register(genericScheme)
+		connectors.Register(genericScheme)
+	}
+
+	if cfg.LDAP.URL != "" {
+		ldapDirectory := services.NewLDAPDirectory(cfg.LDAP.URL, cfg.LDAP.BindDN, cfg.LDAP.BindPassword, cfg.LDAP.UserSearchBase, cfg.LDAP.UserFilter)
+		registry.Register(services.NewLDAPScheme(ldapDirectory, userRepo, remoteIdentityRepo))
+	}
+
+	return registry
+}
+
 func initLogger() zerolog.Logger {
 	logger.Init()
 	return logger.GetLogger("main")
@@ -86,51 +330,238 @@ func initRedis(cfg *config.Config, log zerolog.Logger) *redis.Client {
 	return redisClient
 }
 
-func setupRouter(deps *Dependencies) *mux.Router {
-	healthController := controllers.NewHealthController(deps.DB, deps.RedisClient)
-	authController := controllers.NewAuthController(deps.AuthService)
+func setupRouter(deps *Dependencies) (*mux.Router, *middlewares.RateLimiter) {
+	healthController := controllers.NewHealthController(deps.DB, deps.RedisClient, deps.Config.RefreshIdleTimeout)
+	authController := controllers.NewAuthController(deps.AuthService, deps.Config.Introspection.ClientID, deps.Config.Introspection.ClientSecret, deps.AuditLogger)
+	serviceClientRepo := repositories.NewServiceClientRepository(deps.RawDB)
+	authController.SetServiceClientRepo(serviceClientRepo)
+	oidcProvider := services.NewOIDCProvider(deps.RedisClient, serviceClientRepo, deps.AuthServiceImpl)
+	oidcController := controllers.NewOIDCController(oidcProvider, deps.AuthServiceImpl)
+	jwksController := controllers.NewJWKSController(deps.KeyManager, time.Hour)
+	openIDConfigController := controllers.NewOpenIDConfigurationController(deps.Config.PublicURL, deps.Config.SigningKeyAlgorithm)
+	adminKeyController := controllers.NewAdminKeyController(deps.KeyManager)
+	adminTokenController := controllers.NewAdminTokenController(deps.AuthServiceImpl, deps.AuditLogger)
+	sessionController := controllers.NewSessionController(deps.SessionService, deps.AuditLogger)
+	auditController := controllers.NewAuditController(deps.RedisClient, audit.StreamKey, deps.AuditLogRepo)
+	federationController := controllers.NewFederationController(deps.SchemeRegistry, deps.ConnectorManager, deps.TokenIssuer)
+	resetController := controllers.NewPasswordResetController(deps.ResetService, deps.AuditLogger)
+	verificationController := controllers.NewEmailVerificationController(deps.VerificationService)
+	invitationController := controllers.NewInvitationController(deps.InvitationService)
+	mfaController := controllers.NewMFAController(deps.MFAService, deps.AuditLogger)
 
 	authMiddleware := middlewares.NewAuthMiddleware(deps.AuthService)
-	rateLimiter := middlewares.NewRateLimiter(deps.RedisClient)
+	rateLimiter := middlewares.NewRateLimiter(deps.RedisClient, deps.Config, deps.TokenRepo)
 	securityConfig := middlewares.NewSecurityConfig()
 
 	router := mux.NewRouter()
 
+	router.Use(middlewares.CorrelationID)
 	router.Use(rateLimiter.RateLimit)
 	router.Use(securityConfig.SecurityMiddleware)
 
 	router.HandleFunc("/health", healthController.Check).Methods("GET")
-	router.HandleFunc("/api/EchoAuth/register", authController.Register).Methods("POST")
-	router.HandleFunc("/api/EchoAuth/login", authController.Login).Methods("POST")
-	router.HandleFunc("/api/EchoAuth/refresh", authController.RefreshToken).Methods("POST")
+	router.HandleFunc("/health/live", healthController.Live).Methods("GET")
+	router.HandleFunc("/health/ready", healthController.Ready).Methods("GET")
+	router.HandleFunc("/health/startup", healthController.Startup).Methods("GET")
+	// /livez and /readyz alias the handlers above under the path names a
+	// Kubernetes probe most commonly expects, alongside the existing /health
+	// routes rather than replacing them.
+	router.HandleFunc("/livez", healthController.Live).Methods("GET")
+	router.HandleFunc("/readyz", healthController.Ready).Methods("GET")
+	router.HandleFunc("/.well-known/jwks.json", jwksController.Serve).Methods("GET")
+	router.HandleFunc("/.well-known/openid-configuration", openIDConfigController.Serve).Methods("GET")
+	router.HandleFunc("/oauth/token", oidcController.Token).Methods("POST")
+	router.Handle("/oauth/authorize", authMiddleware.Authenticate(http.HandlerFunc(oidcController.Authorize))).Methods("GET")
+	router.Handle("/oauth/userinfo", authMiddleware.Authenticate(http.HandlerFunc(oidcController.UserInfo))).Methods("GET")
+	router.Handle("/api/EchoAuth/register", rateLimiter.ForRoute("register")(http.HandlerFunc(authController.Register))).Methods("POST")
+	router.Handle("/api/EchoAuth/login", rateLimiter.ForRoute("login")(http.HandlerFunc(authController.Login))).Methods("POST")
+	router.Handle("/api/EchoAuth/login/mfa", rateLimiter.ForRoute("login")(http.HandlerFunc(authController.LoginMFA))).Methods("POST")
+	router.Handle("/api/EchoAuth/refresh", rateLimiter.ForRoute("refresh")(http.HandlerFunc(authController.RefreshToken))).Methods("POST")
+	router.HandleFunc("/api/EchoAuth/revoke", authController.Revoke).Methods("POST")
+	router.HandleFunc("/api/EchoAuth/introspect", authController.Introspect).Methods("POST")
+	router.HandleFunc("/api/EchoAuth/oauth/{provider}/start", federationController.OAuthStart).Methods("GET")
+	router.HandleFunc("/api/EchoAuth/oauth/{provider}/callback", federationController.OAuthCallback).Methods("GET")
+	router.HandleFunc("/api/EchoAuth/saml/acs", federationController.SAMLACS).Methods("POST")
+	router.HandleFunc("/api/EchoAuth/ldap/login", federationController.LDAPLogin).Methods("POST")
+	router.Handle("/api/EchoAuth/password-reset", rateLimiter.ForRoute("password_reset")(http.HandlerFunc(resetController.RequestReset))).Methods("POST")
+	router.Handle("/api/EchoAuth/password-reset/confirm", rateLimiter.ForRoute("password_reset")(http.HandlerFunc(resetController.ResetPassword))).Methods("POST")
+	router.HandleFunc("/api/EchoAuth/verify-email", verificationController.RequestVerification).Methods("POST")
+	router.HandleFunc("/api/EchoAuth/verify-email/{token}", verificationController.ConfirmEmail).Methods("POST")
+	router.Handle("/api/EchoAuth/invitations", rateLimiter.ForRoute("invitation")(http.HandlerFunc(invitationController.CreateInvitation))).Methods("POST")
+	router.HandleFunc("/api/EchoAuth/invitations/{token}", invitationController.ValidateInvitation).Methods("GET")
+	router.Handle("/api/EchoAuth/invitations/{token}/accept", rateLimiter.ForRoute("invitation")(http.HandlerFunc(invitationController.AcceptInvitation))).Methods("POST")
 
 	protected := router.PathPrefix("/api").Subrouter()
 	protected.Use(authMiddleware.Authenticate)
 	protected.HandleFunc("/EchoAuth/logout", authController.Logout).Methods("POST")
+	protected.HandleFunc("/EchoAuth/sessions", sessionController.ListSessions).Methods("GET")
+	protected.HandleFunc("/EchoAuth/sessions/{id}", sessionController.RevokeSession).Methods("DELETE")
+	protected.HandleFunc("/EchoAuth/sessions/revoke-others", sessionController.RevokeOtherSessions).Methods("POST")
+	protected.Handle("/EchoAuth/admin/keys/rotate", middlewares.AdminOnly(http.HandlerFunc(adminKeyController.RotateKeys))).Methods("POST")
+	protected.HandleFunc("/EchoAuth/mfa/enroll", mfaController.Enroll).Methods("POST")
+	protected.HandleFunc("/EchoAuth/mfa/confirm", mfaController.Confirm).Methods("POST")
+	protected.HandleFunc("/EchoAuth/audit", auditController.Tail).Methods("GET")
+	protected.HandleFunc("/EchoAuth/me/audit", auditController.History).Methods("GET")
+	protected.HandleFunc("/EchoAuth/me/identities/{provider}/link", federationController.LinkIdentity).Methods("POST")
+	protected.HandleFunc("/EchoAuth/me/identities/{provider}", federationController.UnlinkIdentity).Methods("DELETE")
+	protected.Handle("/admin/tokens/revoke", middlewares.AdminOnly(http.HandlerFunc(adminTokenController.RevokeToken))).Methods("POST")
+	protected.Handle("/admin/users/{id}/revoke-all", middlewares.AdminOnly(http.HandlerFunc(adminTokenController.RevokeAllUserTokens))).Methods("POST")
 
 	router.Handle("/metrics", promhttp.Handler())
 
-	return router
+	return router, rateLimiter
 }
 
-func startCleanupRoutine(ctx context.Context, tokenRepo *repositories.TokenRepository, log zerolog.Logger) {
-	go func() {
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
-		for {
+// newCleanupHook builds a lifecycle.Hook that runs the token-cleanup sweep
+// on a 24-hour ticker. Unlike the old fire-and-forget goroutine, Stop
+// actually waits for the sweep's goroutine to exit instead of just
+// canceling its context and moving on, so a shutdown doesn't race a sweep
+// that's mid-cleanup.
+func newCleanupHook(tokenRepo *repositories.TokenRepository, actionTokenRepo repositories.ActionTokenRepository, idleTimeout time.Duration, log zerolog.Logger) lifecycle.Hook {
+	done := make(chan struct{})
+	return lifecycle.NewHookFuncs("token-cleanup",
+		func(ctx context.Context) error {
+			go func() {
+				defer close(done)
+				ticker := time.NewTicker(24 * time.Hour)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						if err := tokenRepo.CleanupExpiredTokens(idleTimeout); err != nil {
+							log.Error().Err(err).Msg("Failed to cleanup expired tokens")
+						}
+						if err := actionTokenRepo.CleanupExpired(); err != nil {
+							log.Error().Err(err).Msg("Failed to cleanup expired action tokens")
+						}
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		func(ctx context.Context) error {
 			select {
-			case <-ticker.C:
-				if err := tokenRepo.CleanupExpiredTokens(); err != nil {
-					log.Error().Err(err).Msg("Failed to cleanup expired tokens")
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	)
+}
+
+// newActiveTokensGaugeHook builds a lifecycle.Hook that periodically
+// refreshes metrics.ActiveTokens with the current number of active
+// refresh-token families, on a much shorter interval than the cleanup
+// hook's since it's just a read, not a mutating sweep.
+func newActiveTokensGaugeHook(tokenRepo *repositories.TokenRepository, log zerolog.Logger) lifecycle.Hook {
+	done := make(chan struct{})
+	return lifecycle.NewHookFuncs("active-tokens-gauge",
+		func(ctx context.Context) error {
+			go func() {
+				defer close(done)
+				ticker := time.NewTicker(1 * time.Minute)
+				defer ticker.Stop()
+				for {
+					count, err := tokenRepo.CountActiveFamilies()
+					if err != nil {
+						log.Error().Err(err).Msg("Failed to count active token families")
+					} else {
+						metrics.RecordActiveTokens(count)
+					}
+
+					select {
+					case <-ticker.C:
+					case <-ctx.Done():
+						return
+					}
 				}
+			}()
+			return nil
+		},
+		func(ctx context.Context) error {
+			select {
+			case <-done:
+				return nil
 			case <-ctx.Done():
-				return
+				return ctx.Err()
+			}
+		},
+	)
+}
+
+// startGRPCServer starts grpcserver.Server (the gRPC counterpart of the HTTP
+// router, backed by the same deps.AuthService/SessionService) listening on
+// cfg.GRPCPort, and stops it once ctx is done. Reflection is only registered
+// when cfg.GRPCReflection is set, since it lets any client enumerate and
+// introspect the service - fine for local development, not for production.
+func startGRPCServer(ctx context.Context, cfg *config.Config, log zerolog.Logger, deps *Dependencies) *grpc.Server {
+	rateLimiters := make(map[string]services.RateLimiter, len(grpcserver.RateLimitedDimensions()))
+	for _, dimension := range grpcserver.RateLimitedDimensions() {
+		spec, ok := cfg.RateLimit.RateLimits[dimension]
+		policy := services.MustParsePolicy("100/1m")
+		if ok {
+			if parsed, err := services.ParsePolicy(spec); err == nil {
+				policy = parsed
 			}
 		}
+		rateLimiters[dimension] = services.NewRateLimiter(deps.RedisClient, policy)
+	}
+
+	grpcSrv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			grpcserver.MetricsUnaryInterceptor(),
+			grpcserver.RateLimitUnaryInterceptor(rateLimiters),
+			grpcserver.AuthUnaryInterceptor(deps.AuthService),
+		),
+	)
+	echoauthv1.RegisterAuthServiceServer(grpcSrv, grpcserver.New(deps.AuthService, deps.SessionService))
+	if cfg.GRPCReflection {
+		reflection.Register(grpcSrv)
+	}
+
+	lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to listen for gRPC")
+	}
+
+	go func() {
+		log.Info().Str("port", cfg.GRPCPort).Msg("Starting gRPC server")
+		if err := grpcSrv.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+			log.Error().Err(err).Msg("gRPC server failed")
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		grpcSrv.GracefulStop()
 	}()
+
+	return grpcSrv
 }
 
-func startServer(router *mux.Router, cfg *config.Config, log zerolog.Logger, deps *Dependencies) {
+// configReloadPollInterval is how often watchConfig stats CONFIG_FILE for a
+// changed mtime, between SIGHUPs.
+const configReloadPollInterval = 5 * time.Second
+
+// watchConfig applies every config reload loader.Watch delivers to the
+// subsystems that can actually pick up new values without a restart:
+// rateLimiter's policies (RateLimiter.UpdateConfig) and the global log level
+// (logger.SetLevel). configFile empty disables file-mtime polling but
+// loader.Watch still reloads on SIGHUP using the process's original
+// environment. Returns once ctx is done, when loader.Watch closes its
+// channel.
+func watchConfig(ctx context.Context, loader *config.Loader, configFile string, rateLimiter *middlewares.RateLimiter, log zerolog.Logger) {
+	for cfg := range loader.Watch(ctx, configFile, configReloadPollInterval) {
+		rateLimiter.UpdateConfig(cfg)
+		logger.SetLevel(cfg.LogLevel)
+		log.Info().Msg("Applied reloaded configuration")
+	}
+}
+
+func startServer(ctx context.Context, router *mux.Router, cfg *config.Config, log zerolog.Logger, deps *Dependencies, mgr *lifecycle.Manager) {
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
 		Handler:      router,
@@ -139,30 +570,68 @@ func startServer(router *mux.Router, cfg *config.Config, log zerolog.Logger, dep
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Create context that listens for signals
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
-
-	// Start server in a goroutine
-	go func() {
-		log.Info().Str("port", cfg.Port).Msg("Starting server")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal().Err(err).Msg("Server failed to start")
-		}
-	}()
+	// challengeSrv only runs under autocert, to answer HTTP-01 challenges
+	// on :80 and redirect everything else to https.
+	var challengeSrv *http.Server
+
+	switch echotls.Mode(cfg.TLS.Mode) {
+	case echotls.ModeAutocert:
+		manager := echotls.NewManager(cfg.TLS.Domains, cfg.TLS.CacheDir, deps.RawDB)
+		srv.Addr = ":443"
+		srv.TLSConfig = manager.TLSConfig()
+		challengeSrv = &http.Server{Addr: ":80", Handler: manager.HTTPHandler(nil)}
+
+		go func() {
+			log.Info().Msg("Starting HTTP-01 challenge listener on :80")
+			if err := challengeSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error().Err(err).Msg("HTTP-01 challenge listener failed")
+			}
+		}()
+		go func() {
+			log.Info().Strs("domains", cfg.TLS.Domains).Msg("Starting server with autocert TLS")
+			if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				log.Fatal().Err(err).Msg("Server failed to start")
+			}
+		}()
+	case echotls.ModeFiles:
+		go func() {
+			log.Info().Str("port", cfg.Port).Msg("Starting server with static TLS certificate")
+			if err := srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile); err != nil && err != http.ErrServerClosed {
+				log.Fatal().Err(err).Msg("Server failed to start")
+			}
+		}()
+	default:
+		go func() {
+			log.Info().Str("port", cfg.Port).Msg("Starting server")
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal().Err(err).Msg("Server failed to start")
+			}
+		}()
+	}
 
 	// Wait for interrupt signal
 	<-ctx.Done()
 	log.Info().Msg("Shutting down gracefully...")
 
 	// Create shutdown context with timeout
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	// Attempt graceful shutdown
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatal().Err(err).Msg("Server forced to shutdown")
 	}
+	if challengeSrv != nil {
+		if err := challengeSrv.Shutdown(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("HTTP-01 challenge listener forced to shutdown")
+		}
+	}
+
+	// Stop background hooks (token cleanup, active-tokens gauge) and wait
+	// for them to actually exit before closing the connections they use.
+	if err := mgr.Stop(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Error stopping background lifecycle hooks")
+	}
 
 	// Close database connection
 	sqlDB, err := deps.DB.DB()
@@ -186,19 +655,55 @@ func main() {
 	log := initLogger()
 	log.Info().Msg("Starting authentication service")
 
-	cfg := config.LoadConfig()
+	// config.NewLoader overlays CONFIG_FILE (YAML/TOML/JSON) on top of
+	// LoadConfig's env-var layer, resolves any "<scheme>://" secret
+	// references, and validates the result - so a deployment can move its
+	// non-secret settings into a checked-in config.yaml while still failing
+	// fast on a bad value instead of limping along on LoadConfig's lenient
+	// defaults.
+	configFile := os.Getenv("CONFIG_FILE")
+	configLoader := config.NewLoader()
+	cfg, err := configLoader.Load(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
 	log.Debug().Interface("config", cfg).Msg("Configuration loaded")
 
 	db := initDatabase(cfg, log)
+	rawDB, err := database.InitDB(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize raw database connection")
+	}
 	redisClient := initRedis(cfg, log)
-	deps := NewDependencies(db, redisClient, cfg)
+	deps := NewDependencies(db, rawDB, redisClient, cfg)
 
-	router := setupRouter(deps)
+	router, rateLimiter := setupRouter(deps)
 
-	// Create context for cleanup routine
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Shared context for the cleanup routine and both servers: canceled on
+	// SIGINT/SIGTERM, so gRPC and HTTP shut down on the same signal.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// watchConfig re-applies a reloaded config's rate-limit policies and log
+	// level without a restart. Everything else config.Loader.Watch could in
+	// principle push (JWT expiry, SMTP settings, lockout thresholds, ...)
+	// still requires one: AccountLockoutService has no config-driven fields
+	// to update, and the rest are only read once at startup in
+	// NewDependencies.
+	go watchConfig(ctx, configLoader, configFile, rateLimiter, log)
+
+	// mgr gives the token-cleanup sweep and active-tokens gauge an ordered,
+	// awaitable Start/Stop, so startServer's shutdown path can be sure
+	// they've actually exited before it closes the DB and Redis connections
+	// they use. Future background subsystems (an SMTP worker, an OIDC
+	// connector's refresh loop) register here the same way.
+	mgr := lifecycle.NewManager()
+	mgr.Register(newCleanupHook(deps.TokenRepo, deps.ActionTokenRepo, cfg.RefreshIdleTimeout, log))
+	mgr.Register(newActiveTokensGaugeHook(deps.TokenRepo, log))
+	if err := mgr.Start(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to start background lifecycle hooks")
+	}
 
-	startCleanupRoutine(ctx, deps.TokenRepo, log)
-	startServer(router, cfg, log, deps)
+	startGRPCServer(ctx, cfg, log, deps)
+	startServer(ctx, router, cfg, log, deps, mgr)
 }
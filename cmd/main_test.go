@@ -3,12 +3,15 @@ package main
 import (
 	"EchoAuth/config"
 	"EchoAuth/controllers"
+	"EchoAuth/keys"
+	"EchoAuth/lifecycle"
 	"EchoAuth/repositories"
 	"EchoAuth/services"
 	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/signal"
 	"testing"
 	"time"
 
@@ -21,6 +24,29 @@ import (
 	"gorm.io/gorm"
 )
 
+// memKeySetRepository is an in-memory keys.KeySetRepository for tests that
+// need a real RSA-backed KeyManager without touching a database.
+type memKeySetRepository struct {
+	set *keys.PrivateKeySet
+}
+
+func (r *memKeySetRepository) Load() (*keys.PrivateKeySet, error) {
+	return r.set, nil
+}
+
+func (r *memKeySetRepository) Save(set *keys.PrivateKeySet) error {
+	r.set = set
+	return nil
+}
+
+func newTestKeyManager(t *testing.T) keys.KeyManager {
+	km, err := keys.NewKeyManager(&memKeySetRepository{}, 24*time.Hour, "RS256")
+	if err != nil {
+		t.Fatalf("NewKeyManager() error = %v", err)
+	}
+	return km
+}
+
 // setupTestDB creates a test database connection
 func setupTestDB(t *testing.T) *gorm.DB {
 	dsn := "host=localhost user=postgres password=postgres dbname=auth_test_db port=5433 sslmode=disable"
@@ -92,11 +118,12 @@ func TestSetupRouter(t *testing.T) {
 	userRepo := repositories.NewUserRepository(db)
 	tokenRepo := repositories.NewTokenRepository(db)
 	lockoutSvc := services.NewAccountLockoutService(redisClient)
-	authService := services.NewAuthService(userRepo, tokenRepo, cfg, lockoutSvc)
+	keyManager := newTestKeyManager(t)
+	authService := services.NewAuthService(userRepo, tokenRepo, cfg, lockoutSvc, redisClient, keyManager)
 
 	// Create router
 	router := mux.NewRouter()
-	authController := controllers.NewAuthController(authService)
+	authController := controllers.NewAuthController(authService, cfg.Introspection.ClientID, cfg.Introspection.ClientSecret)
 
 	router.HandleFunc("/api/EchoAuth/register", authController.Register).Methods("POST")
 	router.HandleFunc("/api/EchoAuth/login", authController.Login).Methods("POST")
@@ -216,12 +243,19 @@ func TestStartCleanupRoutine(t *testing.T) {
 	}
 
 	tokenRepo := repositories.NewTokenRepository(db)
+	actionTokenRepo := repositories.NewActionTokenRepository(db)
 
-	// Start cleanup routine
-	startCleanupRoutine(ctx, tokenRepo, log)
+	// Start cleanup hook
+	hook := newCleanupHook(tokenRepo, actionTokenRepo, 0, log)
+	if err := hook.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
 
 	// Wait for context to be done
 	<-ctx.Done()
+	if err := hook.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
 }
 
 func TestStartServer(t *testing.T) {
@@ -248,11 +282,14 @@ func TestStartServer(t *testing.T) {
 		RedisClient: redisClient,
 	}
 
-	router := setupRouter(deps)
+	router, _ := setupRouter(deps)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	mgr := lifecycle.NewManager()
 
 	// Start server in a goroutine
 	go func() {
-		startServer(router, cfg, log, deps)
+		startServer(ctx, router, cfg, log, deps, mgr)
 	}()
 
 	// Give the server a moment to start
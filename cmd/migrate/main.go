@@ -0,0 +1,120 @@
+// Command migrate is an operator CLI around database.DB's versioned
+// migration engine. It shares config.LoadConfig so DATABASE_URL and the
+// rest of the service's env vars apply uniformly, rather than requiring a
+// separate set of flags for the migration tool.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"EchoAuth/config"
+	"EchoAuth/database"
+	"EchoAuth/utils/logger"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: migrate <command> [args]
+
+Commands:
+  up [n]       apply up to n pending migrations (all if n is omitted)
+  down [n]     roll back up to n applied migrations (all if n is omitted)
+  goto <v>     migrate up or down until version v is the latest applied
+  force <v>    record v as the current clean version without running SQL (-1 clears it)
+  status       list every known migration and whether it's applied/dirty`)
+}
+
+func main() {
+	logger.Init()
+	log := logger.GetLogger("migrate")
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := config.LoadConfig()
+	db, err := database.InitDB(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to database")
+	}
+	defer db.Close()
+
+	command := os.Args[1]
+	args := os.Args[2:]
+
+	switch command {
+	case "up":
+		n := parseOptionalInt(args, 0)
+		if err := db.Up(n); err != nil {
+			log.Fatal().Err(err).Msg("migrate up failed")
+		}
+		log.Info().Msg("migrate up succeeded")
+	case "down":
+		n := parseOptionalInt(args, 0)
+		if err := db.Down(n); err != nil {
+			log.Fatal().Err(err).Msg("migrate down failed")
+		}
+		log.Info().Msg("migrate down succeeded")
+	case "goto":
+		if len(args) != 1 {
+			usage()
+			os.Exit(2)
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatal().Err(err).Msg("goto requires a numeric version")
+		}
+		if err := db.Goto(version); err != nil {
+			log.Fatal().Err(err).Msg("migrate goto failed")
+		}
+		log.Info().Int("version", version).Msg("migrate goto succeeded")
+	case "force":
+		if len(args) != 1 {
+			usage()
+			os.Exit(2)
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatal().Err(err).Msg("force requires a numeric version")
+		}
+		if err := db.Force(version); err != nil {
+			log.Fatal().Err(err).Msg("migrate force failed")
+		}
+		log.Info().Int("version", version).Msg("migrate force succeeded")
+	case "status":
+		statuses, err := db.Status()
+		if err != nil {
+			log.Fatal().Err(err).Msg("migrate status failed")
+		}
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Applied && s.Dirty:
+				state = "dirty"
+			case s.Applied && s.ChecksumMismatch:
+				state = "checksum-mismatch"
+			case s.Applied:
+				state = "applied"
+			}
+			fmt.Printf("%04d  %-50s  %s\n", s.Version, s.Name, state)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// parseOptionalInt parses args[0] as the migration count n, defaulting to
+// def (0, meaning "no limit") when no count was given.
+func parseOptionalInt(args []string, def int) int {
+	if len(args) == 0 {
+		return def
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return def
+	}
+	return n
+}
@@ -3,22 +3,243 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// RedisConfig's Addr is validate:"required" (enforced by Loader, see
+// loader.go) since a blank address reaches go-redis and fails on the first
+// command issued against it instead of at startup, where it's far cheaper
+// to diagnose.
 type RedisConfig struct {
-	Addr     string
+	Addr     string `validate:"required"`
 	Password string
 	DB       int
 }
 
+// OAuthProviderConfig holds the client registration for one OAuth2
+// provider. A provider with an empty ClientID is treated as unconfigured
+// and is not registered as a login scheme.
+type OAuthProviderConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// OIDCProviderConfig holds the client registration for a generic,
+// discovery-based OIDC provider (e.g. Keycloak) - one whose authorization
+// and token endpoints are resolved at startup from IssuerURL's
+// /.well-known/openid-configuration document instead of being hardcoded
+// like OAuthProviderConfig's Google/GitHub counterparts. A config with an
+// empty IssuerURL is treated as unconfigured and is not registered as a
+// login scheme. Name labels the scheme it's registered under (e.g.
+// "keycloak"), so more than one discovery-based provider could eventually
+// be configured without colliding.
+type OIDCProviderConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// GenericOAuthConfig holds the client registration and explicit endpoints
+// for a plain OAuth2 provider that isn't full-discovery OIDC (e.g. an
+// OpenShift-style identity provider), so its authorization/token/userinfo
+// URLs and the field names its userinfo response uses for subject/email/
+// name are all given directly instead of resolved from a well-known
+// document like OIDCProviderConfig's GenericOIDC does. A config with an
+// empty ClientID is treated as unconfigured and is not registered as a
+// login scheme.
+type GenericOAuthConfig struct {
+	Name         string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	SubjectField string
+	EmailField   string
+	NameField    string
+}
+
+// LDAPConfig holds the connection info for a search-then-bind LDAP
+// directory. A config with an empty URL is treated as unconfigured and is
+// not registered as a login scheme. UserFilter is a filter template with
+// one %s for the submitted username, e.g. "(uid=%s)" or
+// "(sAMAccountName=%s)" for Active Directory.
+type LDAPConfig struct {
+	URL            string
+	BindDN         string
+	BindPassword   string
+	UserSearchBase string
+	UserFilter     string
+}
+
+// IntrospectionConfig holds the basic-auth service credentials resource
+// servers use to call POST /api/EchoAuth/introspect. A config with an empty
+// ClientID is treated as unconfigured, and introspection rejects every
+// request.
+type IntrospectionConfig struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// SMTPConfig holds the connection info for the SMTP relay used to send
+// email. A config with an empty Host is treated as unconfigured, and the
+// application falls back to a no-op mailer.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLSMode  string
+}
+
+// EmailTransport names which mailer.Transport buildMailer should construct.
+type EmailTransport string
+
+const (
+	EmailTransportSMTP     EmailTransport = "smtp"
+	EmailTransportSendGrid EmailTransport = "sendgrid"
+	EmailTransportSES      EmailTransport = "ses"
+)
+
+// MailerConfig sizes the async delivery queue mailer.NewAsyncMailer wraps
+// the real transport with: Workers background goroutines pull from a
+// channel buffered to QueueSize, retrying a failed send up to MaxAttempts
+// times with exponential backoff (starting at BaseBackoff) before giving up
+// and dead-lettering it.
+type MailerConfig struct {
+	Workers     int
+	QueueSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+}
+
+// AuditSink names which audit.Logger buildAuditLogger should construct.
+type AuditSink string
+
+const (
+	AuditSinkNoop   AuditSink = "noop"
+	AuditSinkStdout AuditSink = "stdout"
+	AuditSinkFile   AuditSink = "file"
+	AuditSinkRedis  AuditSink = "redis"
+	// AuditSinkDB persists events to the audit_logs table, the one sink a
+	// user's own history can be read back out of via GET /me/audit.
+	AuditSinkDB AuditSink = "db"
+)
+
+// PasswordHashConfig controls which password hashing algorithm is used for
+// new hashes and its cost parameters. Existing hashes created under
+// different parameters (or a different algorithm entirely) keep verifying
+// correctly; see the password package for how that's dispatched.
+type PasswordHashConfig struct {
+	Algorithm         string
+	Argon2Memory      uint32 // KiB
+	Argon2Time        uint32
+	Argon2Parallelism uint8
+	Argon2SaltLength  uint32
+	Argon2KeyLength   uint32
+	BcryptCost        int
+	ScryptN           int
+	ScryptR           int
+	ScryptP           int
+	ScryptSaltLength  int
+	ScryptKeyLength   int
+}
+
+// TLSConfig controls how the server terminates TLS. Mode "off" serves
+// plain HTTP on Port (the local development default); "files" serves
+// HTTPS from a static certificate/key pair; "autocert" obtains and
+// renews certificates automatically from an ACME CA for Domains.
+type TLSConfig struct {
+	Mode     string
+	Domains  []string
+	CacheDir string
+	CertFile string
+	KeyFile  string
+}
+
+// RateLimitWindowConfig bounds how many attempts a single key may make
+// within Window before requests start being rejected with 429.
+type RateLimitWindowConfig struct {
+	MaxAttempts int
+	Window      time.Duration
+}
+
+// RateLimitConfig controls the per-route rate limiting applied to
+// enumeration/brute-force-prone auth endpoints, plus the global, looser
+// limit applied to every request. TrustedProxyCIDRs lists the CIDR ranges
+// allowed to set X-Forwarded-For; a request from outside all of them is
+// always keyed by its own RemoteAddr.
+//
+// RateLimits holds the identity-dimension limits middlewares.RateLimiter
+// enforces, keyed by logical name ("login_by_ip", "login_by_email",
+// "register_by_ip", "refresh_by_user", "password_reset_by_ip",
+// "password_reset_by_email", "global_by_ip") with a services.ParsePolicy
+// "<attempts>/<duration>" spec as the value (e.g. "5/30m"). Invitation
+// predates that dimension scheme and is still configured as a flat
+// MaxAttempts/Window pair, since it's enforced along a single dimension
+// (IP) rather than several.
+//
+// FailOpen controls what middlewares.RateLimiter does when its backing
+// limiter (normally Redis) errors out: true lets the request through
+// unchecked, false rejects it with 503 rather than risk letting a brute
+// force past a limiter that's merely unreachable.
+type RateLimitConfig struct {
+	TrustedProxyCIDRs []string
+	RateLimits        map[string]string
+	Invitation        RateLimitWindowConfig
+	FailOpen          bool
+}
+
+// defaultJWTSecret is the fallback LoadConfig uses when JWT_SECRET isn't
+// set. Loader.Load refuses to start with it in a production Environment
+// (see validateConfig in loader.go), since it being public in this source
+// file makes it worthless as an actual secret.
+const defaultJWTSecret = "your-secret-key"
+
 type Config struct {
-	Port        string
-	JWTSecret   string
-	JWTExpiry   time.Duration
-	DatabaseURL string
-	Redis       RedisConfig
-	Environment string
+	Port                    string
+	JWTSecret               string `validate:"required,min=32"`
+	JWTExpiry               time.Duration
+	JWTKeyRotation          time.Duration
+	JWTAudience             string
+	SigningKeyAlgorithm     string `validate:"oneof=RS256 ES256"`
+	DatabaseURL             string
+	Redis                   RedisConfig
+	Environment             string `validate:"oneof=development staging production"`
+	KeyEncryptionKey        string
+	LogLevel                string
+	PasswordMaxAge          time.Duration
+	RefreshIdleTimeout      time.Duration
+	RefreshAbsoluteTimeout  time.Duration
+	MaxSimultaneousSessions int
+	AllowMultiLogin         bool
+	GoogleOAuth             OAuthProviderConfig
+	GitHubOAuth             OAuthProviderConfig
+	GenericOIDC             OIDCProviderConfig
+	GenericOAuth            GenericOAuthConfig
+	LDAP                    LDAPConfig
+	SMTP                    SMTPConfig
+	PublicURL               string
+	RequireVerifiedEmail    bool
+	PasswordHash            PasswordHashConfig
+	TLS                     TLSConfig
+	RateLimit               RateLimitConfig
+	Introspection           IntrospectionConfig
+	EmailTransport          EmailTransport
+	SendGridAPIKey          string
+	Mailer                  MailerConfig
+	AuditSink               AuditSink
+	AuditLogFile            string
+	GRPCPort                string
+	GRPCReflection          bool
+	ShutdownTimeout         time.Duration
 }
 
 func LoadConfig() *Config {
@@ -29,6 +250,34 @@ func LoadConfig() *Config {
 		}
 	}
 
+	jwtKeyRotation := 30 * 24 * time.Hour
+	if rotStr := getEnv("JWT_KEY_ROTATION", "720h"); rotStr != "" {
+		if rot, err := time.ParseDuration(rotStr); err == nil {
+			jwtKeyRotation = rot
+		}
+	}
+
+	passwordMaxAge := 90 * 24 * time.Hour
+	if maxAgeStr := getEnv("PASSWORD_MAX_AGE", "2160h"); maxAgeStr != "" {
+		if maxAge, err := time.ParseDuration(maxAgeStr); err == nil {
+			passwordMaxAge = maxAge
+		}
+	}
+
+	refreshIdleTimeout := 14 * 24 * time.Hour
+	if idleStr := getEnv("REFRESH_IDLE_TIMEOUT", "336h"); idleStr != "" {
+		if idle, err := time.ParseDuration(idleStr); err == nil {
+			refreshIdleTimeout = idle
+		}
+	}
+
+	shutdownTimeout := 30 * time.Second
+	if shutdownStr := getEnv("SHUTDOWN_TIMEOUT", "30s"); shutdownStr != "" {
+		if shutdown, err := time.ParseDuration(shutdownStr); err == nil {
+			shutdownTimeout = shutdown
+		}
+	}
+
 	redisDB := 0
 	if dbStr := getEnv("REDIS_DB", "0"); dbStr != "" {
 		if db, err := strconv.Atoi(dbStr); err == nil {
@@ -36,17 +285,218 @@ func LoadConfig() *Config {
 		}
 	}
 
+	refreshAbsoluteTimeout := 30 * 24 * time.Hour
+	if absStr := getEnv("REFRESH_ABSOLUTE_TIMEOUT", "720h"); absStr != "" {
+		if abs, err := time.ParseDuration(absStr); err == nil {
+			refreshAbsoluteTimeout = abs
+		}
+	}
+
+	maxSessions := 5
+	if maxSessionsStr := getEnv("AUTH_MAX_SIMULTANEOUS_SESSIONS", "5"); maxSessionsStr != "" {
+		if n, err := strconv.Atoi(maxSessionsStr); err == nil {
+			maxSessions = n
+		}
+	}
+
+	allowMultiLogin := true
+	if v, err := strconv.ParseBool(getEnv("AUTH_ALLOW_MULTI_LOGIN", "true")); err == nil {
+		allowMultiLogin = v
+	}
+
+	mailerWorkers := 4
+	if workersStr := getEnv("MAILER_WORKERS", "4"); workersStr != "" {
+		if n, err := strconv.Atoi(workersStr); err == nil {
+			mailerWorkers = n
+		}
+	}
+
+	mailerQueueSize := 100
+	if queueSizeStr := getEnv("MAILER_QUEUE_SIZE", "100"); queueSizeStr != "" {
+		if n, err := strconv.Atoi(queueSizeStr); err == nil {
+			mailerQueueSize = n
+		}
+	}
+
+	mailerMaxAttempts := 5
+	if maxAttemptsStr := getEnv("MAILER_MAX_ATTEMPTS", "5"); maxAttemptsStr != "" {
+		if n, err := strconv.Atoi(maxAttemptsStr); err == nil {
+			mailerMaxAttempts = n
+		}
+	}
+
+	mailerBaseBackoff := 2 * time.Second
+	if backoffStr := getEnv("MAILER_BASE_BACKOFF", "2s"); backoffStr != "" {
+		if backoff, err := time.ParseDuration(backoffStr); err == nil {
+			mailerBaseBackoff = backoff
+		}
+	}
+
+	smtpPort := 587
+	if portStr := getEnv("SMTP_PORT", "587"); portStr != "" {
+		if p, err := strconv.Atoi(portStr); err == nil {
+			smtpPort = p
+		}
+	}
+
+	requireVerifiedEmail := false
+	if v, err := strconv.ParseBool(getEnv("REQUIRE_VERIFIED_EMAIL", "false")); err == nil {
+		requireVerifiedEmail = v
+	}
+
+	grpcReflection := false
+	if v, err := strconv.ParseBool(getEnv("GRPC_REFLECTION", "false")); err == nil {
+		grpcReflection = v
+	}
+
+	rateLimitFailOpen := true
+	if v, err := strconv.ParseBool(getEnv("RATE_LIMIT_FAIL_OPEN", "true")); err == nil {
+		rateLimitFailOpen = v
+	}
+
+	passwordHash := PasswordHashConfig{
+		Algorithm:         getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		Argon2Memory:      getEnvUint32("PASSWORD_HASH_ARGON2_MEMORY_KIB", 64*1024),
+		Argon2Time:        getEnvUint32("PASSWORD_HASH_ARGON2_TIME", 3),
+		Argon2Parallelism: uint8(getEnvUint32("PASSWORD_HASH_ARGON2_PARALLELISM", 2)),
+		Argon2SaltLength:  getEnvUint32("PASSWORD_HASH_ARGON2_SALT_LENGTH", 16),
+		Argon2KeyLength:   getEnvUint32("PASSWORD_HASH_ARGON2_KEY_LENGTH", 32),
+		BcryptCost:        getEnvInt("PASSWORD_HASH_BCRYPT_COST", 10),
+		ScryptN:           getEnvInt("PASSWORD_HASH_SCRYPT_N", 32768),
+		ScryptR:           getEnvInt("PASSWORD_HASH_SCRYPT_R", 8),
+		ScryptP:           getEnvInt("PASSWORD_HASH_SCRYPT_P", 1),
+		ScryptSaltLength:  getEnvInt("PASSWORD_HASH_SCRYPT_SALT_LENGTH", 16),
+		ScryptKeyLength:   getEnvInt("PASSWORD_HASH_SCRYPT_KEY_LENGTH", 32),
+	}
+
+	tlsConfig := TLSConfig{
+		Mode:     getEnv("TLS_MODE", "off"),
+		Domains:  getEnvList("TLS_DOMAINS", nil),
+		CacheDir: getEnv("TLS_CACHE_DIR", ""),
+		CertFile: getEnv("TLS_CERT_FILE", ""),
+		KeyFile:  getEnv("TLS_KEY_FILE", ""),
+	}
+
+	rateLimit := RateLimitConfig{
+		TrustedProxyCIDRs: getEnvList("TRUSTED_PROXY_CIDRS", nil),
+		RateLimits: map[string]string{
+			"login_by_ip":             getEnv("RATE_LIMIT_LOGIN_BY_IP", "5/5m"),
+			"login_by_email":          getEnv("RATE_LIMIT_LOGIN_BY_EMAIL", "5/5m"),
+			"register_by_ip":          getEnv("RATE_LIMIT_REGISTER_BY_IP", "5/1h"),
+			"refresh_by_user":         getEnv("RATE_LIMIT_REFRESH_BY_USER", "30/1m"),
+			"password_reset_by_ip":    getEnv("RATE_LIMIT_PASSWORD_RESET_BY_IP", "20/1h"),
+			"password_reset_by_email": getEnv("RATE_LIMIT_PASSWORD_RESET_BY_EMAIL", "5/1h"),
+			"global_by_ip":            getEnv("RATE_LIMIT_GLOBAL_BY_IP", "100/1m"),
+		},
+		Invitation: getRateLimitWindow("RATE_LIMIT_INVITATION", 10, time.Hour),
+		FailOpen:   rateLimitFailOpen,
+	}
+
 	return &Config{
-		Port:        getEnv("PORT", "8080"),
-		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key"),
-		JWTExpiry:   jwtExpiry,
-		DatabaseURL: getEnv("DATABASE_URL", "host=localhost user=postgres password=postgres dbname=auth_db port=5432 sslmode=disable"),
+		Port:                getEnv("PORT", "8080"),
+		JWTSecret:           getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTExpiry:           jwtExpiry,
+		JWTKeyRotation:      jwtKeyRotation,
+		JWTAudience:         getEnv("JWT_AUDIENCE", "EchoAuth"),
+		SigningKeyAlgorithm: getEnv("SIGNING_KEY_ALGORITHM", "RS256"),
+		DatabaseURL:         getEnv("DATABASE_URL", "host=localhost user=postgres password=postgres dbname=auth_db port=5432 sslmode=disable"),
 		Redis: RedisConfig{
 			Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
 			Password: getEnv("REDIS_PASS", ""),
 			DB:       redisDB,
 		},
-		Environment: getEnv("ENV", "development"),
+		Environment:             getEnv("ENV", "development"),
+		KeyEncryptionKey:        getEnv("KEY_ENCRYPTION_KEY", "0123456789abcdef0123456789abcdef"),
+		LogLevel:                getEnv("LOG_LEVEL", "info"),
+		PasswordMaxAge:          passwordMaxAge,
+		RefreshIdleTimeout:      refreshIdleTimeout,
+		RefreshAbsoluteTimeout:  refreshAbsoluteTimeout,
+		MaxSimultaneousSessions: maxSessions,
+		AllowMultiLogin:         allowMultiLogin,
+		GoogleOAuth: OAuthProviderConfig{
+			ClientID:     getEnv("GOOGLE_OAUTH_CLIENT_ID", ""),
+			ClientSecret: getEnv("GOOGLE_OAUTH_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("GOOGLE_OAUTH_REDIRECT_URL", ""),
+		},
+		GitHubOAuth: OAuthProviderConfig{
+			ClientID:     getEnv("GITHUB_OAUTH_CLIENT_ID", ""),
+			ClientSecret: getEnv("GITHUB_OAUTH_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("GITHUB_OAUTH_REDIRECT_URL", ""),
+		},
+		GenericOIDC: OIDCProviderConfig{
+			Name:         getEnv("OIDC_PROVIDER_NAME", "oidc"),
+			IssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+			ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		},
+		GenericOAuth: GenericOAuthConfig{
+			Name:         getEnv("GENERIC_OAUTH_PROVIDER_NAME", "generic"),
+			AuthURL:      getEnv("GENERIC_OAUTH_AUTH_URL", ""),
+			TokenURL:     getEnv("GENERIC_OAUTH_TOKEN_URL", ""),
+			UserInfoURL:  getEnv("GENERIC_OAUTH_USERINFO_URL", ""),
+			ClientID:     getEnv("GENERIC_OAUTH_CLIENT_ID", ""),
+			ClientSecret: getEnv("GENERIC_OAUTH_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("GENERIC_OAUTH_REDIRECT_URL", ""),
+			Scopes:       getEnvList("GENERIC_OAUTH_SCOPES", []string{"user:info"}),
+			SubjectField: getEnv("GENERIC_OAUTH_SUBJECT_FIELD", "sub"),
+			EmailField:   getEnv("GENERIC_OAUTH_EMAIL_FIELD", "email"),
+			NameField:    getEnv("GENERIC_OAUTH_NAME_FIELD", "name"),
+		},
+		LDAP: LDAPConfig{
+			URL:            getEnv("LDAP_URL", ""),
+			BindDN:         getEnv("LDAP_BIND_DN", ""),
+			BindPassword:   getEnv("LDAP_BIND_PASSWORD", ""),
+			UserSearchBase: getEnv("LDAP_USER_SEARCH_BASE", ""),
+			UserFilter:     getEnv("LDAP_USER_FILTER", "(uid=%s)"),
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     smtpPort,
+			Username: getEnv("SMTP_USER", ""),
+			Password: getEnv("SMTP_PASS", ""),
+			From:     getEnv("SMTP_FROM", "no-reply@EchoAuth.local"),
+			TLSMode:  getEnv("SMTP_TLS_MODE", "starttls"),
+		},
+		PublicURL:            getEnv("PUBLIC_URL", "http://localhost:8080"),
+		RequireVerifiedEmail: requireVerifiedEmail,
+		PasswordHash:         passwordHash,
+		TLS:                  tlsConfig,
+		RateLimit:            rateLimit,
+		Introspection: IntrospectionConfig{
+			ClientID:     getEnv("INTROSPECTION_CLIENT_ID", ""),
+			ClientSecret: getEnv("INTROSPECTION_CLIENT_SECRET", ""),
+		},
+		EmailTransport: EmailTransport(getEnv("EMAIL_TRANSPORT", string(EmailTransportSMTP))),
+		SendGridAPIKey: getEnv("SENDGRID_API_KEY", ""),
+		Mailer: MailerConfig{
+			Workers:     mailerWorkers,
+			QueueSize:   mailerQueueSize,
+			MaxAttempts: mailerMaxAttempts,
+			BaseBackoff: mailerBaseBackoff,
+		},
+		AuditSink:       AuditSink(getEnv("AUDIT_SINK", string(AuditSinkStdout))),
+		AuditLogFile:    getEnv("AUDIT_LOG_FILE", "audit.log"),
+		GRPCPort:        getEnv("GRPC_PORT", "9090"),
+		GRPCReflection:  grpcReflection,
+		ShutdownTimeout: shutdownTimeout,
+	}
+}
+
+// getRateLimitWindow reads "<prefix>_MAX_ATTEMPTS" and "<prefix>_WINDOW"
+// (a time.ParseDuration string, e.g. "5m"), falling back to
+// defaultMaxAttempts/defaultWindow for any value that's unset or invalid.
+func getRateLimitWindow(prefix string, defaultMaxAttempts int, defaultWindow time.Duration) RateLimitWindowConfig {
+	window := defaultWindow
+	if windowStr := getEnv(prefix+"_WINDOW", ""); windowStr != "" {
+		if w, err := time.ParseDuration(windowStr); err == nil {
+			window = w
+		}
+	}
+
+	return RateLimitWindowConfig{
+		MaxAttempts: getEnvInt(prefix+"_MAX_ATTEMPTS", defaultMaxAttempts),
+		Window:      window,
 	}
 }
 
@@ -56,3 +506,34 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if v, err := strconv.Atoi(getEnv(key, "")); err == nil {
+		return v
+	}
+	return defaultValue
+}
+
+func getEnvUint32(key string, defaultValue uint32) uint32 {
+	if v, err := strconv.ParseUint(getEnv(key, ""), 10, 32); err == nil {
+		return uint32(v)
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated env var into a trimmed, non-empty
+// list of values, or returns defaultValue if it's unset.
+func getEnvList(key string, defaultValue []string) []string {
+	raw := getEnv(key, "")
+	if raw == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"EchoAuth/keys"
+	"EchoAuth/utils/response"
+)
+
+// AdminKeyController triggers an out-of-schedule signing key rotation. The
+// route is gated to admins with middlewares.AdminOnly.
+type AdminKeyController struct {
+	keyManager keys.KeyManager
+}
+
+func NewAdminKeyController(keyManager keys.KeyManager) *AdminKeyController {
+	return &AdminKeyController{keyManager: keyManager}
+}
+
+// RotateKeys forces a signing key rotation. An optional "ttl" query param
+// (a Go duration string, e.g. "5m") overrides how long the outgoing key
+// stays trusted for verification; without it, the key manager's default
+// retention is used.
+func (c *AdminKeyController) RotateKeys(w http.ResponseWriter, r *http.Request) {
+	var err error
+	if ttlStr := r.URL.Query().Get("ttl"); ttlStr != "" {
+		ttl, parseErr := time.ParseDuration(ttlStr)
+		if parseErr != nil {
+			response.JSONError(w, "Invalid ttl", http.StatusBadRequest)
+			return
+		}
+		err = c.keyManager.RotateKeys(ttl)
+	} else {
+		err = c.keyManager.Rotate()
+	}
+
+	if err != nil {
+		response.JSONError(w, "Failed to rotate signing keys", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSONResponse(w, map[string]string{"message": "Signing keys rotated"}, http.StatusOK)
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"EchoAuth/models"
+	"EchoAuth/utils/response"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AuditLogReader returns a user's own durable audit history;
+// repositories.AuditLogRepository satisfies it.
+type AuditLogReader interface {
+	FindByUserID(userID uint, limit int) ([]*models.AuditLog, error)
+}
+
+// auditHistoryLimit bounds how many rows History returns, so a long-lived
+// account can't turn GET /me/audit into an unbounded table scan.
+const auditHistoryLimit = 100
+
+// AuditController tails the Redis audit event stream for live debugging,
+// and - via History - lets a user read back their own durable audit trail.
+// Tail predates the AdminOnly middleware, so it is still reachable by any
+// authenticated user rather than gated to admins specifically.
+type AuditController struct {
+	redisClient *redis.Client
+	streamKey   string
+	logRepo     AuditLogReader
+}
+
+func NewAuditController(redisClient *redis.Client, streamKey string, logRepo AuditLogReader) *AuditController {
+	return &AuditController{redisClient: redisClient, streamKey: streamKey, logRepo: logRepo}
+}
+
+// Tail returns audit events added to the stream after the "after" query
+// param (a Redis stream ID; defaults to "$", meaning "only events added
+// from now on"), blocking for up to 5 seconds for at least one to arrive.
+func (c *AuditController) Tail(w http.ResponseWriter, r *http.Request) {
+	after := r.URL.Query().Get("after")
+	if after == "" {
+		after = "$"
+	}
+
+	result, err := c.redisClient.XRead(r.Context(), &redis.XReadArgs{
+		Streams: []string{c.streamKey, after},
+		Block:   5 * time.Second,
+		Count:   100,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		response.JSONError(w, "Failed to read audit stream", http.StatusInternalServerError)
+		return
+	}
+
+	events := []map[string]interface{}{}
+	if len(result) > 0 {
+		for _, msg := range result[0].Messages {
+			events = append(events, map[string]interface{}{
+				"id":     msg.ID,
+				"fields": msg.Values,
+			})
+		}
+	}
+
+	response.JSONResponse(w, events, http.StatusOK)
+}
+
+// History returns the authenticated user's own audit log entries, newest
+// first, from the durable audit_logs table - unlike Tail, this survives
+// regardless of which audit.Logger sink is configured for live events.
+func (c *AuditController) History(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	logs, err := c.logRepo.FindByUserID(userID, auditHistoryLimit)
+	if err != nil {
+		response.JSONError(w, "Failed to read audit history", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSONResponse(w, logs, http.StatusOK)
+}
@@ -1,33 +1,90 @@
 package controllers
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"EchoAuth/audit"
 	"EchoAuth/models"
+	"EchoAuth/repositories"
+	"EchoAuth/services"
+	"EchoAuth/utils/logger"
+	"EchoAuth/utils/problem"
 	"EchoAuth/utils/response"
 
 	"github.com/go-playground/validator/v10"
 )
 
 type AuthService interface {
-	Register(email, password, firstName, lastName string) error
-	LoginWithRefresh(email, password, deviceInfo, ip string) (string, string, error)
-	Logout(token string) error
+	Register(ctx context.Context, email, password, firstName, lastName string) error
+	LoginWithRefresh(ctx context.Context, email, password, deviceInfo, ip string, scopes []string) (string, string, error)
+	Logout(ctx context.Context, token string) error
 	ValidateToken(token string) (*models.TokenClaims, error)
-	RefreshToken(refreshToken, deviceInfo, ip string) (string, string, error)
+	RefreshToken(ctx context.Context, refreshToken, deviceInfo, ip string, scopes []string) (string, string, error)
 	GetJWTExpiry() time.Duration
 	GetUserByEmail(email string) (*models.User, error)
+	IssuePasswordChangeToken(email string) (string, error)
+	RevokeAny(token, tokenTypeHint string) error
+	IntrospectToken(token string) (*models.Introspection, error)
+	GetLockoutStatus(ctx context.Context, email string) (services.LockoutStatus, error)
+	IssueMFAToken(email string) (string, error)
+	CompleteMFALogin(mfaToken, code, deviceInfo, ip string) (string, string, error)
 }
 
 type AuthController struct {
-	authService AuthService
+	authService               AuthService
+	introspectionClientID     string
+	introspectionClientSecret string
+	auditLogger               audit.Logger
+	serviceClients            repositories.ServiceClientRepository
 }
 
-func NewAuthController(authService AuthService) *AuthController {
+// NewAuthController builds an AuthController. introspectionClientID and
+// introspectionClientSecret are the basic-auth credentials resource servers
+// must present to POST /introspect; an empty introspectionClientID disables
+// the endpoint entirely. auditLogger receives one audit.Event per register,
+// login, refresh and logout attempt.
+func NewAuthController(authService AuthService, introspectionClientID, introspectionClientSecret string, auditLogger audit.Logger) *AuthController {
 	return &AuthController{
-		authService: authService,
+		authService:               authService,
+		introspectionClientID:     introspectionClientID,
+		introspectionClientSecret: introspectionClientSecret,
+		auditLogger:               auditLogger,
+	}
+}
+
+// SetServiceClientRepo lets introspection accept any number of independently
+// revocable resource-server clients, each with its own hashed secret,
+// instead of just the single static introspectionClientID/Secret pair. It's
+// optional: without it, Introspect checks only the static pair, exactly as
+// before.
+func (ac *AuthController) SetServiceClientRepo(repo repositories.ServiceClientRepository) {
+	ac.serviceClients = repo
+}
+
+// correlationID reads the correlation ID middlewares.CorrelationID attached
+// to the request context, so audit events can be traced back to the
+// request that triggered them.
+func correlationID(r *http.Request) string {
+	id, _ := r.Context().Value("correlation_id").(string)
+	return id
+}
+
+func (ac *AuthController) audit(r *http.Request, typ audit.EventType, outcome audit.Outcome, userID uint, ip, deviceInfo, reason string) {
+	ac.auditWithEmail(r, typ, outcome, userID, "", ip, deviceInfo, reason)
+}
+
+func (ac *AuthController) auditWithEmail(r *http.Request, typ audit.EventType, outcome audit.Outcome, userID uint, email, ip, deviceInfo, reason string) {
+	event := audit.NewEvent(typ, outcome, userID, ip, deviceInfo, reason, correlationID(r))
+	event.Email = email
+	if err := ac.auditLogger.Log(event); err != nil {
+		logger.GetLogger("audit").Error().Err(err).Str("event_type", string(typ)).Msg("failed to write audit event")
 	}
 }
 
@@ -39,8 +96,9 @@ type RegisterRequest struct {
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Email    string   `json:"email" validate:"required,email"`
+	Password string   `json:"password" validate:"required"`
+	Scopes   []string `json:"scopes"`
 }
 
 type LoginResponse struct {
@@ -48,8 +106,35 @@ type LoginResponse struct {
 	User *models.User `json:"user"`
 }
 
+// MFARequiredResponse is returned instead of a LoginResponse when the
+// account being logged into has TOTP enabled; the client completes the
+// login by POSTing MFAToken and the current code to /login/mfa.
+type MFARequiredResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
 type RefreshTokenRequest struct {
-	RefreshToken string `json:"refresh_token" validate:"required"`
+	RefreshToken string   `json:"refresh_token" validate:"required"`
+	Scopes       []string `json:"scopes"`
+}
+
+// RevokeRequest follows RFC 7009: token may be an access or refresh token,
+// with token_type_hint optionally disambiguating which to try first.
+type RevokeRequest struct {
+	Token         string `json:"token" validate:"required"`
+	TokenTypeHint string `json:"token_type_hint"`
+}
+
+// IntrospectRequest follows RFC 7662: token may be an access or refresh
+// token.
+type IntrospectRequest struct {
+	Token string `json:"token" validate:"required"`
 }
 
 type TokenResponse struct {
@@ -61,6 +146,21 @@ type TokenResponse struct {
 
 var validate = validator.New()
 
+// requestIP returns the best-effort client IP for display purposes (e.g. the
+// session list shown to a user). It prefers the first address in
+// X-Forwarded-For when present, since requests typically arrive through a
+// proxy in front of this service; unlike middlewares.RateLimiter.clientIP it
+// does not validate against a trusted-proxy list, so it must not be used for
+// security decisions.
+func requestIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		if first := strings.TrimSpace(strings.Split(xff, ",")[0]); first != "" {
+			return first
+		}
+	}
+	return r.RemoteAddr
+}
+
 func (ac *AuthController) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -73,8 +173,12 @@ func (ac *AuthController) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := ac.authService.Register(req.Email, req.Password, req.FirstName, req.LastName)
+	deviceInfo := r.Header.Get("User-Agent")
+	ip := requestIP(r)
+
+	err := ac.authService.Register(r.Context(), req.Email, req.Password, req.FirstName, req.LastName)
 	if err != nil {
+		ac.auditWithEmail(r, audit.EventRegister, audit.OutcomeFailure, 0, req.Email, ip, deviceInfo, err.Error())
 		if err.Error() == "user already exists" {
 			response.JSONError(w, err.Error(), http.StatusConflict)
 			return
@@ -83,6 +187,7 @@ func (ac *AuthController) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ac.auditWithEmail(r, audit.EventRegister, audit.OutcomeSuccess, 0, req.Email, ip, deviceInfo, "")
 	response.JSONResponse(w, map[string]string{"message": "User registered successfully"}, http.StatusCreated)
 }
 
@@ -99,10 +204,41 @@ func (ac *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	deviceInfo := r.Header.Get("User-Agent")
-	ip := r.RemoteAddr
+	ip := requestIP(r)
 
-	accessToken, refreshToken, err := ac.authService.LoginWithRefresh(req.Email, req.Password, deviceInfo, ip)
+	accessToken, refreshToken, err := ac.authService.LoginWithRefresh(r.Context(), req.Email, req.Password, deviceInfo, ip, req.Scopes)
 	if err != nil {
+		ac.auditWithEmail(r, audit.EventLogin, audit.OutcomeFailure, 0, req.Email, ip, deviceInfo, err.Error())
+		if errors.Is(err, services.ErrMFARequired) {
+			mfaToken, tokenErr := ac.authService.IssueMFAToken(req.Email)
+			if tokenErr != nil {
+				response.JSONError(w, "Invalid credentials", http.StatusUnauthorized)
+				return
+			}
+			response.JSONResponse(w, MFARequiredResponse{MFARequired: true, MFAToken: mfaToken}, http.StatusAccepted)
+			return
+		}
+		if err.Error() == "password has expired and must be changed" {
+			changeToken, tokenErr := ac.authService.IssuePasswordChangeToken(req.Email)
+			if tokenErr != nil {
+				response.JSONError(w, "Invalid credentials", http.StatusUnauthorized)
+				return
+			}
+			response.JSONResponse(w, map[string]string{
+				"error":                 "password_expired",
+				"password_change_token": changeToken,
+			}, http.StatusForbidden)
+			return
+		}
+		if err.Error() == "account is locked due to too many failed attempts" {
+			if status, statusErr := ac.authService.GetLockoutStatus(r.Context(), req.Email); statusErr == nil && !status.NextAttemptAt.IsZero() {
+				if retryAfter := int(time.Until(status.NextAttemptAt).Seconds()); retryAfter > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				}
+			}
+			response.JSONError(w, err.Error(), http.StatusLocked)
+			return
+		}
 		response.JSONError(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
@@ -113,6 +249,8 @@ func (ac *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ac.auditWithEmail(r, audit.EventLogin, audit.OutcomeSuccess, user.ID, req.Email, ip, deviceInfo, "")
+
 	resp := LoginResponse{
 		TokenResponse: TokenResponse{
 			AccessToken:  accessToken,
@@ -126,6 +264,115 @@ func (ac *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 	response.JSONResponse(w, resp, http.StatusOK)
 }
 
+// LoginMFA completes a login that Login reported as mfa_required, by
+// presenting the mfa_token it returned alongside the current TOTP (or a
+// recovery) code.
+func (ac *AuthController) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req LoginMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deviceInfo := r.Header.Get("User-Agent")
+	ip := requestIP(r)
+
+	accessToken, refreshToken, err := ac.authService.CompleteMFALogin(req.MFAToken, req.Code, deviceInfo, ip)
+	if err != nil {
+		ac.audit(r, audit.EventLogin, audit.OutcomeFailure, 0, ip, deviceInfo, err.Error())
+		response.JSONError(w, "Invalid or expired MFA code", http.StatusUnauthorized)
+		return
+	}
+
+	ac.audit(r, audit.EventLogin, audit.OutcomeSuccess, 0, ip, deviceInfo, "")
+
+	resp := TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(ac.authService.GetJWTExpiry().Seconds()),
+	}
+
+	response.JSONResponse(w, resp, http.StatusOK)
+}
+
+// Revoke handles RFC 7009 style token revocation. Per the RFC, an
+// unrecognized or already-invalid token must not cause an error response, so
+// this always returns 200 once the request body itself is well-formed.
+func (ac *AuthController) Revoke(w http.ResponseWriter, r *http.Request) {
+	var req RevokeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_ = ac.authService.RevokeAny(req.Token, req.TokenTypeHint)
+
+	response.JSONResponse(w, map[string]string{"message": "Token revoked"}, http.StatusOK)
+}
+
+// Introspect handles RFC 7662 style token introspection. It requires basic
+// auth with either a ServiceClient registered via SetServiceClientRepo or
+// the static resource server credentials configured for this deployment;
+// the token itself may be either an access or a refresh token.
+func (ac *AuthController) Introspect(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || !ac.authenticateIntrospectionClient(clientID, clientSecret) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="introspect"`)
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req IntrospectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	introspection, err := ac.authService.IntrospectToken(req.Token)
+	if err != nil {
+		response.JSONError(w, "Failed to introspect token", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSONResponse(w, introspection, http.StatusOK)
+}
+
+// authenticateIntrospectionClient checks clientID/clientSecret against the
+// ServiceClient repo first, if one is configured, then falls back to the
+// static introspectionClientID/Secret pair so a deployment that hasn't
+// migrated to per-client credentials keeps working unchanged.
+func (ac *AuthController) authenticateIntrospectionClient(clientID, clientSecret string) bool {
+	if ac.serviceClients != nil {
+		client, err := ac.serviceClients.FindByClientID(clientID)
+		if err == nil {
+			return !client.Revoked() && client.CheckSecret(clientSecret)
+		}
+		if !errors.Is(err, repositories.ErrNotFound) {
+			return false
+		}
+	}
+
+	return ac.introspectionClientID != "" &&
+		subtle.ConstantTimeCompare([]byte(clientID), []byte(ac.introspectionClientID)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(clientSecret), []byte(ac.introspectionClientSecret)) == 1
+}
+
 func (ac *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
 	var req RefreshTokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -133,11 +380,16 @@ func (ac *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := ac.authService.Logout(req.RefreshToken); err != nil {
+	deviceInfo := r.Header.Get("User-Agent")
+	ip := requestIP(r)
+
+	if err := ac.authService.Logout(r.Context(), req.RefreshToken); err != nil {
+		ac.audit(r, audit.EventLogout, audit.OutcomeFailure, 0, ip, deviceInfo, err.Error())
 		response.JSONError(w, "Failed to logout", http.StatusInternalServerError)
 		return
 	}
 
+	ac.audit(r, audit.EventLogout, audit.OutcomeSuccess, 0, ip, deviceInfo, "")
 	response.JSONResponse(w, map[string]string{"message": "Successfully logged out"}, http.StatusOK)
 }
 
@@ -154,14 +406,36 @@ func (ac *AuthController) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	deviceInfo := r.Header.Get("User-Agent")
-	ip := r.RemoteAddr
+	ip := requestIP(r)
 
-	accessToken, refreshToken, err := ac.authService.RefreshToken(req.RefreshToken, deviceInfo, ip)
+	accessToken, refreshToken, err := ac.authService.RefreshToken(r.Context(), req.RefreshToken, deviceInfo, ip, req.Scopes)
 	if err != nil {
+		reason := err.Error()
+		if reason == "refresh token has already been used; all sessions in its family have been revoked" {
+			reason = "reuse_detected"
+		}
+		ac.audit(r, audit.EventRefresh, audit.OutcomeFailure, 0, ip, deviceInfo, reason)
+
+		if err.Error() == "requested scope exceeds the scope granted to this token" {
+			response.JSONError(w, "invalid_scope", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrRefreshTokenReused) {
+			// A distinct, stable "code" lets a client tell reuse apart from
+			// an ordinary expired/invalid token and react to it
+			// differently (e.g. force a full re-login instead of silently
+			// retrying), rather than pattern-matching the message text.
+			p := problem.New(http.StatusUnauthorized, "Refresh Token Reused", err.Error())
+			p.Extensions = map[string]any{"code": "refresh_reuse_detected"}
+			problem.Write(w, p)
+			return
+		}
 		response.JSONError(w, "Invalid or expired refresh token", http.StatusUnauthorized)
 		return
 	}
 
+	ac.audit(r, audit.EventRefresh, audit.OutcomeSuccess, 0, ip, deviceInfo, "")
+
 	resp := TokenResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
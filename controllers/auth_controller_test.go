@@ -2,14 +2,18 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
+	"EchoAuth/audit"
 	"EchoAuth/models"
+	"EchoAuth/services"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,18 +24,18 @@ type mockAuthService struct {
 	mock.Mock
 }
 
-func (m *mockAuthService) Register(email, password, firstName, lastName string) error {
-	args := m.Called(email, password, firstName, lastName)
+func (m *mockAuthService) Register(ctx context.Context, email, password, firstName, lastName string) error {
+	args := m.Called(ctx, email, password, firstName, lastName)
 	return args.Error(0)
 }
 
-func (m *mockAuthService) LoginWithRefresh(email, password, deviceInfo, ip string) (string, string, error) {
-	args := m.Called(email, password, deviceInfo, ip)
+func (m *mockAuthService) LoginWithRefresh(ctx context.Context, email, password, deviceInfo, ip string, scopes []string) (string, string, error) {
+	args := m.Called(ctx, email, password, deviceInfo, ip, scopes)
 	return args.String(0), args.String(1), args.Error(2)
 }
 
-func (m *mockAuthService) Logout(token string) error {
-	args := m.Called(token)
+func (m *mockAuthService) Logout(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
 	return args.Error(0)
 }
 
@@ -43,8 +47,8 @@ func (m *mockAuthService) ValidateToken(token string) (*models.TokenClaims, erro
 	return args.Get(0).(*models.TokenClaims), args.Error(1)
 }
 
-func (m *mockAuthService) RefreshToken(refreshToken, deviceInfo, ip string) (string, string, error) {
-	args := m.Called(refreshToken, deviceInfo, ip)
+func (m *mockAuthService) RefreshToken(ctx context.Context, refreshToken, deviceInfo, ip string, scopes []string) (string, string, error) {
+	args := m.Called(ctx, refreshToken, deviceInfo, ip, scopes)
 	return args.String(0), args.String(1), args.Error(2)
 }
 
@@ -66,6 +70,39 @@ func (m *mockAuthService) LogoutWithRefresh(token string) error {
 	return args.Error(0)
 }
 
+func (m *mockAuthService) IssuePasswordChangeToken(email string) (string, error) {
+	args := m.Called(email)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockAuthService) RevokeAny(token, tokenTypeHint string) error {
+	args := m.Called(token, tokenTypeHint)
+	return args.Error(0)
+}
+
+func (m *mockAuthService) IntrospectToken(token string) (*models.Introspection, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Introspection), args.Error(1)
+}
+
+func (m *mockAuthService) GetLockoutStatus(ctx context.Context, email string) (services.LockoutStatus, error) {
+	args := m.Called(ctx, email)
+	return args.Get(0).(services.LockoutStatus), args.Error(1)
+}
+
+func (m *mockAuthService) IssueMFAToken(email string) (string, error) {
+	args := m.Called(email)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockAuthService) CompleteMFALogin(mfaToken, code, deviceInfo, ip string) (string, string, error) {
+	args := m.Called(mfaToken, code, deviceInfo, ip)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
 func TestAuthControllerRegister(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -83,7 +120,7 @@ func TestAuthControllerRegister(t *testing.T) {
 				LastName:  "User",
 			},
 			setupMock: func(mockService *mockAuthService) {
-				mockService.On("Register", "test@example.com", "password123", "Test", "User").
+				mockService.On("Register", mock.Anything, "test@example.com", "password123", "Test", "User").
 					Return(nil)
 			},
 			expectedStatus: http.StatusCreated,
@@ -94,7 +131,7 @@ func TestAuthControllerRegister(t *testing.T) {
 			requestBody:    "invalid json",
 			setupMock:      func(mockService *mockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Invalid request body"}`,
+			expectedBody:   `{"error":"Invalid request body","type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request body"}`,
 		},
 		{
 			name: "Missing required fields",
@@ -106,7 +143,7 @@ func TestAuthControllerRegister(t *testing.T) {
 			},
 			setupMock:      func(mock *mockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Key: 'RegisterRequest.Email' Error:Field validation for 'Email' failed on the 'required' tag\nKey: 'RegisterRequest.Password' Error:Field validation for 'Password' failed on the 'required' tag"}`,
+			expectedBody:   `{"error":"Key: 'RegisterRequest.Email' Error:Field validation for 'Email' failed on the 'required' tag\nKey: 'RegisterRequest.Password' Error:Field validation for 'Password' failed on the 'required' tag","type":"about:blank","title":"Bad Request","status":400,"detail":"Key: 'RegisterRequest.Email' Error:Field validation for 'Email' failed on the 'required' tag\nKey: 'RegisterRequest.Password' Error:Field validation for 'Password' failed on the 'required' tag"}`,
 		},
 		{
 			name: "User already exists",
@@ -117,11 +154,11 @@ func TestAuthControllerRegister(t *testing.T) {
 				LastName:  "User",
 			},
 			setupMock: func(mockService *mockAuthService) {
-				mockService.On("Register", "existing@example.com", "password123", "Test", "User").
+				mockService.On("Register", mock.Anything, "existing@example.com", "password123", "Test", "User").
 					Return(models.ErrUserExists)
 			},
 			expectedStatus: http.StatusConflict,
-			expectedBody:   `{"error":"user already exists"}`,
+			expectedBody:   `{"error":"user already exists","type":"about:blank","title":"Conflict","status":409,"detail":"user already exists"}`,
 		},
 	}
 
@@ -129,7 +166,7 @@ func TestAuthControllerRegister(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := new(mockAuthService)
 			tt.setupMock(mockService)
-			controller := NewAuthController(mockService)
+			controller := NewAuthController(mockService, "test-client", "test-secret", audit.NewNoopLogger())
 
 			var body []byte
 			var err error
@@ -167,7 +204,7 @@ func TestAuthControllerLogin(t *testing.T) {
 				Password: "password123",
 			},
 			setupMock: func(mockService *mockAuthService) {
-				mockService.On("LoginWithRefresh", "test@example.com", "password123", "test-user-agent", "127.0.0.1").
+				mockService.On("LoginWithRefresh", mock.Anything, "test@example.com", "password123", "test-user-agent", "127.0.0.1", []string(nil)).
 					Return("access-token", "refresh-token", nil)
 				mockService.On("GetJWTExpiry").Return(time.Hour * 24)
 				mockService.On("GetUserByEmail", "test@example.com").
@@ -188,7 +225,7 @@ func TestAuthControllerLogin(t *testing.T) {
 			requestBody:    "invalid json",
 			setupMock:      func(mockService *mockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Invalid request body"}`,
+			expectedBody:   `{"error":"Invalid request body","type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request body"}`,
 		},
 		{
 			name: "Invalid credentials",
@@ -197,11 +234,11 @@ func TestAuthControllerLogin(t *testing.T) {
 				Password: "wrongpass",
 			},
 			setupMock: func(mockService *mockAuthService) {
-				mockService.On("LoginWithRefresh", "test@example.com", "wrongpass", "test-user-agent", "127.0.0.1").
+				mockService.On("LoginWithRefresh", mock.Anything, "test@example.com", "wrongpass", "test-user-agent", "127.0.0.1", []string(nil)).
 					Return("", "", errors.New("invalid credentials"))
 			},
 			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   `{"error":"Invalid credentials"}`,
+			expectedBody:   `{"error":"Invalid credentials","type":"about:blank","title":"Unauthorized","status":401,"detail":"Invalid credentials"}`,
 		},
 		{
 			name: "Missing email",
@@ -210,7 +247,7 @@ func TestAuthControllerLogin(t *testing.T) {
 			},
 			setupMock:      func(mockService *mockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Key: 'LoginRequest.Email' Error:Field validation for 'Email' failed on the 'required' tag"}`,
+			expectedBody:   `{"error":"Key: 'LoginRequest.Email' Error:Field validation for 'Email' failed on the 'required' tag","type":"about:blank","title":"Bad Request","status":400,"detail":"Key: 'LoginRequest.Email' Error:Field validation for 'Email' failed on the 'required' tag"}`,
 		},
 		{
 			name: "Invalid email format",
@@ -220,7 +257,36 @@ func TestAuthControllerLogin(t *testing.T) {
 			},
 			setupMock:      func(mockService *mockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Key: 'LoginRequest.Email' Error:Field validation for 'Email' failed on the 'email' tag"}`,
+			expectedBody:   `{"error":"Key: 'LoginRequest.Email' Error:Field validation for 'Email' failed on the 'email' tag","type":"about:blank","title":"Bad Request","status":400,"detail":"Key: 'LoginRequest.Email' Error:Field validation for 'Email' failed on the 'email' tag"}`,
+		},
+		{
+			name: "Account locked",
+			requestBody: LoginRequest{
+				Email:    "locked@example.com",
+				Password: "password123",
+			},
+			setupMock: func(mockService *mockAuthService) {
+				mockService.On("LoginWithRefresh", mock.Anything, "locked@example.com", "password123", "test-user-agent", "127.0.0.1", []string(nil)).
+					Return("", "", services.ErrAccountLocked)
+				mockService.On("GetLockoutStatus", mock.Anything, "locked@example.com").
+					Return(services.LockoutStatus{}, errors.New("redis unavailable"))
+			},
+			expectedStatus: http.StatusLocked,
+			expectedBody:   `{"error":"account is locked due to too many failed attempts","type":"about:blank","title":"Locked","status":423,"detail":"account is locked due to too many failed attempts"}`,
+		},
+		{
+			name: "MFA required",
+			requestBody: LoginRequest{
+				Email:    "mfa@example.com",
+				Password: "password123",
+			},
+			setupMock: func(mockService *mockAuthService) {
+				mockService.On("LoginWithRefresh", mock.Anything, "mfa@example.com", "password123", "test-user-agent", "127.0.0.1", []string(nil)).
+					Return("", "", services.ErrMFARequired)
+				mockService.On("IssueMFAToken", "mfa@example.com").Return("mfa-token", nil)
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `{"mfa_required":true,"mfa_token":"mfa-token"}`,
 		},
 	}
 
@@ -228,7 +294,7 @@ func TestAuthControllerLogin(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := new(mockAuthService)
 			tt.setupMock(mockService)
-			controller := NewAuthController(mockService)
+			controller := NewAuthController(mockService, "test-client", "test-secret", audit.NewNoopLogger())
 
 			var body []byte
 			var err error
@@ -253,6 +319,112 @@ func TestAuthControllerLogin(t *testing.T) {
 	}
 }
 
+func TestAuthControllerLoginLockedSetsRetryAfterHeader(t *testing.T) {
+	mockService := new(mockAuthService)
+	mockService.On("LoginWithRefresh", mock.Anything, "locked@example.com", "password123", "test-user-agent", "127.0.0.1", []string(nil)).
+		Return("", "", services.ErrAccountLocked)
+	mockService.On("GetLockoutStatus", mock.Anything, "locked@example.com").
+		Return(services.LockoutStatus{Locked: true, NextAttemptAt: time.Now().Add(5 * time.Minute)}, nil)
+	controller := NewAuthController(mockService, "test-client", "test-secret", audit.NewNoopLogger())
+
+	body, err := json.Marshal(LoginRequest{Email: "locked@example.com", Password: "password123"})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+	req.Header.Set("User-Agent", "test-user-agent")
+	req.RemoteAddr = "127.0.0.1"
+	w := httptest.NewRecorder()
+
+	controller.Login(w, req)
+
+	assert.Equal(t, http.StatusLocked, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, 0)
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthControllerLoginMFA(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		setupMock      func(mockService *mockAuthService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Valid MFA code",
+			requestBody: LoginMFARequest{
+				MFAToken: "mfa-token",
+				Code:     "123456",
+			},
+			setupMock: func(mockService *mockAuthService) {
+				mockService.On("CompleteMFALogin", "mfa-token", "123456", "test-user-agent", "127.0.0.1").
+					Return("access-token", "refresh-token", nil)
+				mockService.On("GetJWTExpiry").Return(time.Hour * 24)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"access_token":"access-token","refresh_token":"refresh-token","token_type":"Bearer","expires_in":86400}`,
+		},
+		{
+			name:           "Invalid request body",
+			requestBody:    "invalid json",
+			setupMock:      func(mockService *mockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Invalid request body","type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request body"}`,
+		},
+		{
+			name: "Invalid MFA code",
+			requestBody: LoginMFARequest{
+				MFAToken: "mfa-token",
+				Code:     "000000",
+			},
+			setupMock: func(mockService *mockAuthService) {
+				mockService.On("CompleteMFALogin", "mfa-token", "000000", "test-user-agent", "127.0.0.1").
+					Return("", "", errors.New("invalid or expired MFA code"))
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Invalid or expired MFA code","type":"about:blank","title":"Unauthorized","status":401,"detail":"Invalid or expired MFA code"}`,
+		},
+		{
+			name: "Missing code",
+			requestBody: LoginMFARequest{
+				MFAToken: "mfa-token",
+			},
+			setupMock:      func(mockService *mockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Key: 'LoginMFARequest.Code' Error:Field validation for 'Code' failed on the 'required' tag","type":"about:blank","title":"Bad Request","status":400,"detail":"Key: 'LoginMFARequest.Code' Error:Field validation for 'Code' failed on the 'required' tag"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockAuthService)
+			tt.setupMock(mockService)
+			controller := NewAuthController(mockService, "test-client", "test-secret", audit.NewNoopLogger())
+
+			var body []byte
+			var err error
+			if str, ok := tt.requestBody.(string); ok {
+				body = []byte(str)
+			} else {
+				body, err = json.Marshal(tt.requestBody)
+				require.NoError(t, err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/login/mfa", bytes.NewReader(body))
+			req.Header.Set("User-Agent", "test-user-agent")
+			req.RemoteAddr = "127.0.0.1"
+			w := httptest.NewRecorder()
+
+			controller.LoginMFA(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestAuthControllerLogout(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -269,7 +441,7 @@ func TestAuthControllerLogout(t *testing.T) {
 			},
 			setupAuth: "Bearer test-access-token",
 			setupMock: func(mockService *mockAuthService) {
-				mockService.On("Logout", "test-access-token").Return(nil)
+				mockService.On("Logout", mock.Anything, "test-access-token").Return(nil)
 				mockService.On("LogoutWithRefresh", "test-refresh-token").Return(nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -281,7 +453,7 @@ func TestAuthControllerLogout(t *testing.T) {
 			setupAuth:      "Bearer test-access-token",
 			setupMock:      func(mockService *mockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Invalid request body"}`,
+			expectedBody:   `{"error":"Invalid request body","type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request body"}`,
 		},
 		{
 			name: "Missing auth header",
@@ -291,7 +463,7 @@ func TestAuthControllerLogout(t *testing.T) {
 			setupAuth:      "",
 			setupMock:      func(mockService *mockAuthService) {},
 			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   `{"error":"Authorization header required"}`,
+			expectedBody:   `{"error":"Authorization header required","type":"about:blank","title":"Unauthorized","status":401,"detail":"Authorization header required"}`,
 		},
 	}
 
@@ -299,7 +471,7 @@ func TestAuthControllerLogout(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := new(mockAuthService)
 			tt.setupMock(mockService)
-			controller := NewAuthController(mockService)
+			controller := NewAuthController(mockService, "test-client", "test-secret", audit.NewNoopLogger())
 
 			var body []byte
 			var err error
@@ -339,7 +511,7 @@ func TestAuthControllerRefreshToken(t *testing.T) {
 				RefreshToken: "valid_refresh_token",
 			},
 			setupMock: func(mockService *mockAuthService) {
-				mockService.On("RefreshToken", "valid_refresh_token", "test-user-agent", "127.0.0.1").
+				mockService.On("RefreshToken", mock.Anything, "valid_refresh_token", "test-user-agent", "127.0.0.1", []string(nil)).
 					Return("new_access_token", "new_refresh_token", nil)
 				mockService.On("GetJWTExpiry").Return(time.Hour * 24)
 			},
@@ -351,7 +523,7 @@ func TestAuthControllerRefreshToken(t *testing.T) {
 			requestBody:    "invalid json",
 			setupMock:      func(mockService *mockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Invalid request body"}`,
+			expectedBody:   `{"error":"Invalid request body","type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request body"}`,
 		},
 		{
 			name: "Missing refresh token",
@@ -360,7 +532,7 @@ func TestAuthControllerRefreshToken(t *testing.T) {
 			},
 			setupMock:      func(mockService *mockAuthService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"Key: 'RefreshTokenRequest.RefreshToken' Error:Field validation for 'RefreshToken' failed on the 'required' tag"}`,
+			expectedBody:   `{"error":"Key: 'RefreshTokenRequest.RefreshToken' Error:Field validation for 'RefreshToken' failed on the 'required' tag","type":"about:blank","title":"Bad Request","status":400,"detail":"Key: 'RefreshTokenRequest.RefreshToken' Error:Field validation for 'RefreshToken' failed on the 'required' tag"}`,
 		},
 		{
 			name: "Invalid refresh token",
@@ -368,11 +540,23 @@ func TestAuthControllerRefreshToken(t *testing.T) {
 				RefreshToken: "invalid_token",
 			},
 			setupMock: func(mockService *mockAuthService) {
-				mockService.On("RefreshToken", "invalid_token", "test-user-agent", "127.0.0.1").
+				mockService.On("RefreshToken", mock.Anything, "invalid_token", "test-user-agent", "127.0.0.1", []string(nil)).
 					Return("", "", errors.New("invalid token"))
 			},
 			expectedStatus: http.StatusUnauthorized,
-			expectedBody:   `{"error":"Invalid or expired refresh token"}`,
+			expectedBody:   `{"error":"Invalid or expired refresh token","type":"about:blank","title":"Unauthorized","status":401,"detail":"Invalid or expired refresh token"}`,
+		},
+		{
+			name: "Reused refresh token",
+			requestBody: RefreshTokenRequest{
+				RefreshToken: "reused_refresh_token",
+			},
+			setupMock: func(mockService *mockAuthService) {
+				mockService.On("RefreshToken", mock.Anything, "reused_refresh_token", "test-user-agent", "127.0.0.1", []string(nil)).
+					Return("", "", services.ErrRefreshTokenReused)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"type":"about:blank","title":"Refresh Token Reused","status":401,"detail":"refresh token has already been used; all sessions in its family have been revoked","code":"refresh_reuse_detected"}`,
 		},
 	}
 
@@ -380,7 +564,7 @@ func TestAuthControllerRefreshToken(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService := new(mockAuthService)
 			tt.setupMock(mockService)
-			controller := NewAuthController(mockService)
+			controller := NewAuthController(mockService, "test-client", "test-secret", audit.NewNoopLogger())
 
 			var body []byte
 			var err error
@@ -404,3 +588,144 @@ func TestAuthControllerRefreshToken(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthControllerRevoke(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		setupMock      func(mockService *mockAuthService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Valid refresh token",
+			requestBody: RevokeRequest{
+				Token:         "some_refresh_token",
+				TokenTypeHint: "refresh_token",
+			},
+			setupMock: func(mockService *mockAuthService) {
+				mockService.On("RevokeAny", "some_refresh_token", "refresh_token").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Token revoked"}`,
+		},
+		{
+			name:           "Invalid request body",
+			requestBody:    "invalid json",
+			setupMock:      func(mockService *mockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Invalid request body","type":"about:blank","title":"Bad Request","status":400,"detail":"Invalid request body"}`,
+		},
+		{
+			name:           "Missing token",
+			requestBody:    RevokeRequest{},
+			setupMock:      func(mockService *mockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Key: 'RevokeRequest.Token' Error:Field validation for 'Token' failed on the 'required' tag","type":"about:blank","title":"Bad Request","status":400,"detail":"Key: 'RevokeRequest.Token' Error:Field validation for 'Token' failed on the 'required' tag"}`,
+		},
+		{
+			name: "Unrecognized token still returns 200 per RFC 7009",
+			requestBody: RevokeRequest{
+				Token: "does_not_exist",
+			},
+			setupMock: func(mockService *mockAuthService) {
+				mockService.On("RevokeAny", "does_not_exist", "").Return(errors.New("record not found"))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Token revoked"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockAuthService)
+			tt.setupMock(mockService)
+			controller := NewAuthController(mockService, "test-client", "test-secret", audit.NewNoopLogger())
+
+			var body []byte
+			var err error
+			if str, ok := tt.requestBody.(string); ok {
+				body = []byte(str)
+			} else {
+				body, err = json.Marshal(tt.requestBody)
+				require.NoError(t, err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/revoke", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			controller.Revoke(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthControllerIntrospect(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		basicAuthUser  string
+		basicAuthPass  string
+		setupMock      func(mockService *mockAuthService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:          "Active token",
+			requestBody:   IntrospectRequest{Token: "some_access_token"},
+			basicAuthUser: "test-client",
+			basicAuthPass: "test-secret",
+			setupMock: func(mockService *mockAuthService) {
+				mockService.On("IntrospectToken", "some_access_token").Return(&models.Introspection{
+					Active:    true,
+					Subject:   "1",
+					TokenType: "access_token",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"active":true,"sub":"1","token_type":"access_token"}`,
+		},
+		{
+			name:           "Wrong client credentials",
+			requestBody:    IntrospectRequest{Token: "some_access_token"},
+			basicAuthUser:  "wrong-client",
+			basicAuthPass:  "wrong-secret",
+			setupMock:      func(mockService *mockAuthService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"Unauthorized","type":"about:blank","title":"Unauthorized","status":401,"detail":"Unauthorized"}`,
+		},
+		{
+			name:           "Missing token",
+			requestBody:    IntrospectRequest{},
+			basicAuthUser:  "test-client",
+			basicAuthPass:  "test-secret",
+			setupMock:      func(mockService *mockAuthService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"Key: 'IntrospectRequest.Token' Error:Field validation for 'Token' failed on the 'required' tag","type":"about:blank","title":"Bad Request","status":400,"detail":"Key: 'IntrospectRequest.Token' Error:Field validation for 'Token' failed on the 'required' tag"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockAuthService)
+			tt.setupMock(mockService)
+			controller := NewAuthController(mockService, "test-client", "test-secret", audit.NewNoopLogger())
+
+			body, err := json.Marshal(tt.requestBody)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/introspect", bytes.NewReader(body))
+			req.SetBasicAuth(tt.basicAuthUser, tt.basicAuthPass)
+			w := httptest.NewRecorder()
+
+			controller.Introspect(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"EchoAuth/models"
+	"EchoAuth/utils/response"
+
+	"github.com/gorilla/mux"
+)
+
+// EmailVerificationServiceInterface issues and redeems tokens that confirm a
+// user controls the email address they registered with.
+type EmailVerificationServiceInterface interface {
+	GenerateVerificationToken(email string) (string, error)
+	ValidateVerificationToken(token string) (*models.User, error)
+	ConfirmEmail(token string) error
+}
+
+type EmailVerificationController struct {
+	verificationService EmailVerificationServiceInterface
+}
+
+func NewEmailVerificationController(verificationService EmailVerificationServiceInterface) *EmailVerificationController {
+	return &EmailVerificationController{
+		verificationService: verificationService,
+	}
+}
+
+type RequestVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// RequestVerification sends a verification email for the given address.
+// The response doesn't reveal whether the address is registered.
+func (c *EmailVerificationController) RequestVerification(w http.ResponseWriter, r *http.Request) {
+	var req RequestVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.verificationService.GenerateVerificationToken(req.Email)
+
+	response.JSONResponse(w, map[string]string{
+		"message": "If your email is registered, you will receive a verification link shortly",
+	}, http.StatusOK)
+}
+
+// ConfirmEmail handles the link a user follows from their verification email.
+func (c *EmailVerificationController) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	if err := c.verificationService.ConfirmEmail(token); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response.JSONResponse(w, map[string]string{
+		"message": "Email verified successfully",
+	}, http.StatusOK)
+}
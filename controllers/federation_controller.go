@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+	"EchoAuth/services"
+	"EchoAuth/utils/metrics"
+	"EchoAuth/utils/response"
+
+	"github.com/gorilla/mux"
+)
+
+// SchemeRegistry resolves a named authentication scheme (oauth provider,
+// saml, ...) for FederationController to dispatch to.
+type SchemeRegistry interface {
+	Get(name string) (services.Scheme, error)
+}
+
+// Connectors starts and completes PKCE-protected OAuth2 authorization code
+// flows, ahead of the plain Login a SchemeRegistry dispatches to - it owns
+// generating the authorization URL and guarding the callback against
+// CSRF/code-injection via the state parameter.
+type Connectors interface {
+	StartLogin(ctx context.Context, provider string) (authURL, state string, err error)
+	// StartLink is StartLogin for an already-authenticated user linking an
+	// additional provider to their account rather than logging in with it.
+	StartLink(ctx context.Context, provider string, userID uint) (authURL, state string, err error)
+	// CompleteLogin redeems a state/code pair from either flow. linked
+	// reports whether state came from StartLink (the resolved identity was
+	// linked to that flow's user) or StartLogin (a fresh login/auto-
+	// provision), so the caller knows whether to issue tokens or just
+	// confirm the link.
+	CompleteLogin(ctx context.Context, provider, state, code string) (user *models.User, linked bool, err error)
+}
+
+// TokenIssuer mints an access/refresh token pair for a user who has already
+// been authenticated by a scheme, without re-checking a password.
+type TokenIssuer interface {
+	IssueTokensForUser(user *models.User, deviceInfo, ip string) (string, string, error)
+}
+
+// FederationController handles login callbacks for non-native authentication
+// schemes (OAuth, SAML), dispatching to the scheme registered for the route
+// and then issuing EchoAuth's own tokens for the resolved user.
+type FederationController struct {
+	schemes    SchemeRegistry
+	connectors Connectors
+	tokens     TokenIssuer
+}
+
+func NewFederationController(schemes SchemeRegistry, connectors Connectors, tokens TokenIssuer) *FederationController {
+	return &FederationController{
+		schemes:    schemes,
+		connectors: connectors,
+		tokens:     tokens,
+	}
+}
+
+// OAuthStart begins an authorization-code flow for the {provider} in the
+// route, e.g. /auth/oauth/google/start, redirecting the browser to the
+// provider's consent screen with a freshly generated CSRF state and PKCE
+// challenge.
+func (fc *FederationController) OAuthStart(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+
+	authURL, _, err := fc.connectors.StartLogin(r.Context(), provider)
+	if err != nil {
+		response.JSONError(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OAuthCallback completes an authorization-code flow for the {provider} in
+// the route, e.g. /auth/oauth/google/callback?code=...&state=.... The same
+// endpoint serves both a login flow (started by OAuthStart) and a link flow
+// (started by LinkIdentity), since the provider redirects here regardless of
+// which one it was; CompleteLogin's linked return tells them apart.
+func (fc *FederationController) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider := mux.Vars(r)["provider"]
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		response.JSONError(w, "Missing authorization code", http.StatusBadRequest)
+		return
+	}
+	state := r.URL.Query().Get("state")
+	if state == "" {
+		response.JSONError(w, "Missing state parameter", http.StatusBadRequest)
+		return
+	}
+
+	user, linked, err := fc.connectors.CompleteLogin(r.Context(), provider, state, code)
+	if err != nil {
+		metrics.RecordAuthenticationAttempt(false, provider)
+		if errors.Is(err, services.ErrOAuthStateInvalid) {
+			response.JSONError(w, "Invalid or expired state", http.StatusBadRequest)
+			return
+		}
+		response.JSONError(w, "Authentication failed", http.StatusUnauthorized)
+		return
+	}
+	metrics.RecordAuthenticationAttempt(true, provider)
+
+	if linked {
+		response.JSONResponse(w, map[string]string{"message": "Identity linked successfully"}, http.StatusOK)
+		return
+	}
+
+	fc.issueTokens(w, r, user)
+}
+
+// LinkIdentity starts an authorization-code flow that links {provider} to
+// the authenticated user's account, e.g. POST /me/identities/google/link.
+// Unlike OAuthStart, the caller authenticates with a bearer token rather
+// than a browser session cookie, so the provider's consent screen URL is
+// returned as JSON for the client to navigate to, rather than issuing a 302
+// the client's own Authorization header wouldn't survive anyway.
+func (fc *FederationController) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	provider := mux.Vars(r)["provider"]
+
+	authURL, _, err := fc.connectors.StartLink(r.Context(), provider, userID)
+	if err != nil {
+		response.JSONError(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+
+	response.JSONResponse(w, map[string]string{"authorization_url": authURL}, http.StatusOK)
+}
+
+// UnlinkIdentity removes {provider} as a linked identity from the
+// authenticated user's account, e.g. DELETE /me/identities/google.
+func (fc *FederationController) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	provider := mux.Vars(r)["provider"]
+
+	scheme, err := fc.schemes.Get(provider)
+	if err != nil {
+		response.JSONError(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+
+	if err := scheme.Remove(&models.User{ID: userID}); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			response.JSONError(w, "No linked identity for this provider", http.StatusNotFound)
+			return
+		}
+		response.JSONError(w, "Failed to unlink identity", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSONResponse(w, map[string]string{"message": "Identity unlinked"}, http.StatusOK)
+}
+
+// LDAPLoginRequest carries the username/password an LDAPLogin caller binds
+// against the configured directory with, rather than a locally stored
+// password.
+type LDAPLoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// LDAPLogin authenticates against the "ldap" scheme directly - unlike
+// OAuthStart/OAuthCallback, a directory bind has no authorization redirect
+// for the client to follow, so the credentials are posted here and tokens
+// are issued immediately on a successful bind.
+func (fc *FederationController) LDAPLogin(w http.ResponseWriter, r *http.Request) {
+	var req LDAPLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scheme, err := fc.schemes.Get("ldap")
+	if err != nil {
+		response.JSONError(w, "LDAP login is not configured", http.StatusNotFound)
+		return
+	}
+
+	user, err := scheme.Login(r.Context(), services.LDAPCredentials{Username: req.Username, Password: req.Password})
+	if err != nil {
+		metrics.RecordAuthenticationAttempt(false, "ldap")
+		response.JSONError(w, "Authentication failed", http.StatusUnauthorized)
+		return
+	}
+	metrics.RecordAuthenticationAttempt(true, "ldap")
+
+	fc.issueTokens(w, r, user)
+}
+
+// SAMLACS is the SAML 2.0 assertion consumer service endpoint. Until
+// SAMLScheme validates assertions, this always fails closed.
+func (fc *FederationController) SAMLACS(w http.ResponseWriter, r *http.Request) {
+	scheme, err := fc.schemes.Get("saml")
+	if err != nil {
+		response.JSONError(w, "SAML is not configured", http.StatusNotFound)
+		return
+	}
+
+	// A real implementation would parse and validate the SAMLResponse form
+	// field here before building the assertion below.
+	if _, err := scheme.Login(r.Context(), services.SAMLAssertion{}); err != nil {
+		response.JSONError(w, "Authentication failed", http.StatusUnauthorized)
+		return
+	}
+}
+
+func (fc *FederationController) issueTokens(w http.ResponseWriter, r *http.Request, user *models.User) {
+	deviceInfo := r.Header.Get("User-Agent")
+	ip := r.RemoteAddr
+
+	accessToken, refreshToken, err := fc.tokens.IssueTokensForUser(user, deviceInfo, ip)
+	if err != nil {
+		response.JSONError(w, "Failed to issue tokens", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSONResponse(w, TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+	}, http.StatusOK)
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -20,14 +21,26 @@ type RedisInterface interface {
 }
 
 type HealthController struct {
-	db    DBInterface
-	redis RedisInterface
+	db                 DBInterface
+	redis              RedisInterface
+	refreshIdleTimeout time.Duration
+
+	// mu guards checkers, cacheTTL, cache, cachedAt and checkerState,
+	// since Ready/Startup can run concurrently with RegisterChecker calls
+	// made after the router has already started serving traffic.
+	mu           sync.Mutex
+	checkers     []HealthChecker
+	cacheTTL     time.Duration
+	cache        []dependencyStatus
+	cachedAt     time.Time
+	checkerState map[string]*dependencyStatus
 }
 
 type HealthResponse struct {
 	Status    string            `json:"status"`
 	Timestamp time.Time         `json:"timestamp"`
 	Services  map[string]string `json:"services"`
+	Config    map[string]string `json:"config"`
 }
 
 // gormDBAdapter adapts *gorm.DB to DBInterface
@@ -39,11 +52,27 @@ func (g *gormDBAdapter) DB() (*sql.DB, error) {
 	return g.gormDB.DB()
 }
 
-func NewHealthController(db *gorm.DB, redis *redis.Client) *HealthController {
-	return &HealthController{
-		db:    &gormDBAdapter{gormDB: db},
-		redis: redis,
+func NewHealthController(db *gorm.DB, redis *redis.Client, refreshIdleTimeout time.Duration) *HealthController {
+	dbAdapter := &gormDBAdapter{gormDB: db}
+	h := &HealthController{
+		db:                 dbAdapter,
+		redis:              redis,
+		refreshIdleTimeout: refreshIdleTimeout,
+		cacheTTL:           defaultHealthCacheTTL,
+	}
+	h.RegisterChecker(dbHealthChecker{db: dbAdapter})
+	h.RegisterChecker(redisHealthChecker{redis: redis})
+	return h
+}
+
+// idleTimeoutLabel renders a configured idle timeout for operator-facing
+// output, since a zero-or-negative duration means the setting is disabled
+// rather than "immediately expire".
+func idleTimeoutLabel(d time.Duration) string {
+	if d <= 0 {
+		return "disabled"
 	}
+	return d.String()
 }
 
 func (h *HealthController) Check(w http.ResponseWriter, r *http.Request) {
@@ -79,6 +108,9 @@ func (h *HealthController) Check(w http.ResponseWriter, r *http.Request) {
 		Status:    overallStatus,
 		Timestamp: time.Now(),
 		Services:  services,
+		Config: map[string]string{
+			"refresh_token_idle_timeout": idleTimeoutLabel(h.refreshIdleTimeout),
+		},
 	}
 
 	// Send response
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// errNilDatabaseConnection mirrors the message Check already uses inline
+// for a nil *sql.DB, so a caller reading either endpoint's "database"
+// status sees the same wording.
+var errNilDatabaseConnection = errors.New("database connection is nil")
+
+// HealthChecker is a single dependency HealthController can probe for
+// /health/ready and /health/startup. Check should return quickly and
+// respect ctx's deadline; a checker that can hang (a slow network call)
+// should enforce its own timeout internally rather than relying on the
+// caller to kill it.
+type HealthChecker interface {
+	// Name identifies this checker in the response body and metrics; it
+	// should be a short, stable, lowercase token (e.g. "database").
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// HealthCheckerFunc adapts a plain function to a HealthChecker, for a
+// dependency simple enough not to need its own named type.
+type HealthCheckerFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewHealthCheckerFunc builds a HealthChecker named name that delegates to
+// fn.
+func NewHealthCheckerFunc(name string, fn func(ctx context.Context) error) HealthCheckerFunc {
+	return HealthCheckerFunc{name: name, fn: fn}
+}
+
+func (h HealthCheckerFunc) Name() string                    { return h.name }
+func (h HealthCheckerFunc) Check(ctx context.Context) error { return h.fn(ctx) }
+
+// dependencyStatus is the per-dependency detail returned by /health/ready
+// and /health/startup when ?verbose=1 is set, and is also kept internally
+// (without Error) to track LastSuccess/FailureCount across requests.
+type dependencyStatus struct {
+	Name         string    `json:"name"`
+	Healthy      bool      `json:"healthy"`
+	LatencyMS    int64     `json:"latency_ms"`
+	LastSuccess  time.Time `json:"last_success,omitempty"`
+	FailureCount int       `json:"failure_count"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// ReadinessResponse is the body returned by /health/ready and
+// /health/startup. Dependencies is only populated when the request asked
+// for ?verbose=1; otherwise a caller gets just the aggregate status, which
+// is all a probe actually acts on.
+type ReadinessResponse struct {
+	Status       string             `json:"status"`
+	Timestamp    time.Time          `json:"timestamp"`
+	Dependencies []dependencyStatus `json:"dependencies,omitempty"`
+}
+
+// defaultHealthCacheTTL bounds how often registered HealthCheckers are
+// actually invoked; a readiness probe firing every few seconds from every
+// pod replica shouldn't translate into that same query rate against
+// Postgres and Redis.
+const defaultHealthCacheTTL = 5 * time.Second
+
+// dbHealthChecker adapts HealthController's existing DBInterface into a
+// HealthChecker, so the database keeps being probed the same way Check
+// already does (DB() then Ping()) but through the pluggable mechanism.
+type dbHealthChecker struct{ db DBInterface }
+
+func (c dbHealthChecker) Name() string { return "database" }
+
+func (c dbHealthChecker) Check(ctx context.Context) error {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return err
+	}
+	if sqlDB == nil {
+		return errNilDatabaseConnection
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// redisHealthChecker adapts HealthController's existing RedisInterface.
+type redisHealthChecker struct{ redis RedisInterface }
+
+func (c redisHealthChecker) Name() string { return "redis" }
+
+func (c redisHealthChecker) Check(ctx context.Context) error {
+	return c.redis.Ping(ctx).Err()
+}
+
+// RegisterChecker adds c to the set of dependencies /health/ready and
+// /health/startup probe, in addition to the database and Redis checkers
+// NewHealthController registers by default. Call it once at startup for
+// each optional dependency (SMTP relay, an upstream OIDC issuer, ...).
+func (h *HealthController) RegisterChecker(c HealthChecker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checkers = append(h.checkers, c)
+}
+
+// SetCacheTTL overrides defaultHealthCacheTTL, e.g. to probe a
+// particularly expensive dependency less often.
+func (h *HealthController) SetCacheTTL(ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cacheTTL = ttl
+}
+
+// Live reports whether the process itself is alive, with no dependency
+// checks - the Kubernetes liveness-probe semantics of "should this
+// container be restarted", which a down database must never answer by
+// itself (restarting a healthy process doesn't fix the database).
+func (h *HealthController) Live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "alive"})
+}
+
+// Ready reports whether every registered HealthChecker currently passes -
+// the Kubernetes readiness-probe semantics of "should this pod receive
+// traffic". Results are served from h.cacheTTL's cache unless the cache
+// has expired, so a burst of probe requests only exercises the underlying
+// dependencies once per window. ?verbose=1 includes per-dependency detail;
+// without it the body carries only the aggregate status.
+func (h *HealthController) Ready(w http.ResponseWriter, r *http.Request) {
+	h.writeAggregate(w, r, h.snapshot(r.Context()))
+}
+
+// Startup reports the same aggregate as Ready, bypassing the cache - a
+// Kubernetes startup probe is polled on its own schedule until it first
+// succeeds and then is never consulted again, so caching it against Ready
+// or Live's TTL would only delay the one observation that matters.
+func (h *HealthController) Startup(w http.ResponseWriter, r *http.Request) {
+	h.writeAggregate(w, r, h.runCheckers(r.Context()))
+}
+
+// snapshot returns the cached checker results if they're younger than
+// h.cacheTTL, otherwise runs every registered checker and refreshes the
+// cache. A zero or negative cacheTTL (the zero value for a HealthController
+// built outside NewHealthController) disables caching rather than falling
+// back to defaultHealthCacheTTL, since a caller that bypassed the
+// constructor asked for exactly the fields it set.
+func (h *HealthController) snapshot(ctx context.Context) []dependencyStatus {
+	h.mu.Lock()
+	ttl := h.cacheTTL
+	if ttl > 0 && h.cache != nil && time.Since(h.cachedAt) < ttl {
+		cached := make([]dependencyStatus, len(h.cache))
+		copy(cached, h.cache)
+		h.mu.Unlock()
+		return cached
+	}
+	h.mu.Unlock()
+
+	results := h.runCheckers(ctx)
+
+	h.mu.Lock()
+	h.cache = results
+	h.cachedAt = time.Now()
+	h.mu.Unlock()
+
+	return results
+}
+
+// runCheckers invokes every registered HealthChecker, folding each result
+// into the rolling LastSuccess/FailureCount tracked for it in
+// h.checkerState.
+func (h *HealthController) runCheckers(ctx context.Context) []dependencyStatus {
+	h.mu.Lock()
+	checkers := make([]HealthChecker, len(h.checkers))
+	copy(checkers, h.checkers)
+	h.mu.Unlock()
+
+	results := make([]dependencyStatus, 0, len(checkers))
+	for _, c := range checkers {
+		start := time.Now()
+		err := c.Check(ctx)
+		latency := time.Since(start)
+
+		status := h.recordResult(c.Name(), err, latency)
+		results = append(results, status)
+	}
+	return results
+}
+
+// recordResult updates the rolling LastSuccess/FailureCount state kept for
+// checker name and returns the dependencyStatus reflecting it.
+func (h *HealthController) recordResult(name string, err error, latency time.Duration) dependencyStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.checkerState == nil {
+		h.checkerState = make(map[string]*dependencyStatus)
+	}
+	state, ok := h.checkerState[name]
+	if !ok {
+		state = &dependencyStatus{Name: name}
+		h.checkerState[name] = state
+	}
+
+	state.LatencyMS = latency.Milliseconds()
+	if err != nil {
+		state.Healthy = false
+		state.FailureCount++
+		state.Error = err.Error()
+	} else {
+		state.Healthy = true
+		state.FailureCount = 0
+		state.Error = ""
+		state.LastSuccess = time.Now()
+	}
+
+	return *state
+}
+
+// writeAggregate writes results as a ReadinessResponse, including
+// per-dependency detail only when r asked for ?verbose=1.
+func (h *HealthController) writeAggregate(w http.ResponseWriter, r *http.Request, results []dependencyStatus) {
+	resp := ReadinessResponse{Status: "ready", Timestamp: time.Now()}
+
+	for _, dep := range results {
+		if !dep.Healthy {
+			resp.Status = "not_ready"
+		}
+	}
+
+	if r.URL.Query().Get("verbose") == "1" {
+		resp.Dependencies = results
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != "ready" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthControllerLive(t *testing.T) {
+	controller := &HealthController{}
+
+	req := httptest.NewRequest(http.MethodGet, "/health/live", nil)
+	w := httptest.NewRecorder()
+	controller.Live(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body map[string]string
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, "alive", body["status"])
+}
+
+func TestHealthControllerReady(t *testing.T) {
+	t.Run("all checkers healthy", func(t *testing.T) {
+		controller := &HealthController{}
+		controller.RegisterChecker(NewHealthCheckerFunc("database", func(ctx context.Context) error { return nil }))
+		controller.RegisterChecker(NewHealthCheckerFunc("redis", func(ctx context.Context) error { return nil }))
+
+		req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+		w := httptest.NewRecorder()
+		controller.Ready(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp ReadinessResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, "ready", resp.Status)
+		assert.Nil(t, resp.Dependencies)
+	})
+
+	t.Run("one checker failing", func(t *testing.T) {
+		controller := &HealthController{}
+		controller.RegisterChecker(NewHealthCheckerFunc("database", func(ctx context.Context) error { return nil }))
+		controller.RegisterChecker(NewHealthCheckerFunc("smtp", func(ctx context.Context) error {
+			return errors.New("connection refused")
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/health/ready?verbose=1", nil)
+		w := httptest.NewRecorder()
+		controller.Ready(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var resp ReadinessResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, "not_ready", resp.Status)
+		assert.Len(t, resp.Dependencies, 2)
+	})
+
+	t.Run("results are cached within the TTL", func(t *testing.T) {
+		calls := 0
+		controller := &HealthController{cacheTTL: time.Hour}
+		controller.RegisterChecker(NewHealthCheckerFunc("database", func(ctx context.Context) error {
+			calls++
+			return nil
+		}))
+
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+			w := httptest.NewRecorder()
+			controller.Ready(w, req)
+		}
+
+		assert.Equal(t, 1, calls, "expected the checker to only run once within cacheTTL")
+	})
+
+	t.Run("failure count accumulates across requests", func(t *testing.T) {
+		controller := &HealthController{cacheTTL: -1}
+		controller.RegisterChecker(NewHealthCheckerFunc("database", func(ctx context.Context) error {
+			return errors.New("timeout")
+		}))
+
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/health/ready?verbose=1", nil)
+			w := httptest.NewRecorder()
+			controller.Ready(w, req)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/health/ready?verbose=1", nil)
+		w := httptest.NewRecorder()
+		controller.Ready(w, req)
+
+		var resp ReadinessResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, 4, resp.Dependencies[0].FailureCount)
+	})
+}
+
+func TestHealthControllerStartup(t *testing.T) {
+	calls := 0
+	controller := &HealthController{cacheTTL: time.Hour}
+	controller.RegisterChecker(NewHealthCheckerFunc("database", func(ctx context.Context) error {
+		calls++
+		return nil
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/health/startup", nil)
+	w := httptest.NewRecorder()
+	controller.Startup(w, req)
+
+	req2 := httptest.NewRequest(http.MethodGet, "/health/startup", nil)
+	w2 := httptest.NewRecorder()
+	controller.Startup(w2, req2)
+
+	assert.Equal(t, 2, calls, "Startup should bypass the readiness cache")
+	assert.Equal(t, http.StatusOK, w2.Code)
+}
+
+func TestDBHealthCheckerNilConnection(t *testing.T) {
+	checker := dbHealthChecker{db: &mockDB{}}
+	err := checker.Check(context.Background())
+	assert.ErrorIs(t, err, errNilDatabaseConnection)
+}
@@ -143,9 +143,43 @@ func TestNewHealthController(t *testing.T) {
 	db := &gorm.DB{}
 	redis := &redis.Client{}
 
-	controller := NewHealthController(db, redis)
+	controller := NewHealthController(db, redis, 30*time.Minute)
 
 	assert.NotNil(t, controller)
 	assert.IsType(t, &gormDBAdapter{}, controller.db)
 	assert.Equal(t, redis, controller.redis)
+	assert.Equal(t, 30*time.Minute, controller.refreshIdleTimeout)
+}
+
+func TestHealthCheckIncludesConfig(t *testing.T) {
+	controller := &HealthController{
+		db:                 &mockDB{},
+		redis:              &mockRedisClient{},
+		refreshIdleTimeout: 45 * time.Minute,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	controller.Check(w, req)
+
+	var response HealthResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "45m0s", response.Config["refresh_token_idle_timeout"])
+}
+
+func TestHealthCheckConfigDisabled(t *testing.T) {
+	controller := &HealthController{
+		db:    &mockDB{},
+		redis: &mockRedisClient{},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	controller.Check(w, req)
+
+	var response HealthResponse
+	err := json.NewDecoder(w.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, "disabled", response.Config["refresh_token_idle_timeout"])
 }
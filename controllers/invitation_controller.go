@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"EchoAuth/models"
+	"EchoAuth/utils/response"
+
+	"github.com/gorilla/mux"
+)
+
+// InvitationServiceInterface issues and redeems tokens that let an
+// admin-invited user claim their account and set their own password.
+type InvitationServiceInterface interface {
+	CreateInvitation(email string, roles []string, ttl time.Duration) (string, error)
+	ValidateInvitation(token string) (*models.User, error)
+	AcceptInvitation(token, password, firstName, lastName string) error
+}
+
+type InvitationController struct {
+	invitationService InvitationServiceInterface
+}
+
+func NewInvitationController(invitationService InvitationServiceInterface) *InvitationController {
+	return &InvitationController{
+		invitationService: invitationService,
+	}
+}
+
+type CreateInvitationRequest struct {
+	Email string   `json:"email" validate:"required,email"`
+	Roles []string `json:"roles"`
+	TTL   string   `json:"ttl"`
+}
+
+type AcceptInvitationRequest struct {
+	Password  string `json:"password" validate:"required"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// CreateInvitation handles a request to invite a new user by email. ttl, if
+// given, must be a Go duration string (e.g. "72h"); an empty or unparsable
+// value falls back to the service's default invitation TTL.
+func (c *InvitationController) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	var req CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil {
+			response.JSONError(w, "Invalid ttl", http.StatusBadRequest)
+			return
+		}
+		ttl = parsed
+	}
+
+	if _, err := c.invitationService.CreateInvitation(req.Email, req.Roles, ttl); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response.JSONResponse(w, map[string]string{
+		"message": "Invitation sent successfully",
+	}, http.StatusCreated)
+}
+
+// ValidateInvitation handles a request to check whether an invitation token
+// is still valid, returning the invited user so the client can pre-fill an
+// accept-invitation form.
+func (c *InvitationController) ValidateInvitation(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	user, err := c.invitationService.ValidateInvitation(token)
+	if err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response.JSONResponse(w, user, http.StatusOK)
+}
+
+// AcceptInvitation handles a new user claiming their account with the token
+// from their invitation email.
+func (c *InvitationController) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	var req AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.invitationService.AcceptInvitation(token, req.Password, req.FirstName, req.LastName); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response.JSONResponse(w, map[string]string{
+		"message": "Invitation accepted successfully",
+	}, http.StatusOK)
+}
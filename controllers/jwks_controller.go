@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"EchoAuth/keys"
+	"net/http"
+	"time"
+)
+
+// JWKSController exposes the active KeyManager's public keys at the
+// standard /.well-known/jwks.json path.
+type JWKSController struct {
+	handler http.HandlerFunc
+}
+
+// NewJWKSController builds a JWKSController. maxAge controls the
+// Cache-Control header so verifiers don't refetch on every request.
+func NewJWKSController(km keys.KeyManager, maxAge time.Duration) *JWKSController {
+	return &JWKSController{handler: keys.JWKSHandler(km, maxAge)}
+}
+
+func (c *JWKSController) Serve(w http.ResponseWriter, r *http.Request) {
+	c.handler(w, r)
+}
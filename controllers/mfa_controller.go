@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"EchoAuth/audit"
+	"EchoAuth/utils/logger"
+	"EchoAuth/utils/response"
+)
+
+// MFAServiceInterface enrolls and confirms TOTP-based two-factor
+// authentication for the authenticated user. Verifying a code at login time
+// goes through AuthService.CompleteMFALogin instead, since that path isn't
+// authenticated yet.
+type MFAServiceInterface interface {
+	EnrollTOTP(userID uint) (secret, otpauthURL string, recoveryCodes []string, err error)
+	ConfirmTOTP(userID uint, code string) error
+}
+
+type MFAController struct {
+	authService MFAServiceInterface
+	auditLogger audit.Logger
+}
+
+func NewMFAController(authService MFAServiceInterface, auditLogger audit.Logger) *MFAController {
+	return &MFAController{authService: authService, auditLogger: auditLogger}
+}
+
+func (mc *MFAController) audit(r *http.Request, typ audit.EventType, outcome audit.Outcome, userID uint, reason string) {
+	event := audit.NewEvent(typ, outcome, userID, requestIP(r), r.Header.Get("User-Agent"), reason, correlationID(r))
+	if err := mc.auditLogger.Log(event); err != nil {
+		logger.GetLogger("audit").Error().Err(err).Str("event_type", string(typ)).Msg("failed to write audit event")
+	}
+}
+
+// EnrollTOTPResponse carries the secret and recovery codes generated by
+// Enroll; both are shown to the user exactly this once.
+type EnrollTOTPResponse struct {
+	Secret        string   `json:"secret"`
+	OTPAuthURL    string   `json:"otpauth_url"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// Enroll generates a new TOTP secret and recovery codes for the
+// authenticated user. MFA isn't enabled until Confirm succeeds with a code
+// from the newly enrolled authenticator app.
+func (mc *MFAController) Enroll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	secret, otpauthURL, recoveryCodes, err := mc.authService.EnrollTOTP(userID)
+	if err != nil {
+		mc.audit(r, audit.EventMFAEnrolled, audit.OutcomeFailure, userID, err.Error())
+		response.JSONError(w, "Failed to enroll MFA", http.StatusInternalServerError)
+		return
+	}
+
+	mc.audit(r, audit.EventMFAEnrolled, audit.OutcomeSuccess, userID, "")
+	response.JSONResponse(w, EnrollTOTPResponse{
+		Secret:        secret,
+		OTPAuthURL:    otpauthURL,
+		RecoveryCodes: recoveryCodes,
+	}, http.StatusOK)
+}
+
+// Confirm activates MFA for the authenticated user once they prove their
+// authenticator app is set up correctly.
+func (mc *MFAController) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ConfirmTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := mc.authService.ConfirmTOTP(userID, req.Code); err != nil {
+		mc.audit(r, audit.EventMFAConfirmed, audit.OutcomeFailure, userID, err.Error())
+		response.JSONError(w, "Invalid or expired code", http.StatusBadRequest)
+		return
+	}
+
+	mc.audit(r, audit.EventMFAConfirmed, audit.OutcomeSuccess, userID, "")
+	response.JSONResponse(w, map[string]string{"message": "MFA enabled"}, http.StatusOK)
+}
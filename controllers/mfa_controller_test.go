@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"EchoAuth/audit"
+)
+
+// mockMFAService is a mock implementation of MFAServiceInterface.
+type mockMFAService struct {
+	enrollTOTPFunc  func(userID uint) (string, string, []string, error)
+	confirmTOTPFunc func(userID uint, code string) error
+}
+
+func (m *mockMFAService) EnrollTOTP(userID uint) (string, string, []string, error) {
+	return m.enrollTOTPFunc(userID)
+}
+
+func (m *mockMFAService) ConfirmTOTP(userID uint, code string) error {
+	return m.confirmTOTPFunc(userID, code)
+}
+
+func withUserID(req *http.Request, userID uint) *http.Request {
+	ctx := context.WithValue(req.Context(), "user_id", userID)
+	return req.WithContext(ctx)
+}
+
+func TestMFAController_Enroll(t *testing.T) {
+	tests := []struct {
+		name           string
+		authenticated  bool
+		setupMock      func(m *mockMFAService)
+		wantStatusCode int
+		description    string
+	}{
+		{
+			name:          "Successful enrollment",
+			authenticated: true,
+			setupMock: func(m *mockMFAService) {
+				m.enrollTOTPFunc = func(userID uint) (string, string, []string, error) {
+					return "SECRET", "otpauth://totp/EchoAuth:test@example.com?secret=SECRET", []string{"code1", "code2"}, nil
+				}
+			},
+			wantStatusCode: http.StatusOK,
+			description:    "Should return a secret, otpauth URL, and recovery codes",
+		},
+		{
+			name:          "Unauthenticated request",
+			authenticated: false,
+			setupMock: func(m *mockMFAService) {
+				m.enrollTOTPFunc = func(userID uint) (string, string, []string, error) {
+					return "", "", nil, nil
+				}
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			description:    "Should reject a request with no authenticated user",
+		},
+		{
+			name:          "Service error",
+			authenticated: true,
+			setupMock: func(m *mockMFAService) {
+				m.enrollTOTPFunc = func(userID uint) (string, string, []string, error) {
+					return "", "", nil, errors.New("user not found")
+				}
+			},
+			wantStatusCode: http.StatusInternalServerError,
+			description:    "Should surface an enrollment failure as a 500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockMFAService{}
+			tt.setupMock(mockService)
+			controller := NewMFAController(mockService, audit.NewNoopLogger())
+
+			req := httptest.NewRequest(http.MethodPost, "/mfa/enroll", nil)
+			if tt.authenticated {
+				req = withUserID(req, 1)
+			}
+			rec := httptest.NewRecorder()
+
+			controller.Enroll(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Errorf("Enroll() status code = %v, want %v", rec.Code, tt.wantStatusCode)
+			}
+
+			if tt.wantStatusCode == http.StatusOK {
+				var got EnrollTOTPResponse
+				if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if got.Secret == "" || got.OTPAuthURL == "" || len(got.RecoveryCodes) == 0 {
+					t.Errorf("Enroll() response = %+v, want populated secret/otpauth_url/recovery_codes", got)
+				}
+			}
+		})
+	}
+}
+
+func TestMFAController_Confirm(t *testing.T) {
+	tests := []struct {
+		name           string
+		authenticated  bool
+		requestBody    interface{}
+		setupMock      func(m *mockMFAService)
+		wantStatusCode int
+		description    string
+	}{
+		{
+			name:          "Valid code",
+			authenticated: true,
+			requestBody:   ConfirmTOTPRequest{Code: "123456"},
+			setupMock: func(m *mockMFAService) {
+				m.confirmTOTPFunc = func(userID uint, code string) error { return nil }
+			},
+			wantStatusCode: http.StatusOK,
+			description:    "Should activate MFA once the code is confirmed",
+		},
+		{
+			name:          "Invalid code",
+			authenticated: true,
+			requestBody:   ConfirmTOTPRequest{Code: "000000"},
+			setupMock: func(m *mockMFAService) {
+				m.confirmTOTPFunc = func(userID uint, code string) error { return errors.New("invalid or expired MFA code") }
+			},
+			wantStatusCode: http.StatusBadRequest,
+			description:    "Should reject a code that doesn't validate",
+		},
+		{
+			name:          "Unauthenticated request",
+			authenticated: false,
+			requestBody:   ConfirmTOTPRequest{Code: "123456"},
+			setupMock: func(m *mockMFAService) {
+				m.confirmTOTPFunc = func(userID uint, code string) error { return nil }
+			},
+			wantStatusCode: http.StatusUnauthorized,
+			description:    "Should reject a request with no authenticated user",
+		},
+		{
+			name:          "Missing code",
+			authenticated: true,
+			requestBody:   ConfirmTOTPRequest{Code: ""},
+			setupMock: func(m *mockMFAService) {
+				m.confirmTOTPFunc = func(userID uint, code string) error { return nil }
+			},
+			wantStatusCode: http.StatusBadRequest,
+			description:    "Should reject a request missing the code field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockMFAService{}
+			tt.setupMock(mockService)
+			controller := NewMFAController(mockService, audit.NewNoopLogger())
+
+			var body bytes.Buffer
+			if err := json.NewEncoder(&body).Encode(tt.requestBody); err != nil {
+				t.Fatalf("Failed to encode request body: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/mfa/confirm", &body)
+			req.Header.Set("Content-Type", "application/json")
+			if tt.authenticated {
+				req = withUserID(req, 1)
+			}
+			rec := httptest.NewRecorder()
+
+			controller.Confirm(rec, req)
+
+			if rec.Code != tt.wantStatusCode {
+				t.Errorf("Confirm() status code = %v, want %v", rec.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
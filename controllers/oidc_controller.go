@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"EchoAuth/models"
+	"EchoAuth/services"
+	"EchoAuth/utils/response"
+)
+
+// OIDCAuthService is the subset of AuthService the OIDC token/userinfo
+// endpoints need: issuing the refresh_token grant and looking up the user
+// behind an already-validated access token.
+type OIDCAuthService interface {
+	RefreshToken(ctx context.Context, refreshToken, deviceInfo, ip string, scopes []string) (string, string, error)
+	GetJWTExpiry() time.Duration
+	GetUserByID(id uint) (*models.User, error)
+}
+
+// OIDCTokenResponse is the RFC 6749 / OpenID Connect Core token endpoint
+// response body; IDToken is only populated for the authorization_code
+// grant.
+type OIDCTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// OIDCController serves EchoAuth's OpenID Connect authorization-server
+// endpoints: /oauth/authorize, /oauth/token and /oauth/userinfo.
+type OIDCController struct {
+	provider    *services.OIDCProvider
+	authService OIDCAuthService
+}
+
+// NewOIDCController builds an OIDCController.
+func NewOIDCController(provider *services.OIDCProvider, authService OIDCAuthService) *OIDCController {
+	return &OIDCController{provider: provider, authService: authService}
+}
+
+// Authorize issues an authorization code for the caller's authenticated
+// session. EchoAuth has no browser-session login to render a consent
+// screen against, so this is a bearer-protected JSON endpoint rather than a
+// redirect: the caller authenticates normally (e.g. via password login),
+// then calls this with the access token to get a code + redirect_uri to
+// hand back to the OAuth client's own redirect handling.
+func (oc *OIDCController) Authorize(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	clientID := query.Get("client_id")
+	redirectURI := query.Get("redirect_uri")
+	state := query.Get("state")
+	nonce := query.Get("nonce")
+	codeChallenge := query.Get("code_challenge")
+	codeChallengeMethod := query.Get("code_challenge_method")
+	var scopes []string
+	if scope := query.Get("scope"); scope != "" {
+		scopes = strings.Fields(scope)
+	}
+
+	if clientID == "" || redirectURI == "" {
+		response.JSONError(w, "client_id and redirect_uri are required", http.StatusBadRequest)
+		return
+	}
+
+	code, err := oc.provider.Authorize(r.Context(), clientID, redirectURI, scopes, nonce, codeChallenge, codeChallengeMethod, userID)
+	if err != nil {
+		response.JSONError(w, oauthErrorMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := redirectURI + "?code=" + code
+	if state != "" {
+		redirectURL += "&state=" + state
+	}
+
+	response.JSONResponse(w, map[string]string{"redirect_uri": redirectURL, "code": code, "state": state}, http.StatusOK)
+}
+
+// Token is the OAuth2 token endpoint, dispatching on grant_type to the
+// authorization_code (with PKCE), refresh_token, and client_credentials
+// grants. It takes the standard application/x-www-form-urlencoded body so
+// any off-the-shelf OAuth2/OIDC client library can call it unmodified.
+func (oc *OIDCController) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	deviceInfo := r.Header.Get("User-Agent")
+	ip := requestIP(r)
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		oc.tokenFromAuthorizationCode(w, r, deviceInfo, ip)
+	case "refresh_token":
+		oc.tokenFromRefreshToken(w, r, deviceInfo, ip)
+	case "client_credentials":
+		oc.tokenFromClientCredentials(w, r)
+	default:
+		response.JSONError(w, "unsupported_grant_type", http.StatusBadRequest)
+	}
+}
+
+func (oc *OIDCController) tokenFromAuthorizationCode(w http.ResponseWriter, r *http.Request, deviceInfo, ip string) {
+	clientID := r.PostForm.Get("client_id")
+	redirectURI := r.PostForm.Get("redirect_uri")
+	code := r.PostForm.Get("code")
+	codeVerifier := r.PostForm.Get("code_verifier")
+
+	accessToken, refreshToken, idToken, err := oc.provider.ExchangeCode(r.Context(), clientID, redirectURI, code, codeVerifier, deviceInfo, ip)
+	if err != nil {
+		response.JSONError(w, oauthErrorMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	response.JSONResponse(w, OIDCTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		IDToken:      idToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oc.authService.GetJWTExpiry().Seconds()),
+	}, http.StatusOK)
+}
+
+func (oc *OIDCController) tokenFromRefreshToken(w http.ResponseWriter, r *http.Request, deviceInfo, ip string) {
+	refreshToken := r.PostForm.Get("refresh_token")
+	var scopes []string
+	if scope := r.PostForm.Get("scope"); scope != "" {
+		scopes = strings.Fields(scope)
+	}
+
+	accessToken, newRefreshToken, err := oc.authService.RefreshToken(r.Context(), refreshToken, deviceInfo, ip, scopes)
+	if err != nil {
+		response.JSONError(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	response.JSONResponse(w, OIDCTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oc.authService.GetJWTExpiry().Seconds()),
+	}, http.StatusOK)
+}
+
+func (oc *OIDCController) tokenFromClientCredentials(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+	var scopes []string
+	if scope := r.PostForm.Get("scope"); scope != "" {
+		scopes = strings.Fields(scope)
+	}
+
+	accessToken, err := oc.provider.ClientCredentials(clientID, clientSecret, scopes)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth/token"`)
+		response.JSONError(w, oauthErrorMessage(err), http.StatusUnauthorized)
+		return
+	}
+
+	response.JSONResponse(w, OIDCTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(oc.authService.GetJWTExpiry().Seconds()),
+	}, http.StatusOK)
+}
+
+// UserInfo implements the OpenID Connect UserInfo endpoint, returning
+// standard claims about the bearer token's subject.
+func (oc *OIDCController) UserInfo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := oc.authService.GetUserByID(userID)
+	if err != nil {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	response.JSONResponse(w, map[string]string{
+		"sub":         fmt.Sprintf("%d", userID),
+		"email":       user.Email,
+		"given_name":  user.FirstName,
+		"family_name": user.LastName,
+	}, http.StatusOK)
+}
+
+// oauthErrorMessage maps an OIDCProvider error to the short OAuth2
+// error-code string clients are expected to switch on, falling back to the
+// error's own text for anything unrecognized.
+func oauthErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, services.ErrOAuthClientNotFound):
+		return "invalid_client"
+	case errors.Is(err, services.ErrInvalidRedirectURI):
+		return "invalid_request"
+	case errors.Is(err, services.ErrUnsupportedGrantType):
+		return "unauthorized_client"
+	case errors.Is(err, services.ErrInvalidScope):
+		return "invalid_scope"
+	case errors.Is(err, services.ErrAuthorizationCodeInvalid):
+		return "invalid_grant"
+	case errors.Is(err, services.ErrPKCEVerificationFailed):
+		return "invalid_grant"
+	default:
+		return err.Error()
+	}
+}
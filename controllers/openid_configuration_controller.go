@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"EchoAuth/keys"
+	"net/http"
+)
+
+// OpenIDConfigurationController serves the OpenID Connect discovery
+// document at the standard /.well-known/openid-configuration path.
+type OpenIDConfigurationController struct {
+	handler http.HandlerFunc
+}
+
+// NewOpenIDConfigurationController builds an OpenIDConfigurationController.
+// issuer is advertised as both the issuer and the base for jwks_uri;
+// signingAlg is the KeyManager's configured id_token signing algorithm.
+func NewOpenIDConfigurationController(issuer, signingAlg string) *OpenIDConfigurationController {
+	return &OpenIDConfigurationController{handler: keys.OpenIDConfigurationHandler(issuer, signingAlg)}
+}
+
+func (c *OpenIDConfigurationController) Serve(w http.ResponseWriter, r *http.Request) {
+	c.handler(w, r)
+}
@@ -1,9 +1,12 @@
 package controllers
 
 import (
-	"EchoAuth/models"
 	"encoding/json"
 	"net/http"
+
+	"EchoAuth/audit"
+	"EchoAuth/models"
+	"EchoAuth/utils/logger"
 )
 
 type PasswordResetServiceInterface interface {
@@ -14,11 +17,21 @@ type PasswordResetServiceInterface interface {
 
 type PasswordResetController struct {
 	resetService PasswordResetServiceInterface
+	auditLogger  audit.Logger
 }
 
-func NewPasswordResetController(resetService PasswordResetServiceInterface) *PasswordResetController {
+func NewPasswordResetController(resetService PasswordResetServiceInterface, auditLogger audit.Logger) *PasswordResetController {
 	return &PasswordResetController{
 		resetService: resetService,
+		auditLogger:  auditLogger,
+	}
+}
+
+func (c *PasswordResetController) audit(r *http.Request, typ audit.EventType, outcome audit.Outcome, email, reason string) {
+	event := audit.NewEvent(typ, outcome, 0, requestIP(r), r.Header.Get("User-Agent"), reason, correlationID(r))
+	event.Email = email
+	if err := c.auditLogger.Log(event); err != nil {
+		logger.GetLogger("audit").Error().Err(err).Str("event_type", string(typ)).Msg("failed to write audit event")
 	}
 }
 
@@ -44,22 +57,19 @@ func (c *PasswordResetController) RequestReset(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	token, err := c.resetService.GenerateResetToken(req.Email)
+	// The reset token is delivered by email, not in the response - the
+	// response is identical whether or not the email is registered so
+	// callers can't use it to enumerate accounts.
+	_, err := c.resetService.GenerateResetToken(req.Email)
 	if err != nil {
-		// Don't reveal whether the email exists
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{
-			"message": "If your email is registered, you will receive a reset link shortly",
-		})
-		return
+		c.audit(r, audit.EventPasswordResetRequest, audit.OutcomeFailure, req.Email, err.Error())
+	} else {
+		c.audit(r, audit.EventPasswordResetRequest, audit.OutcomeSuccess, req.Email, "")
 	}
 
-	// TODO: Send email with reset link
-	// For now, just return the token in the response
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
-		"token":   token,
-		"message": "Reset token generated successfully",
+		"message": "If your email is registered, you will receive a reset link shortly",
 	})
 }
 
@@ -83,10 +93,12 @@ func (c *PasswordResetController) ResetPassword(w http.ResponseWriter, r *http.R
 
 	err := c.resetService.ResetPassword(req.Token, req.NewPassword)
 	if err != nil {
+		c.audit(r, audit.EventPasswordResetComplete, audit.OutcomeFailure, "", err.Error())
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	c.audit(r, audit.EventPasswordResetComplete, audit.OutcomeSuccess, "", "")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Password reset successfully",
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"EchoAuth/audit"
 	"EchoAuth/models"
 	"bytes"
 	"encoding/json"
@@ -36,7 +37,7 @@ func TestNewPasswordResetController(t *testing.T) {
 		validateTokenFunc: func(token string) (*models.User, error) { return nil, nil },
 		resetPasswordFunc: func(token, newPassword string) error { return nil },
 	}
-	controller := NewPasswordResetController(mockService)
+	controller := NewPasswordResetController(mockService, audit.NewNoopLogger())
 
 	if controller == nil {
 		t.Fatal("Expected non-nil controller")
@@ -68,8 +69,7 @@ func TestPasswordResetController_RequestReset(t *testing.T) {
 			},
 			wantStatusCode: http.StatusOK,
 			wantResponse: map[string]string{
-				"token":   "valid-token",
-				"message": "Reset token generated successfully",
+				"message": "If your email is registered, you will receive a reset link shortly",
 			},
 			description: "Should successfully generate a reset token",
 			contentType: "application/json",
@@ -161,7 +161,7 @@ func TestPasswordResetController_RequestReset(t *testing.T) {
 				resetPasswordFunc: func(token, newPassword string) error { return nil },
 			}
 			tt.setupMock(mockService)
-			controller := NewPasswordResetController(mockService)
+			controller := NewPasswordResetController(mockService, audit.NewNoopLogger())
 
 			var body bytes.Buffer
 			if err := json.NewEncoder(&body).Encode(tt.requestBody); err != nil {
@@ -186,9 +186,6 @@ func TestPasswordResetController_RequestReset(t *testing.T) {
 				if got["message"] != tt.wantResponse["message"] {
 					t.Errorf("RequestReset() message = %v, want %v", got["message"], tt.wantResponse["message"])
 				}
-				if token, exists := tt.wantResponse["token"]; exists && got["token"] != token {
-					t.Errorf("RequestReset() token = %v, want %v", got["token"], token)
-				}
 			}
 		})
 	}
@@ -310,7 +307,7 @@ func TestPasswordResetController_ResetPassword(t *testing.T) {
 				resetPasswordFunc: func(token, newPassword string) error { return nil },
 			}
 			tt.setupMock(mockService)
-			controller := NewPasswordResetController(mockService)
+			controller := NewPasswordResetController(mockService, audit.NewNoopLogger())
 
 			var body bytes.Buffer
 			if err := json.NewEncoder(&body).Encode(tt.requestBody); err != nil {
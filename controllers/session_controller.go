@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"EchoAuth/audit"
+	"EchoAuth/models"
+	"EchoAuth/utils/logger"
+	"EchoAuth/utils/response"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SessionService exposes a user's active refresh-token sessions so they can
+// be listed and individually revoked from a "logged-in devices" UI.
+type SessionService interface {
+	ListActiveSessions(userID uint) ([]*models.Session, error)
+	RevokeSession(userID uint, sessionID uuid.UUID) error
+	RevokeOtherSessions(userID uint, currentRefreshToken string) error
+}
+
+// RevokeOtherSessionsRequest carries the caller's current refresh token, so
+// the handler knows which session to keep logged in while revoking the rest.
+type RevokeOtherSessionsRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type SessionController struct {
+	sessionService SessionService
+	auditLogger    audit.Logger
+}
+
+func NewSessionController(sessionService SessionService, auditLogger audit.Logger) *SessionController {
+	return &SessionController{
+		sessionService: sessionService,
+		auditLogger:    auditLogger,
+	}
+}
+
+func (sc *SessionController) audit(r *http.Request, userID uint, outcome audit.Outcome, reason string) {
+	event := audit.NewEvent(audit.EventSessionRevoked, outcome, userID, requestIP(r), r.Header.Get("User-Agent"), reason, correlationID(r))
+	if err := sc.auditLogger.Log(event); err != nil {
+		logger.GetLogger("audit").Error().Err(err).Msg("failed to write audit event")
+	}
+}
+
+// ListSessions returns the authenticated user's active sessions.
+func (sc *SessionController) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := sc.sessionService.ListActiveSessions(userID)
+	if err != nil {
+		response.JSONError(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	response.JSONResponse(w, sessions, http.StatusOK)
+}
+
+// RevokeSession revokes one of the authenticated user's sessions by ID.
+func (sc *SessionController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.JSONError(w, "Invalid session id", http.StatusBadRequest)
+		return
+	}
+
+	if err := sc.sessionService.RevokeSession(userID, sessionID); err != nil {
+		sc.audit(r, userID, audit.OutcomeFailure, err.Error())
+		if err.Error() == "record not found" {
+			response.JSONError(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		response.JSONError(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	sc.audit(r, userID, audit.OutcomeSuccess, "")
+	response.JSONResponse(w, map[string]string{"message": "Session revoked"}, http.StatusOK)
+}
+
+// RevokeOtherSessions revokes every active session for the authenticated
+// user except the one backed by the refresh token in the request body,
+// letting them log out every other device while staying signed in here.
+func (sc *SessionController) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		response.JSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req RevokeOtherSessionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.JSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		response.JSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := sc.sessionService.RevokeOtherSessions(userID, req.RefreshToken); err != nil {
+		sc.audit(r, userID, audit.OutcomeFailure, err.Error())
+		response.JSONError(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	sc.audit(r, userID, audit.OutcomeSuccess, "")
+	response.JSONResponse(w, map[string]string{"message": "Other sessions revoked"}, http.StatusOK)
+}
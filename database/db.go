@@ -1,24 +1,42 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math/rand"
+	"time"
 
-	_ "github.com/lib/pq"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
-// DB represents a database connection
+// maxSerializationRetries bounds how many times WithTx retries a transaction
+// that aborted with a serialization failure before giving up and returning
+// the error to the caller. CockroachDB's docs recommend a client-side retry
+// loop exactly like this one for its default SERIALIZABLE isolation.
+const maxSerializationRetries = 3
+
+// DB represents a database connection. Dialect is the zero value
+// (DialectPostgres) unless InitDB set it from the DATABASE_URL scheme, so
+// code that builds a DB directly (e.g. tests wrapping a sqlmock) keeps the
+// original Postgres-flavored SQL behavior without having to set it.
 type DB struct {
 	*sql.DB
+	Dialect Dialect
 }
 
-// InitDB initializes a database connection with the given URL
+// InitDB parses databaseURL to pick a database/sql driver and Dialect, opens
+// the connection, and verifies it with a ping.
 func InitDB(databaseURL string) (*DB, error) {
-	if databaseURL == "" {
-		return nil, fmt.Errorf("database URL cannot be empty")
+	driverName, dialect, dsn, err := ParseDatabaseURL(databaseURL)
+	if err != nil {
+		return nil, err
 	}
 
-	db, err := sql.Open("postgres", databaseURL)
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
 	}
@@ -32,5 +50,73 @@ func InitDB(databaseURL string) (*DB, error) {
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(5)
 
-	return &DB{db}, nil
+	return &DB{DB: db, Dialect: dialect}, nil
+}
+
+// WithTx runs fn inside a transaction, committing on a nil return and
+// rolling back otherwise. A panic inside fn also rolls back before being
+// re-raised, so callers don't need their own recover/rollback boilerplate
+// for transactional service methods. On a dialect whose transactions can
+// abort with a serialization failure (currently CockroachDB), fn is
+// re-invoked with a jittered backoff up to maxSerializationRetries times
+// before the failure is returned to the caller.
+func (db *DB) WithTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	if !db.Dialect.RetriesSerializationFailures() {
+		return db.runTx(ctx, fn)
+	}
+
+	var err error
+	for attempt := 0; attempt <= maxSerializationRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(attempt) * 10 * time.Millisecond
+			backoff += time.Duration(rand.Int63n(int64(backoff) + 1))
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		err = db.runTx(ctx, fn)
+		if !isSerializationFailure(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (db *DB) runTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// isSerializationFailure reports whether err is a Postgres-wire-protocol
+// SQLSTATE 40001 (serialization_failure), the error CockroachDB returns
+// when a transaction must be retried due to a conflicting concurrent one.
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001"
+	}
+	return false
 }
@@ -1,9 +1,13 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"os"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,26 +57,25 @@ func TestInitDB_Unit(t *testing.T) {
 	}
 }
 
-func TestMigrate_Unit(t *testing.T) {
+func TestUpFS_Unit(t *testing.T) {
 	// Create mock database
 	mockDB, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer mockDB.Close()
 
-	db := &DB{mockDB}
+	db := &DB{DB: mockDB}
 
 	// Expect migrations table check
 	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
 	// Expect query for applied migrations
-	mock.ExpectBegin()
-	mock.ExpectQuery("SELECT version FROM schema_migrations").
-		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectQuery("SELECT version, checksum, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "dirty"}))
 
 	// Since we can't actually read files in unit tests, we'll verify that
-	// the function handles empty migrations directory gracefully
-	err = db.Migrate("nonexistent")
+	// the function handles a missing migrations directory gracefully
+	err = db.UpFS(os.DirFS("nonexistent"), 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to read migrations directory")
 
@@ -80,6 +83,56 @@ func TestMigrate_Unit(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestWithTx_RetriesSerializationFailureOnCockroach(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB, Dialect: DialectCockroach}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE").WillReturnError(&pq.Error{Code: "40001", Message: "restart transaction"})
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	attempts := 0
+	err = db.WithTx(context.Background(), func(tx *sql.Tx) error {
+		attempts++
+		_, err := tx.Exec("UPDATE accounts SET balance = balance + 1")
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTx_DoesNotRetryOnPostgres(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB, Dialect: DialectPostgres}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE").WillReturnError(&pq.Error{Code: "40001", Message: "restart transaction"})
+	mock.ExpectRollback()
+
+	attempts := 0
+	err = db.WithTx(context.Background(), func(tx *sql.Tx) error {
+		attempts++
+		_, err := tx.Exec("UPDATE accounts SET balance = balance + 1")
+		return err
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestInitDB_Integration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test")
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect names one of the database/sql backends this package supports. The
+// zero value ("") behaves like DialectPostgres, since that was this
+// package's only backend before drivers existed, and plenty of call sites
+// (tests, mostly) build a DB without going through InitDB/ParseDatabaseURL.
+type Dialect string
+
+const (
+	DialectPostgres  Dialect = "postgres"
+	DialectSQLite    Dialect = "sqlite"
+	DialectMySQL     Dialect = "mysql"
+	DialectCockroach Dialect = "cockroach"
+)
+
+// Placeholder returns the positional bind-parameter marker for the n-th
+// (1-indexed) argument of a query in this dialect.
+func (d Dialect) Placeholder(n int) string {
+	switch d {
+	case DialectSQLite, DialectMySQL:
+		return "?"
+	default:
+		return fmt.Sprintf("$%d", n)
+	}
+}
+
+// SupportsReturning reports whether this dialect can read back a
+// server-generated column (e.g. a serial id) via "INSERT ... RETURNING" in
+// the same round trip. Dialects that can't fall back to
+// sql.Result.LastInsertId after a plain INSERT.
+func (d Dialect) SupportsReturning() bool {
+	return d != DialectMySQL && d != DialectSQLite
+}
+
+// driverName is the database/sql driver registered for this dialect.
+func (d Dialect) driverName() string {
+	switch d {
+	case DialectSQLite:
+		return "sqlite3"
+	case DialectMySQL:
+		return "mysql"
+	default:
+		// CockroachDB speaks the Postgres wire protocol, so it reuses lib/pq.
+		return "postgres"
+	}
+}
+
+// RetriesSerializationFailures reports whether this dialect's transactions
+// can abort with a retryable serialization error (SQLSTATE 40001) under
+// contention, which WithTx needs to know to retry instead of surfacing it.
+// CockroachDB uses serializable isolation for every transaction, so these
+// are expected in normal operation rather than a sign of a bug.
+func (d Dialect) RetriesSerializationFailures() bool {
+	return d == DialectCockroach
+}
+
+// ParseDatabaseURL splits a DATABASE_URL into the database/sql driver name
+// to pass to sql.Open, the Dialect it implies, and the driver-specific DSN.
+// A URL with no "scheme://" prefix - including libpq's traditional
+// "host=... dbname=..." keyword format, which is what this service has
+// always used - is treated as Postgres, so existing DATABASE_URL values
+// keep working unchanged.
+func ParseDatabaseURL(databaseURL string) (driverName string, dialect Dialect, dsn string, err error) {
+	if databaseURL == "" {
+		return "", "", "", fmt.Errorf("database URL cannot be empty")
+	}
+
+	scheme, rest, hasScheme := strings.Cut(databaseURL, "://")
+	if !hasScheme {
+		return DialectPostgres.driverName(), DialectPostgres, databaseURL, nil
+	}
+
+	switch scheme {
+	case "postgres", "postgresql":
+		return DialectPostgres.driverName(), DialectPostgres, databaseURL, nil
+	case "sqlite", "sqlite3":
+		return DialectSQLite.driverName(), DialectSQLite, rest, nil
+	case "mysql":
+		return DialectMySQL.driverName(), DialectMySQL, rest, nil
+	case "cockroach", "cockroachdb":
+		// lib/pq only recognizes the postgres:// scheme, so swap it back in
+		// before handing the DSN to sql.Open.
+		return DialectCockroach.driverName(), DialectCockroach, "postgres://" + rest, nil
+	default:
+		return "", "", "", fmt.Errorf("unsupported database driver %q", scheme)
+	}
+}
@@ -1,117 +1,447 @@
 package database
 
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
+	"io/fs"
+	"regexp"
 	"sort"
-	"strings"
+	"strconv"
 )
 
-// Migration represents a database migration
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is one versioned schema change, loaded from a paired
+// NNN_name.up.sql / NNN_name.down.sql file set. Checksum is the SHA-256 of
+// Up and Down concatenated, so Up/Down/Goto can detect a migration file that
+// changed on disk after it was already applied and refuse to proceed.
 type Migration struct {
 	Version  int
-	Filename string
-	SQL      string
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
 }
 
-// LoadMigrations loads all SQL migration files from the migrations directory
-func LoadMigrations(migrationsDir string) ([]Migration, error) {
-	files, err := ioutil.ReadDir(migrationsDir)
+var migrationFilenamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads every NNN_name.up.sql / NNN_name.down.sql pair at the
+// root of fsys and returns them sorted by version. A version missing either
+// half of its pair is an error.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read migrations directory: %v", err)
 	}
 
-	var migrations []Migration
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".sql") {
-			content, err := ioutil.ReadFile(filepath.Join(migrationsDir, file.Name()))
-			if err != nil {
-				return nil, fmt.Errorf("failed to read migration file %s: %v", file.Name(), err)
-			}
+	type half struct {
+		name           string
+		up, down       string
+		hasUp, hasDown bool
+	}
+	halves := make(map[int]*half)
 
-			var version int
-			_, err = fmt.Sscanf(file.Name(), "%d_", &version)
-			if err != nil {
-				return nil, fmt.Errorf("invalid migration filename %s: %v", file.Name(), err)
-			}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := migrationFilenamePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration filename %s: %v", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %v", entry.Name(), err)
+		}
 
-			migrations = append(migrations, Migration{
-				Version:  version,
-				Filename: file.Name(),
-				SQL:      string(content),
-			})
+		h, ok := halves[version]
+		if !ok {
+			h = &half{name: m[2]}
+			halves[version] = h
+		}
+		switch m[3] {
+		case "up":
+			h.up, h.hasUp = string(content), true
+		case "down":
+			h.down, h.hasDown = string(content), true
 		}
 	}
 
-	// Sort migrations by version
-	sort.Slice(migrations, func(i, j int) bool {
-		return migrations[i].Version < migrations[j].Version
-	})
+	migrations := make([]Migration, 0, len(halves))
+	for version, h := range halves {
+		if !h.hasUp || !h.hasDown {
+			return nil, fmt.Errorf("migration %d (%s) is missing its up or down file", version, h.name)
+		}
+		sum := sha256.Sum256([]byte(h.up + h.down))
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     h.name,
+			Up:       h.up,
+			Down:     h.down,
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
 
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
 	return migrations, nil
 }
 
-// Migrate applies all pending migrations
-func (db *DB) Migrate(migrationsDir string) error {
-	// Create migrations table if it doesn't exist
+// MigrationStatus is one row of Status's report: a known migration's
+// version/name plus whether and how it's currently recorded in
+// schema_migrations.
+type MigrationStatus struct {
+	Version int
+	Name    string
+	Applied bool
+	Dirty   bool
+
+	// ChecksumMismatch is true when the migration's on-disk checksum no
+	// longer matches the one recorded at apply time, meaning Up/Down/Goto
+	// will refuse to touch this version until it's resolved (edit the
+	// migration back, or Force past it). Surfaced here so an operator can
+	// spot it with `migrate status` before hitting that error.
+	ChecksumMismatch bool
+}
+
+type appliedMigration struct {
+	Version  int
+	Checksum string
+	Dirty    bool
+}
+
+func (db *DB) ensureSchemaMigrationsTable() error {
 	_, err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version INTEGER PRIMARY KEY,
+			checksum TEXT NOT NULL,
+			dirty BOOLEAN NOT NULL DEFAULT false,
 			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 		)`)
 	if err != nil {
-		return fmt.Errorf("failed to create migrations table: %v", err)
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	return nil
+}
+
+func (db *DB) appliedMigrations() (map[int]appliedMigration, error) {
+	if err := db.ensureSchemaMigrationsTable(); err != nil {
+		return nil, err
 	}
 
-	// Load migrations
-	migrations, err := LoadMigrations(migrationsDir)
+	rows, err := db.Query("SELECT version, checksum, dirty FROM schema_migrations ORDER BY version")
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Checksum, &a.Dirty); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations row: %v", err)
+		}
+		applied[a.Version] = a
 	}
+	return applied, rows.Err()
+}
 
-	// Begin transaction
-	tx, err := db.Begin()
+// loadAndCheck loads the migration set and the applied state, and refuses to
+// proceed if any applied version is dirty (a prior run crashed mid-migration
+// and needs an operator's attention) or if a migration's on-disk checksum no
+// longer matches the one recorded when it was applied.
+func (db *DB) loadAndCheck(fsys fs.FS) ([]Migration, map[int]appliedMigration, error) {
+	migrations, err := LoadMigrations(fsys)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
+		return nil, nil, err
 	}
-	defer tx.Rollback()
 
-	// Get applied migrations
-	rows, err := tx.Query("SELECT version FROM schema_migrations ORDER BY version")
+	applied, err := db.appliedMigrations()
 	if err != nil {
-		return fmt.Errorf("failed to get applied migrations: %v", err)
+		return nil, nil, err
 	}
-	defer rows.Close()
 
-	applied := make(map[int]bool)
-	for rows.Next() {
-		var version int
-		if err := rows.Scan(&version); err != nil {
-			return fmt.Errorf("failed to scan migration version: %v", err)
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	for version, a := range applied {
+		if a.Dirty {
+			return nil, nil, fmt.Errorf("schema_migrations has a dirty version %d; resolve the schema manually and run Force(%d) before migrating further", version, version)
+		}
+		if m, ok := byVersion[version]; ok && m.Checksum != a.Checksum {
+			return nil, nil, fmt.Errorf("migration %d: checksum on disk no longer matches the one recorded when it was applied", version)
 		}
-		applied[version] = true
 	}
 
-	// Apply pending migrations
-	for _, migration := range migrations {
-		if !applied[migration.Version] {
-			// Apply migration
-			if _, err := tx.Exec(migration.SQL); err != nil {
-				return fmt.Errorf("failed to apply migration %s: %v", migration.Filename, err)
+	return migrations, applied, nil
+}
+
+// applyMigration runs one direction of m in its own transaction, recording
+// the dirty flag around the SQL so a crash mid-migration leaves a row an
+// operator can find with Status and resolve with Force instead of silent,
+// half-applied state. It goes through WithTx rather than a bare Begin/Commit
+// so a CockroachDB target gets the same client-side serialization-failure
+// retry every other transactional repository method does.
+func (db *DB) applyMigration(m Migration, direction string) error {
+	err := db.WithTx(context.Background(), func(tx *sql.Tx) error {
+		if direction == "up" {
+			insertSQL := fmt.Sprintf("INSERT INTO schema_migrations (version, checksum, dirty) VALUES (%s, %s, %s)",
+				db.Dialect.Placeholder(1), db.Dialect.Placeholder(2), db.Dialect.Placeholder(3))
+			if _, err := tx.Exec(insertSQL, m.Version, m.Checksum, true); err != nil {
+				return fmt.Errorf("failed to mark migration %d dirty: %v", m.Version, err)
+			}
+			if _, err := tx.Exec(m.Up); err != nil {
+				return fmt.Errorf("failed to apply migration %d (%s): %v", m.Version, m.Name, err)
+			}
+			clearSQL := fmt.Sprintf("UPDATE schema_migrations SET dirty = %s WHERE version = %s",
+				db.Dialect.Placeholder(1), db.Dialect.Placeholder(2))
+			if _, err := tx.Exec(clearSQL, false, m.Version); err != nil {
+				return fmt.Errorf("failed to clear dirty flag for migration %d: %v", m.Version, err)
 			}
+		} else {
+			dirtySQL := fmt.Sprintf("UPDATE schema_migrations SET dirty = %s WHERE version = %s",
+				db.Dialect.Placeholder(1), db.Dialect.Placeholder(2))
+			if _, err := tx.Exec(dirtySQL, true, m.Version); err != nil {
+				return fmt.Errorf("failed to mark migration %d dirty: %v", m.Version, err)
+			}
+			if _, err := tx.Exec(m.Down); err != nil {
+				return fmt.Errorf("failed to roll back migration %d (%s): %v", m.Version, m.Name, err)
+			}
+			deleteSQL := fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %s", db.Dialect.Placeholder(1))
+			if _, err := tx.Exec(deleteSQL, m.Version); err != nil {
+				return fmt.Errorf("failed to remove migration %d record: %v", m.Version, err)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func embeddedMigrationsFS() fs.FS {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		// migrationFiles is compiled in via go:embed, so this can't fail at runtime.
+		panic(err)
+	}
+	return sub
+}
+
+// Up applies up to n pending migrations embedded under database/migrations,
+// in version order (all of them if n <= 0).
+func (db *DB) Up(n int) error {
+	return db.UpFS(embeddedMigrationsFS(), n)
+}
+
+// UpFS is Up's implementation, taking the migration set as an fs.FS so
+// tests and binaries that ship migrations alongside the executable can
+// point it at an arbitrary directory instead of the embedded one.
+func (db *DB) UpFS(fsys fs.FS, n int) error {
+	migrations, applied, err := db.loadAndCheck(fsys)
+	if err != nil {
+		return err
+	}
+
+	applyCount := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if n > 0 && applyCount >= n {
+			break
+		}
+		if err := db.applyMigration(m, "up"); err != nil {
+			return err
+		}
+		applyCount++
+	}
+	return nil
+}
 
-			// Record migration
-			if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", migration.Version); err != nil {
-				return fmt.Errorf("failed to record migration %s: %v", migration.Filename, err)
+// Down rolls back up to n applied migrations in reverse version order (all
+// of them if n <= 0).
+func (db *DB) Down(n int) error {
+	return db.DownFS(embeddedMigrationsFS(), n)
+}
+
+// DownFS is Down's implementation, taking the migration set as an fs.FS.
+func (db *DB) DownFS(fsys fs.FS, n int) error {
+	migrations, applied, err := db.loadAndCheck(fsys)
+	if err != nil {
+		return err
+	}
+
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	rolledBack := 0
+	for _, v := range versions {
+		if n > 0 && rolledBack >= n {
+			break
+		}
+		m, ok := byVersion[v]
+		if !ok {
+			return fmt.Errorf("cannot roll back migration %d: its migration files are no longer present", v)
+		}
+		if err := db.applyMigration(m, "down"); err != nil {
+			return err
+		}
+		rolledBack++
+	}
+	return nil
+}
+
+// Goto migrates up or down until the highest applied version equals
+// version, applying or rolling back one migration at a time in between.
+func (db *DB) Goto(version int) error {
+	return db.GotoFS(embeddedMigrationsFS(), version)
+}
+
+// GotoFS is Goto's implementation, taking the migration set as an fs.FS.
+func (db *DB) GotoFS(fsys fs.FS, version int) error {
+	migrations, applied, err := db.loadAndCheck(fsys)
+	if err != nil {
+		return err
+	}
+
+	current := 0
+	for v := range applied {
+		if v > current {
+			current = v
+		}
+	}
+	if version == current {
+		return nil
+	}
+
+	if version > current {
+		for _, m := range migrations {
+			if m.Version <= current || m.Version > version {
+				continue
+			}
+			if err := db.applyMigration(m, "up"); err != nil {
+				return err
 			}
 		}
+		return nil
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
 	}
 
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		if v > version {
+			versions = append(versions, v)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	for _, v := range versions {
+		m, ok := byVersion[v]
+		if !ok {
+			return fmt.Errorf("cannot roll back migration %d: its migration files are no longer present", v)
+		}
+		if err := db.applyMigration(m, "down"); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// Force sets schema_migrations to record version as the current, clean
+// state without running any migration's SQL. It's for an operator who has
+// already fixed a dirty migration's schema by hand and needs to tell the
+// engine it's safe to continue. version of -1 clears schema_migrations
+// entirely (no migrations applied).
+func (db *DB) Force(version int) error {
+	return db.ForceFS(embeddedMigrationsFS(), version)
+}
+
+// ForceFS is Force's implementation, taking the migration set as an fs.FS.
+func (db *DB) ForceFS(fsys fs.FS, version int) error {
+	if err := db.ensureSchemaMigrationsTable(); err != nil {
+		return err
+	}
+
+	if version < 0 {
+		if _, err := db.Exec("DELETE FROM schema_migrations"); err != nil {
+			return fmt.Errorf("failed to clear schema_migrations: %v", err)
+		}
+		return nil
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return err
+	}
+	checksum := ""
+	for _, m := range migrations {
+		if m.Version == version {
+			checksum = m.Checksum
+			break
+		}
+	}
+
+	deleteSQL := fmt.Sprintf("DELETE FROM schema_migrations WHERE version = %s", db.Dialect.Placeholder(1))
+	if _, err := db.Exec(deleteSQL, version); err != nil {
+		return fmt.Errorf("failed to reset migration %d: %v", version, err)
+	}
+	insertSQL := fmt.Sprintf("INSERT INTO schema_migrations (version, checksum, dirty) VALUES (%s, %s, %s)",
+		db.Dialect.Placeholder(1), db.Dialect.Placeholder(2), db.Dialect.Placeholder(3))
+	if _, err := db.Exec(insertSQL, version, checksum, false); err != nil {
+		return fmt.Errorf("failed to force migration %d: %v", version, err)
+	}
+	return nil
+}
+
+// Status reports, for every migration LoadMigrations finds embedded under
+// database/migrations, whether it's applied and (if so) whether it's dirty.
+func (db *DB) Status() ([]MigrationStatus, error) {
+	return db.StatusFS(embeddedMigrationsFS())
+}
+
+// StatusFS is Status's implementation, taking the migration set as an fs.FS.
+func (db *DB) StatusFS(fsys fs.FS) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		a, ok := applied[m.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:          m.Version,
+			Name:             m.Name,
+			Applied:          ok,
+			Dirty:            ok && a.Dirty,
+			ChecksumMismatch: ok && a.Checksum != m.Checksum,
+		})
+	}
+	return statuses, nil
+}
@@ -9,156 +9,330 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func writeMigrationFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for filename, content := range files {
+		err := os.WriteFile(filepath.Join(dir, filename), []byte(content), 0644)
+		assert.NoError(t, err)
+	}
+}
+
 func TestLoadMigrations(t *testing.T) {
-	// Create temporary directory
 	tempDir, err := os.MkdirTemp("", "migrations")
 	assert.NoError(t, err)
 	defer os.RemoveAll(tempDir)
 
-	// Create test migration files
-	files := map[string]string{
-		"001_create_users.sql":      "CREATE TABLE users (id SERIAL PRIMARY KEY);",
-		"002_create_tokens.sql":     "CREATE TABLE tokens (id UUID PRIMARY KEY);",
-		"003_add_user_columns.sql":  "ALTER TABLE users ADD COLUMN email VARCHAR(255);",
-		"invalid_migration.sql":     "INVALID SQL",
-		"not_a_migration.txt":       "NOT A MIGRATION",
-		"004_add_token_columns.sql": "ALTER TABLE tokens ADD COLUMN user_id INTEGER;",
-		"005_add_foreign_keys.sql":  "ALTER TABLE tokens ADD FOREIGN KEY (user_id) REFERENCES users(id);",
-	}
-
-	for filename, content := range files {
-		err := os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644)
-		assert.NoError(t, err)
-	}
-
-	// Test loading migrations
-	migrations, err := LoadMigrations(tempDir)
+	writeMigrationFiles(t, tempDir, map[string]string{
+		"001_create_users.up.sql":    "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+		"001_create_users.down.sql":  "DROP TABLE users;",
+		"002_create_tokens.up.sql":   "CREATE TABLE tokens (id UUID PRIMARY KEY);",
+		"002_create_tokens.down.sql": "DROP TABLE tokens;",
+		"invalid_migration.sql":      "INVALID SQL",
+		"not_a_migration.txt":        "NOT A MIGRATION",
+		"003_add_columns.up.sql":     "ALTER TABLE users ADD COLUMN email VARCHAR(255);",
+		"003_add_columns.down.sql":   "ALTER TABLE users DROP COLUMN email;",
+	})
+
+	migrations, err := LoadMigrations(os.DirFS(tempDir))
 	assert.NoError(t, err)
 
-	// Verify migrations are loaded and sorted correctly
-	assert.Len(t, migrations, 5)
+	assert.Len(t, migrations, 3)
 	assert.Equal(t, 1, migrations[0].Version)
 	assert.Equal(t, 2, migrations[1].Version)
 	assert.Equal(t, 3, migrations[2].Version)
-	assert.Equal(t, 4, migrations[3].Version)
-	assert.Equal(t, 5, migrations[4].Version)
 
-	// Verify migration content
-	assert.Equal(t, "CREATE TABLE users (id SERIAL PRIMARY KEY);", migrations[0].SQL)
+	assert.Equal(t, "CREATE TABLE users (id SERIAL PRIMARY KEY);", migrations[0].Up)
+	assert.Equal(t, "DROP TABLE users;", migrations[0].Down)
+	assert.NotEmpty(t, migrations[0].Checksum)
+}
+
+func TestLoadMigrations_MissingHalf(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "migrations")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	writeMigrationFiles(t, tempDir, map[string]string{
+		"001_create_users.up.sql": "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+	})
+
+	_, err = LoadMigrations(os.DirFS(tempDir))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "missing its up or down file")
 }
 
-func TestMigrate(t *testing.T) {
-	// Create mock database
+func TestUpFS(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer mockDB.Close()
 
-	db := &DB{mockDB}
+	db := &DB{DB: mockDB}
 
-	// Create temporary directory with test migrations
 	tempDir, err := os.MkdirTemp("", "migrations")
 	assert.NoError(t, err)
 	defer os.RemoveAll(tempDir)
 
-	// Create test migration files
-	files := map[string]string{
-		"001_create_users.sql":  "CREATE TABLE users (id SERIAL PRIMARY KEY);",
-		"002_create_tokens.sql": "CREATE TABLE tokens (id UUID PRIMARY KEY);",
-	}
+	writeMigrationFiles(t, tempDir, map[string]string{
+		"001_create_users.up.sql":    "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+		"001_create_users.down.sql":  "DROP TABLE users;",
+		"002_create_tokens.up.sql":   "CREATE TABLE tokens (id UUID PRIMARY KEY);",
+		"002_create_tokens.down.sql": "DROP TABLE tokens;",
+	})
 
-	for filename, content := range files {
-		err := os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644)
-		assert.NoError(t, err)
-	}
-
-	// Expect migrations table creation
 	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "dirty"}))
 
-	// Expect transaction for migrations
 	mock.ExpectBegin()
-
-	// Expect query for applied migrations
-	mock.ExpectQuery("SELECT version FROM schema_migrations").
-		WillReturnRows(sqlmock.NewRows([]string{"version"}))
-
-	// Expect first migration
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("CREATE TABLE users").
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE schema_migrations SET dirty").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO schema_migrations").
-		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	// Expect second migration
 	mock.ExpectExec("CREATE TABLE tokens").
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE schema_migrations SET dirty").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = db.UpFS(os.DirFS(tempDir), 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpFS_WithExistingMigrations(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	tempDir, err := os.MkdirTemp("", "migrations")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"001_create_users.up.sql":   "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+		"001_create_users.down.sql": "DROP TABLE users;",
+		"002_add_columns.up.sql":    "ALTER TABLE users ADD COLUMN email VARCHAR(255);",
+		"002_add_columns.down.sql":  "ALTER TABLE users DROP COLUMN email;",
+	}
+	writeMigrationFiles(t, tempDir, files)
+
+	migrations, err := LoadMigrations(os.DirFS(tempDir))
+	assert.NoError(t, err)
+	var checksum string
+	for _, m := range migrations {
+		if m.Version == 1 {
+			checksum = m.Checksum
+		}
+	}
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "dirty"}).
+			AddRow(1, checksum, false))
+
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO schema_migrations").
-		WithArgs(2).
 		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	// Expect transaction commit
+	mock.ExpectExec("ALTER TABLE users").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE schema_migrations SET dirty").
+		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	// Run migrations
-	err = db.Migrate(tempDir)
+	err = db.UpFS(os.DirFS(tempDir), 0)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpFS_ChecksumMismatch(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
 	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	tempDir, err := os.MkdirTemp("", "migrations")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	writeMigrationFiles(t, tempDir, map[string]string{
+		"001_create_users.up.sql":   "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+		"001_create_users.down.sql": "DROP TABLE users;",
+	})
 
-	// Verify all expectations were met
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "dirty"}).
+			AddRow(1, "stale-checksum-from-a-changed-file", false))
+
+	err = db.UpFS(os.DirFS(tempDir), 0)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum on disk no longer matches")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpFS_RefusesWhenDirty(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	tempDir, err := os.MkdirTemp("", "migrations")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	writeMigrationFiles(t, tempDir, map[string]string{
+		"001_create_users.up.sql":   "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+		"001_create_users.down.sql": "DROP TABLE users;",
+	})
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "dirty"}).
+			AddRow(1, "whatever", true))
+
+	err = db.UpFS(os.DirFS(tempDir), 0)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dirty version 1")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestMigrate_WithExistingMigrations(t *testing.T) {
-	// Create mock database
+func TestDownFS(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer mockDB.Close()
 
-	db := &DB{mockDB}
+	db := &DB{DB: mockDB}
 
-	// Create temporary directory with test migrations
 	tempDir, err := os.MkdirTemp("", "migrations")
 	assert.NoError(t, err)
 	defer os.RemoveAll(tempDir)
 
-	// Create test migration files
 	files := map[string]string{
-		"001_create_users.sql":  "CREATE TABLE users (id SERIAL PRIMARY KEY);",
-		"002_create_tokens.sql": "CREATE TABLE tokens (id UUID PRIMARY KEY);",
-		"003_add_columns.sql":   "ALTER TABLE users ADD COLUMN email VARCHAR(255);",
+		"001_create_users.up.sql":   "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+		"001_create_users.down.sql": "DROP TABLE users;",
 	}
+	writeMigrationFiles(t, tempDir, files)
 
-	for filename, content := range files {
-		err := os.WriteFile(filepath.Join(tempDir, filename), []byte(content), 0644)
-		assert.NoError(t, err)
-	}
+	migrations, err := LoadMigrations(os.DirFS(tempDir))
+	assert.NoError(t, err)
+	checksum := migrations[0].Checksum
 
-	// Expect migrations table creation
 	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "dirty"}).
+			AddRow(1, checksum, false))
 
-	// Expect transaction for migrations
 	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE schema_migrations SET dirty").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DROP TABLE users").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = db.DownFS(os.DirFS(tempDir), 1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-	// Expect query for applied migrations (return some existing migrations)
-	mock.ExpectQuery("SELECT version FROM schema_migrations").
-		WillReturnRows(sqlmock.NewRows([]string{"version"}).
-			AddRow(1).
-			AddRow(2))
+func TestForceFS_Clear(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
 
-	// Expect only the third migration (others are already applied)
-	mock.ExpectExec("ALTER TABLE users").
+	db := &DB{DB: mockDB}
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
 		WillReturnResult(sqlmock.NewResult(0, 0))
-	mock.ExpectExec("INSERT INTO schema_migrations").
-		WithArgs(3).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	// Expect transaction commit
-	mock.ExpectCommit()
+	err = db.ForceFS(os.DirFS("."), -1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatusFS(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
+
+	tempDir, err := os.MkdirTemp("", "migrations")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	writeMigrationFiles(t, tempDir, map[string]string{
+		"001_create_users.up.sql":   "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+		"001_create_users.down.sql": "DROP TABLE users;",
+		"002_add_columns.up.sql":    "ALTER TABLE users ADD COLUMN email VARCHAR(255);",
+		"002_add_columns.down.sql":  "ALTER TABLE users DROP COLUMN email;",
+	})
+
+	migrations, err := LoadMigrations(os.DirFS(tempDir))
+	assert.NoError(t, err)
+	checksum := migrations[0].Checksum
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "dirty"}).
+			AddRow(1, checksum, false))
+
+	statuses, err := db.StatusFS(os.DirFS(tempDir))
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[0].Dirty)
+	assert.False(t, statuses[0].ChecksumMismatch)
+	assert.False(t, statuses[1].Applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatusFS_ChecksumMismatch(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &DB{DB: mockDB}
 
-	// Run migrations
-	err = db.Migrate(tempDir)
+	tempDir, err := os.MkdirTemp("", "migrations")
 	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	writeMigrationFiles(t, tempDir, map[string]string{
+		"001_create_users.up.sql":   "CREATE TABLE users (id SERIAL PRIMARY KEY);",
+		"001_create_users.down.sql": "DROP TABLE users;",
+	})
 
-	// Verify all expectations were met
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "dirty"}).
+			AddRow(1, "stale-checksum-from-before-the-file-changed", false))
+
+	statuses, err := db.StatusFS(os.DirFS(tempDir))
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[0].Dirty)
+	assert.True(t, statuses[0].ChecksumMismatch)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,23 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"EchoAuth/utils/metrics"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsUnaryInterceptor records rpc_request_duration_seconds for every
+// call, the rpc_* counterpart of metrics.RecordRequestDuration for the HTTP
+// router.
+func MetricsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		metrics.RecordRPCDuration(info.FullMethod, status.Code(err).String(), time.Since(start))
+		return resp, err
+	}
+}
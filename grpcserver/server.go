@@ -0,0 +1,119 @@
+// Package grpcserver exposes AuthService (Register/Login/Refresh/Logout/
+// ValidateToken) and SessionService.ListActiveSessions over gRPC, alongside
+// the HTTP router in cmd/main.go, so non-Go services and sidecars can
+// integrate without HTTP/JSON overhead. Every RPC delegates to the same
+// services.AuthService/SessionService instance the HTTP controllers use -
+// this package adds a transport, not a second implementation.
+//
+// The message and service types it implements against
+// (echoauthv1.AuthServiceServer, echoauthv1.RegisterRequest, ...) are
+// generated from proto/echoauth/v1/auth.proto by the protobuf toolchain
+// (`buf generate` or `protoc --go_out=... --go-grpc_out=...`) and aren't
+// hand-written; run codegen before building this package.
+package grpcserver
+
+import (
+	"context"
+
+	"EchoAuth/controllers"
+	echoauthv1 "EchoAuth/proto/echoauth/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements echoauthv1.AuthServiceServer against the same
+// AuthService/SessionService used by the HTTP controllers.
+type Server struct {
+	echoauthv1.UnimplementedAuthServiceServer
+	authService    controllers.AuthService
+	sessionService controllers.SessionService
+}
+
+// New builds a Server. authService and sessionService are the same
+// instances wired into controllers.NewAuthController/NewSessionController,
+// so a session revoked or a token issued over gRPC is visible over HTTP and
+// vice versa.
+func New(authService controllers.AuthService, sessionService controllers.SessionService) *Server {
+	return &Server{authService: authService, sessionService: sessionService}
+}
+
+func (s *Server) Register(ctx context.Context, req *echoauthv1.RegisterRequest) (*echoauthv1.RegisterResponse, error) {
+	if err := s.authService.Register(ctx, req.GetEmail(), req.GetPassword(), req.GetFirstName(), req.GetLastName()); err != nil {
+		if err.Error() == "user already exists" {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &echoauthv1.RegisterResponse{}, nil
+}
+
+func (s *Server) Login(ctx context.Context, req *echoauthv1.LoginRequest) (*echoauthv1.LoginResponse, error) {
+	accessToken, refreshToken, err := s.authService.LoginWithRefresh(ctx, req.GetEmail(), req.GetPassword(), req.GetDeviceInfo(), req.GetIp(), req.GetScopes())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+
+	user, err := s.authService.GetUserByEmail(req.GetEmail())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user not found")
+	}
+
+	return &echoauthv1.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int32(s.authService.GetJWTExpiry().Seconds()),
+		UserId:       uint64(user.ID),
+	}, nil
+}
+
+func (s *Server) Refresh(ctx context.Context, req *echoauthv1.RefreshRequest) (*echoauthv1.RefreshResponse, error) {
+	accessToken, refreshToken, err := s.authService.RefreshToken(ctx, req.GetRefreshToken(), req.GetDeviceInfo(), req.GetIp(), req.GetScopes())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired refresh token")
+	}
+
+	return &echoauthv1.RefreshResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int32(s.authService.GetJWTExpiry().Seconds()),
+	}, nil
+}
+
+func (s *Server) Logout(ctx context.Context, req *echoauthv1.LogoutRequest) (*echoauthv1.LogoutResponse, error) {
+	if err := s.authService.Logout(ctx, req.GetRefreshToken()); err != nil {
+		return nil, status.Error(codes.Internal, "failed to logout")
+	}
+	return &echoauthv1.LogoutResponse{}, nil
+}
+
+func (s *Server) ValidateToken(ctx context.Context, req *echoauthv1.ValidateTokenRequest) (*echoauthv1.ValidateTokenResponse, error) {
+	claims, err := s.authService.ValidateToken(req.GetAccessToken())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	return &echoauthv1.ValidateTokenResponse{
+		UserId:        uint64(claims.UserID),
+		ExpiresAtUnix: claims.ExpiresAt,
+	}, nil
+}
+
+func (s *Server) ListSessions(ctx context.Context, req *echoauthv1.ListSessionsRequest) (*echoauthv1.ListSessionsResponse, error) {
+	sessions, err := s.sessionService.ListActiveSessions(uint(req.GetUserId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list sessions")
+	}
+
+	resp := &echoauthv1.ListSessionsResponse{Sessions: make([]*echoauthv1.Session, 0, len(sessions))}
+	for _, sess := range sessions {
+		resp.Sessions = append(resp.Sessions, &echoauthv1.Session{
+			Id:             sess.ID.String(),
+			DeviceInfo:     sess.DeviceInfo,
+			Ip:             sess.IP,
+			CreatedAtUnix:  sess.CreatedAt.Unix(),
+			LastUsedAtUnix: sess.LastUsedAt.Unix(),
+		})
+	}
+	return resp, nil
+}
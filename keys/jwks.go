@@ -0,0 +1,107 @@
+package keys
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// jwk is a single entry in a JWKS document, RFC 7517 §4. The RSA fields
+// (n, e) and EC fields (crv, x, y) are mutually exclusive depending on Kty.
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jwksResponse struct {
+	Keys []jwk `json:"keys"`
+}
+
+// JWKSHandler serves the public half of the active key manager's key set as
+// a standard /.well-known/jwks.json document.
+func JWKSHandler(km KeyManager, maxAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		public := km.PublicKeys()
+		resp := jwksResponse{Keys: make([]jwk, 0, len(public))}
+		for _, k := range public {
+			entry, err := toJWK(k)
+			if err != nil {
+				continue
+			}
+			resp.Keys = append(resp.Keys, entry)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// toJWK renders k's public key in the JWK encoding for its concrete type,
+// RSA or ECDSA (P-256 only, the one curve NewKeyManager generates ES256
+// keys on).
+func toJWK(k PublicKey) (jwk, error) {
+	rsaPub, ecdsaPub := publicKeyTypes(k.Key)
+	switch {
+	case rsaPub != nil:
+		return jwk{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: k.Kid,
+			N:   base64.RawURLEncoding.EncodeToString(rsaPub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(bigEndianUint(rsaPub.E)),
+		}, nil
+	case ecdsaPub != nil:
+		size := (ecdsaPub.Curve.Params().BitSize + 7) / 8
+		return jwk{
+			Kty: "EC",
+			Use: "sig",
+			Alg: "ES256",
+			Kid: k.Kid,
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(fixedWidthBytes(ecdsaPub.X, size)),
+			Y:   base64.RawURLEncoding.EncodeToString(fixedWidthBytes(ecdsaPub.Y, size)),
+		}, nil
+	default:
+		return jwk{}, fmt.Errorf("unsupported public key type for kid %q", k.Kid)
+	}
+}
+
+// fixedWidthBytes encodes n as a big-endian byte slice exactly size bytes
+// wide, left-padding with zeros - big.Int.Bytes() drops leading zero bytes,
+// which would otherwise silently shorten an EC coordinate that happens to
+// start with a zero byte.
+func fixedWidthBytes(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
+
+// bigEndianUint encodes a small positive int (the RSA public exponent) as
+// the minimal big-endian byte slice JWK expects for "e".
+func bigEndianUint(v int) []byte {
+	if v == 0 {
+		return []byte{0}
+	}
+	var b []byte
+	for v > 0 {
+		b = append([]byte{byte(v & 0xff)}, b...)
+		v >>= 8
+	}
+	return b
+}
@@ -0,0 +1,99 @@
+// Package keys manages the set of asymmetric keys used to sign and verify
+// access tokens, and exposes the public half of that set as a JWKS document.
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"time"
+)
+
+// PrivateKey is a single signing key in the set, identified by Kid. Alg is
+// the JWT alg this key signs with ("RS256" or "ES256"); Key's concrete type
+// is *rsa.PrivateKey for RS256 and *ecdsa.PrivateKey for ES256.
+// ExpiresAt marks when the key should be dropped from the JWKS entirely,
+// not when it stops being used to sign new tokens.
+type PrivateKey struct {
+	Kid       string
+	Alg       string
+	Key       crypto.Signer
+	NotBefore time.Time
+	ExpiresAt time.Time
+}
+
+// PublicKey is the JWKS-safe view of a PrivateKey.
+type PublicKey struct {
+	Kid       string
+	Alg       string
+	Key       crypto.PublicKey
+	NotBefore time.Time
+	ExpiresAt time.Time
+}
+
+// Public returns the public half of k.
+func (k *PrivateKey) Public() PublicKey {
+	return PublicKey{
+		Kid:       k.Kid,
+		Alg:       k.Alg,
+		Key:       k.Key.Public(),
+		NotBefore: k.NotBefore,
+		ExpiresAt: k.ExpiresAt,
+	}
+}
+
+// PrivateKeySet holds the single key currently used to sign new tokens plus
+// the retired keys kept around only to verify tokens issued before rotation.
+type PrivateKeySet struct {
+	Active  *PrivateKey
+	Retired []*PrivateKey
+}
+
+// All returns Active followed by Retired, the order JWKS consumers should
+// try keys in.
+func (s *PrivateKeySet) All() []*PrivateKey {
+	if s.Active == nil {
+		return s.Retired
+	}
+	return append([]*PrivateKey{s.Active}, s.Retired...)
+}
+
+// Find returns the key with the given kid, including retired ones.
+func (s *PrivateKeySet) Find(kid string) (*PrivateKey, bool) {
+	for _, k := range s.All() {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// validAlgs are the JWT alg values a PrivateKey may carry.
+var validAlgs = map[string]bool{"RS256": true, "ES256": true}
+
+// normalizeAlg defaults an unset algorithm to RS256 and rejects anything
+// this package doesn't know how to generate or sign with.
+func normalizeAlg(alg string) (string, error) {
+	if alg == "" {
+		alg = "RS256"
+	}
+	if !validAlgs[alg] {
+		return "", fmt.Errorf("unsupported signing key algorithm %q", alg)
+	}
+	return alg, nil
+}
+
+// publicKeyTypes asserts k's concrete type for callers (e.g. the JWKS
+// encoder) that need to branch on RSA vs ECDSA rather than treat it as an
+// opaque crypto.PublicKey.
+func publicKeyTypes(k crypto.PublicKey) (*rsa.PublicKey, *ecdsa.PublicKey) {
+	switch pub := k.(type) {
+	case *rsa.PublicKey:
+		return pub, nil
+	case *ecdsa.PublicKey:
+		return nil, pub
+	default:
+		return nil, nil
+	}
+}
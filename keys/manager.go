@@ -0,0 +1,185 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const rsaKeyBits = 2048
+
+// KeyManager gives callers the key to sign new tokens with, the full set of
+// public keys that should currently be trusted for verification, and a way
+// to force a rotation outside the background schedule.
+type KeyManager interface {
+	Signer() (*PrivateKey, error)
+	PublicKeys() []PublicKey
+	Rotate() error
+	// RotateKeys is Rotate with an explicit retention period for the
+	// outgoing key, for callers (e.g. an admin endpoint) that want to
+	// override the default of twice the access token TTL.
+	RotateKeys(retain time.Duration) error
+}
+
+// KeySetRepository persists a PrivateKeySet so that a restart doesn't
+// invalidate tokens signed by keys that are still within their lifetime.
+type KeySetRepository interface {
+	Load() (*PrivateKeySet, error)
+	Save(set *PrivateKeySet) error
+}
+
+// manager is the default KeyManager, rotating a single active key on a
+// fixed interval and retaining retired keys until they age out of the JWKS.
+// Every key it generates uses the same algorithm (RS256 or ES256); rotating
+// to a different algorithm retires the old one like any other rotation, so
+// verifiers keep trusting it until it ages out.
+type manager struct {
+	mu             sync.RWMutex
+	set            *PrivateKeySet
+	repo           KeySetRepository
+	accessTokenTTL time.Duration
+	algorithm      string
+}
+
+// NewKeyManager loads a persisted key set from repo, generating and saving
+// a fresh one if none exists yet. algorithm is the JWT alg new keys are
+// generated for ("RS256" or "ES256"); it defaults to RS256 when empty.
+func NewKeyManager(repo KeySetRepository, accessTokenTTL time.Duration, algorithm string) (*manager, error) {
+	algorithm, err := normalizeAlg(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := repo.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key set: %w", err)
+	}
+
+	m := &manager{repo: repo, accessTokenTTL: accessTokenTTL, algorithm: algorithm}
+
+	if set == nil || set.Active == nil {
+		generated, err := newPrivateKey(time.Now(), algorithm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate initial signing key: %w", err)
+		}
+		set = &PrivateKeySet{Active: generated}
+		if err := repo.Save(set); err != nil {
+			return nil, fmt.Errorf("failed to persist initial key set: %w", err)
+		}
+	}
+
+	m.set = set
+	return m, nil
+}
+
+func newPrivateKey(now time.Time, algorithm string) (*PrivateKey, error) {
+	var signer crypto.Signer
+	var err error
+	switch algorithm {
+	case "ES256":
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &PrivateKey{
+		Kid:       uuid.New().String(),
+		Alg:       algorithm,
+		Key:       signer,
+		NotBefore: now,
+	}, nil
+}
+
+// Signer returns the key that should sign newly issued tokens.
+func (m *manager) Signer() (*PrivateKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.set == nil || m.set.Active == nil {
+		return nil, fmt.Errorf("no active signing key")
+	}
+	return m.set.Active, nil
+}
+
+// PublicKeys returns every key that is still within the JWKS window,
+// active first so verifiers prefer it.
+func (m *manager) PublicKeys() []PublicKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := m.set.All()
+	public := make([]PublicKey, 0, len(keys))
+	for _, k := range keys {
+		public = append(public, k.Public())
+	}
+	return public
+}
+
+// Rotate generates a new active key and demotes the previous one to
+// retired, keeping it around for twice the access token TTL - long enough
+// for the longest-lived token it signed to expire.
+func (m *manager) Rotate() error {
+	return m.RotateKeys(2 * m.accessTokenTTL)
+}
+
+// RotateKeys generates a new active key, demotes the previous one to
+// retired (keeping it trusted for verification for retain), and drops any
+// retired key whose ExpiresAt has passed.
+func (m *manager) RotateKeys(retain time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	next, err := newPrivateKey(now, m.algorithm)
+	if err != nil {
+		return fmt.Errorf("failed to generate rotated signing key: %w", err)
+	}
+
+	retired := make([]*PrivateKey, 0, len(m.set.Retired)+1)
+	if m.set.Active != nil {
+		demoted := m.set.Active
+		demoted.ExpiresAt = now.Add(retain)
+		retired = append(retired, demoted)
+	}
+	for _, k := range m.set.Retired {
+		if k.ExpiresAt.After(now) {
+			retired = append(retired, k)
+		}
+	}
+
+	newSet := &PrivateKeySet{Active: next, Retired: retired}
+	if err := m.repo.Save(newSet); err != nil {
+		return fmt.Errorf("failed to persist rotated key set: %w", err)
+	}
+
+	m.set = newSet
+	return nil
+}
+
+// StartRotation runs Rotate on interval until stop is closed, logging
+// failures rather than crashing the server over a single missed rotation.
+func (m *manager) StartRotation(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.Rotate(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
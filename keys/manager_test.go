@@ -0,0 +1,156 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"testing"
+	"time"
+)
+
+// memRepository is an in-memory KeySetRepository for unit tests.
+type memRepository struct {
+	set *PrivateKeySet
+}
+
+func (r *memRepository) Load() (*PrivateKeySet, error) {
+	return r.set, nil
+}
+
+func (r *memRepository) Save(set *PrivateKeySet) error {
+	r.set = set
+	return nil
+}
+
+func TestNewKeyManagerGeneratesInitialKey(t *testing.T) {
+	repo := &memRepository{}
+
+	km, err := NewKeyManager(repo, time.Hour, "RS256")
+	if err != nil {
+		t.Fatalf("NewKeyManager() error = %v", err)
+	}
+
+	signer, err := km.Signer()
+	if err != nil {
+		t.Fatalf("Signer() error = %v", err)
+	}
+	if signer.Kid == "" {
+		t.Fatal("expected generated signing key to have a kid")
+	}
+	if repo.set == nil || repo.set.Active == nil {
+		t.Fatal("expected initial key set to be persisted")
+	}
+}
+
+func TestRotatePromotesNewKeyAndRetiresOld(t *testing.T) {
+	repo := &memRepository{}
+	km, err := NewKeyManager(repo, time.Hour, "RS256")
+	if err != nil {
+		t.Fatalf("NewKeyManager() error = %v", err)
+	}
+
+	original, _ := km.Signer()
+
+	if err := km.Rotate(); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	rotated, _ := km.Signer()
+	if rotated.Kid == original.Kid {
+		t.Fatal("expected Rotate to promote a new active key")
+	}
+
+	_, found := km.set.Find(original.Kid)
+	if !found {
+		t.Fatal("expected previous active key to still be verifiable after rotation")
+	}
+}
+
+func TestRotateDropsRetiredKeysPastExpiry(t *testing.T) {
+	repo := &memRepository{}
+	km, err := NewKeyManager(repo, time.Hour, "RS256")
+	if err != nil {
+		t.Fatalf("NewKeyManager() error = %v", err)
+	}
+
+	// Force the current active key to look already expired once retired.
+	original, _ := km.Signer()
+	_ = original
+
+	if err := km.Rotate(); err != nil {
+		t.Fatalf("first Rotate() error = %v", err)
+	}
+	km.mu.Lock()
+	km.set.Retired[0].ExpiresAt = time.Now().Add(-time.Minute)
+	km.mu.Unlock()
+
+	if err := km.Rotate(); err != nil {
+		t.Fatalf("second Rotate() error = %v", err)
+	}
+
+	for _, k := range km.PublicKeys() {
+		if k.ExpiresAt.Before(time.Now()) && !k.ExpiresAt.IsZero() {
+			t.Fatalf("expected expired retired key to be dropped, found kid %s", k.Kid)
+		}
+	}
+}
+
+func TestPublicKeysIncludesActiveFirst(t *testing.T) {
+	repo := &memRepository{}
+	km, err := NewKeyManager(repo, time.Hour, "RS256")
+	if err != nil {
+		t.Fatalf("NewKeyManager() error = %v", err)
+	}
+	_ = km.Rotate()
+
+	active, _ := km.Signer()
+	public := km.PublicKeys()
+	if len(public) < 2 {
+		t.Fatalf("expected active + retired key in JWKS, got %d", len(public))
+	}
+	if public[0].Kid != active.Kid {
+		t.Fatalf("expected active key first, got kid %s", public[0].Kid)
+	}
+}
+
+func TestNewKeyManagerGeneratesES256Key(t *testing.T) {
+	repo := &memRepository{}
+
+	km, err := NewKeyManager(repo, time.Hour, "ES256")
+	if err != nil {
+		t.Fatalf("NewKeyManager() error = %v", err)
+	}
+
+	signer, err := km.Signer()
+	if err != nil {
+		t.Fatalf("Signer() error = %v", err)
+	}
+	if signer.Alg != "ES256" {
+		t.Fatalf("Alg = %q, want ES256", signer.Alg)
+	}
+	if _, ok := signer.Key.Public().(*ecdsa.PublicKey); !ok {
+		t.Fatalf("expected an ECDSA public key, got %T", signer.Key.Public())
+	}
+}
+
+func TestRotateKeysHonorsExplicitRetention(t *testing.T) {
+	repo := &memRepository{}
+	km, err := NewKeyManager(repo, time.Hour, "RS256")
+	if err != nil {
+		t.Fatalf("NewKeyManager() error = %v", err)
+	}
+
+	original, _ := km.Signer()
+
+	retain := 5 * time.Minute
+	if err := km.RotateKeys(retain); err != nil {
+		t.Fatalf("RotateKeys() error = %v", err)
+	}
+
+	retired, found := km.set.Find(original.Kid)
+	if !found {
+		t.Fatal("expected previous active key to still be verifiable after rotation")
+	}
+	wantExpiry := time.Now().Add(retain)
+	if retired.ExpiresAt.After(wantExpiry.Add(time.Second)) || retired.ExpiresAt.Before(wantExpiry.Add(-time.Second)) {
+		t.Fatalf("ExpiresAt = %v, want ~%v", retired.ExpiresAt, wantExpiry)
+	}
+}
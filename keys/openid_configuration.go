@@ -0,0 +1,57 @@
+package keys
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// openIDConfiguration is the OpenID Connect Discovery 1.0 metadata document
+// advertising EchoAuth's full authorization-server surface: JWKS-backed
+// token verification plus the authorization_code, refresh_token and
+// client_credentials grants served at the authorization/token/userinfo
+// endpoints below.
+type openIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// OpenIDConfigurationHandler serves the standard
+// /.well-known/openid-configuration discovery document, pointing verifiers
+// at issuer's JWKS endpoint and authorization-server endpoints. signingAlg
+// is the KeyManager's configured algorithm ("RS256" or "ES256"),
+// advertised as the only id_token signing alg since EchoAuth signs
+// everything with whichever key is currently active.
+func OpenIDConfigurationHandler(issuer, signingAlg string) http.HandlerFunc {
+	if signingAlg == "" {
+		signingAlg = "RS256"
+	}
+	resp := openIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/oauth/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   []string{"openid", "profile", "email"},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256", "plain"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{signingAlg},
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,105 @@
+// Package lifecycle gives a process a single place to register the
+// start/stop ordering for its background subsystems, instead of main
+// hand-rolling a goroutine plus a matching shutdown step for each one.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+)
+
+// Hook is a subsystem a Manager starts and stops as a unit: an SMTP worker
+// pool, an OIDC connector's background refresh, a metrics pusher. Start
+// should return once the subsystem is ready (or fail fast if it can't get
+// there); long-running work belongs in a goroutine Start launches, not in
+// Start itself. Stop should block until that work has wound down, respecting
+// ctx's deadline rather than running it out.
+type Hook interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// HookFuncs adapts a pair of plain functions to a Hook, for a subsystem
+// simple enough not to need its own named type.
+type HookFuncs struct {
+	name  string
+	start func(ctx context.Context) error
+	stop  func(ctx context.Context) error
+}
+
+// NewHookFuncs builds a Hook named name that delegates to start and stop.
+// Either may be nil, in which case that step is a no-op.
+func NewHookFuncs(name string, start, stop func(ctx context.Context) error) HookFuncs {
+	return HookFuncs{name: name, start: start, stop: stop}
+}
+
+func (h HookFuncs) Name() string { return h.name }
+
+func (h HookFuncs) Start(ctx context.Context) error {
+	if h.start == nil {
+		return nil
+	}
+	return h.start(ctx)
+}
+
+func (h HookFuncs) Stop(ctx context.Context) error {
+	if h.stop == nil {
+		return nil
+	}
+	return h.stop(ctx)
+}
+
+// Manager starts registered Hooks in registration order and stops them in
+// the reverse order, so a hook that depends on one registered before it
+// (e.g. a worker pool that depends on the DB connection already being up)
+// is always torn down before its dependency is.
+type Manager struct {
+	hooks []Hook
+}
+
+// NewManager returns an empty Manager ready for Register calls.
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Register adds h to the end of the start order. Call it during setup,
+// before Start - registering after Start has already run has no effect on
+// hooks that have already started.
+func (m *Manager) Register(h Hook) {
+	m.hooks = append(m.hooks, h)
+}
+
+// Start starts every registered hook in registration order. If one fails,
+// Start stops the hooks that already started (in reverse order) before
+// returning the error, so a partially-started Manager never leaks a
+// subsystem the caller thinks never ran.
+func (m *Manager) Start(ctx context.Context) error {
+	for i, h := range m.hooks {
+		if err := h.Start(ctx); err != nil {
+			m.stopFrom(ctx, i-1)
+			return fmt.Errorf("starting %q: %w", h.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Stop stops every started hook in reverse registration order, continuing
+// past individual failures so one stuck subsystem doesn't prevent the rest
+// from shutting down. It returns the first error encountered, if any.
+func (m *Manager) Stop(ctx context.Context) error {
+	return m.stopFrom(ctx, len(m.hooks)-1)
+}
+
+// stopFrom stops hooks[0..from] in reverse order, returning the first error
+// encountered.
+func (m *Manager) stopFrom(ctx context.Context, from int) error {
+	var firstErr error
+	for i := from; i >= 0; i-- {
+		h := m.hooks[i]
+		if err := h.Stop(ctx); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("stopping %q: %w", h.Name(), err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,71 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func recordingHook(name string, events *[]string, failStart bool) Hook {
+	return NewHookFuncs(name,
+		func(ctx context.Context) error {
+			if failStart {
+				return errors.New("boom")
+			}
+			*events = append(*events, "start:"+name)
+			return nil
+		},
+		func(ctx context.Context) error {
+			*events = append(*events, "stop:"+name)
+			return nil
+		},
+	)
+}
+
+func TestManagerStartsInOrderAndStopsInReverse(t *testing.T) {
+	var events []string
+	m := NewManager()
+	m.Register(recordingHook("a", &events, false))
+	m.Register(recordingHook("b", &events, false))
+	m.Register(recordingHook("c", &events, false))
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	want := []string{"start:a", "start:b", "start:c", "stop:c", "stop:b", "stop:a"}
+	if len(events) != len(want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("events = %v, want %v", events, want)
+		}
+	}
+}
+
+func TestManagerStartRollsBackOnFailure(t *testing.T) {
+	var events []string
+	m := NewManager()
+	m.Register(recordingHook("a", &events, false))
+	m.Register(recordingHook("b", &events, true))
+	m.Register(recordingHook("c", &events, false))
+
+	err := m.Start(context.Background())
+	if err == nil {
+		t.Fatal("expected Start() to return an error")
+	}
+
+	want := []string{"start:a", "stop:a"}
+	if len(events) != len(want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("events = %v, want %v", events, want)
+		}
+	}
+}
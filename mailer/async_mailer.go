@@ -0,0 +1,186 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"EchoAuth/utils/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// deadLetterKey is the Redis list AsyncMailer pushes a job to once it's
+// exhausted every retry, so an operator can inspect (or replay) mail that
+// never went out instead of it being silently dropped.
+const deadLetterKey = "mailer:dead_letter"
+
+// mailKind names which Mailer method a queued job should be redelivered
+// through.
+type mailKind string
+
+const (
+	kindPasswordReset     mailKind = "password_reset"
+	kindEmailVerification mailKind = "email_verification"
+	kindInvitation        mailKind = "invitation"
+	kindLoginNotification mailKind = "login_notification"
+)
+
+// mailJob is one queued send, carrying enough to retry it and, if retries
+// run out, to describe it in the dead letter list. URL is only set for the
+// link-driven kinds; DeviceInfo/IP/LoginAt are only set for
+// kindLoginNotification.
+type mailJob struct {
+	Kind       mailKind  `json:"kind"`
+	To         string    `json:"to"`
+	URL        string    `json:"url"`
+	DeviceInfo string    `json:"device_info,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	LoginAt    time.Time `json:"login_at,omitempty"`
+	Attempt    int       `json:"attempt"`
+}
+
+// deadLetter is the JSON shape a failed-out job is recorded as in Redis.
+type deadLetter struct {
+	mailJob
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// AsyncMailer wraps another Mailer so SendPasswordReset/SendEmailVerification/
+// SendInvitation enqueue their job onto a buffered channel and return
+// immediately instead of blocking the caller on a live send. A pool of
+// background workers delivers each job through inner, retrying a failed
+// send with exponential backoff up to maxAttempts times before giving up and
+// recording it in deadLetterKey. A full queue dead-letters the job
+// immediately rather than blocking the caller, since a caller enqueuing mail
+// is never expected to wait on it.
+type AsyncMailer struct {
+	inner       Mailer
+	redisClient *redis.Client
+	jobs        chan mailJob
+	maxAttempts int
+	baseBackoff time.Duration
+}
+
+// NewAsyncMailer builds an AsyncMailer delivering through inner, with
+// workers background goroutines consuming a channel buffered to queueSize.
+// redisClient may be nil, in which case exhausted jobs are only logged
+// rather than dead-lettered. workers, queueSize, maxAttempts, and
+// baseBackoff all fall back to a sane minimum of 1 if given as zero or
+// negative.
+func NewAsyncMailer(inner Mailer, redisClient *redis.Client, workers, queueSize, maxAttempts int, baseBackoff time.Duration) *AsyncMailer {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	if baseBackoff <= 0 {
+		baseBackoff = time.Second
+	}
+
+	m := &AsyncMailer{
+		inner:       inner,
+		redisClient: redisClient,
+		jobs:        make(chan mailJob, queueSize),
+		maxAttempts: maxAttempts,
+		baseBackoff: baseBackoff,
+	}
+
+	for i := 0; i < workers; i++ {
+		go m.worker()
+	}
+
+	return m
+}
+
+func (m *AsyncMailer) SendPasswordReset(to, resetURL string) error {
+	m.enqueue(mailJob{Kind: kindPasswordReset, To: to, URL: resetURL})
+	return nil
+}
+
+func (m *AsyncMailer) SendEmailVerification(to, verifyURL string) error {
+	m.enqueue(mailJob{Kind: kindEmailVerification, To: to, URL: verifyURL})
+	return nil
+}
+
+func (m *AsyncMailer) SendInvitation(to, inviteURL string) error {
+	m.enqueue(mailJob{Kind: kindInvitation, To: to, URL: inviteURL})
+	return nil
+}
+
+func (m *AsyncMailer) SendLoginNotification(to, deviceInfo, ip string, loginAt time.Time) error {
+	m.enqueue(mailJob{Kind: kindLoginNotification, To: to, DeviceInfo: deviceInfo, IP: ip, LoginAt: loginAt})
+	return nil
+}
+
+func (m *AsyncMailer) enqueue(job mailJob) {
+	select {
+	case m.jobs <- job:
+	default:
+		m.deadLetter(job, errors.New("mail queue full"))
+	}
+}
+
+func (m *AsyncMailer) worker() {
+	for job := range m.jobs {
+		if err := m.deliver(job); err != nil {
+			m.retryOrDeadLetter(job, err)
+		}
+	}
+}
+
+func (m *AsyncMailer) deliver(job mailJob) error {
+	switch job.Kind {
+	case kindPasswordReset:
+		return m.inner.SendPasswordReset(job.To, job.URL)
+	case kindEmailVerification:
+		return m.inner.SendEmailVerification(job.To, job.URL)
+	case kindInvitation:
+		return m.inner.SendInvitation(job.To, job.URL)
+	case kindLoginNotification:
+		return m.inner.SendLoginNotification(job.To, job.DeviceInfo, job.IP, job.LoginAt)
+	default:
+		return nil
+	}
+}
+
+// retryOrDeadLetter re-queues job after a delay that doubles with each
+// attempt (job.Attempt=0 waits baseBackoff, 1 waits 2*baseBackoff, and so
+// on), or dead-letters it once maxAttempts is reached.
+func (m *AsyncMailer) retryOrDeadLetter(job mailJob, sendErr error) {
+	if job.Attempt+1 >= m.maxAttempts {
+		m.deadLetter(job, sendErr)
+		return
+	}
+
+	backoff := m.baseBackoff << uint(job.Attempt)
+	job.Attempt++
+	time.AfterFunc(backoff, func() {
+		m.enqueue(job)
+	})
+}
+
+func (m *AsyncMailer) deadLetter(job mailJob, sendErr error) {
+	log := logger.GetLogger("mailer")
+	log.Error().Err(sendErr).Str("kind", string(job.Kind)).Str("to", job.To).Msg("Giving up on email after exhausting retries")
+
+	if m.redisClient == nil {
+		return
+	}
+
+	entry, err := json.Marshal(deadLetter{mailJob: job, Error: sendErr.Error(), FailedAt: time.Now()})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal dead-lettered mail job")
+		return
+	}
+
+	if err := m.redisClient.LPush(context.Background(), deadLetterKey, entry).Err(); err != nil {
+		log.Error().Err(err).Msg("Failed to push dead-lettered mail job to Redis")
+	}
+}
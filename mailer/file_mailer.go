@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer writes each message it would send to a .eml file under dir
+// instead of delivering it, so tests can assert on what was sent without a
+// real SMTP server.
+type FileMailer struct {
+	dir       string
+	templates templateSet
+}
+
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{dir: dir}
+}
+
+func (m *FileMailer) SendPasswordReset(to, resetURL string) error {
+	return m.write(to, "password_reset", templateData{ActionURL: resetURL})
+}
+
+func (m *FileMailer) SendEmailVerification(to, verifyURL string) error {
+	return m.write(to, "email_verify", templateData{ActionURL: verifyURL})
+}
+
+func (m *FileMailer) SendInvitation(to, inviteURL string) error {
+	return m.write(to, "invitation", templateData{ActionURL: inviteURL})
+}
+
+func (m *FileMailer) SendLoginNotification(to, deviceInfo, ip string, loginAt time.Time) error {
+	return m.write(to, "login_alert", templateData{DeviceInfo: deviceInfo, IP: ip, LoginAt: loginAt.Format(time.RFC1123)})
+}
+
+func (m *FileMailer) write(to, templateName string, data templateData) error {
+	rendered, err := m.templates.render(templateName, data)
+	if err != nil {
+		return fmt.Errorf("render %s template: %w", templateName, err)
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("%d-%s-%s.eml", time.Now().UnixNano(), templateName, sanitizeFilename(to))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s", to, rendered.subject, rendered.text)
+
+	return os.WriteFile(filepath.Join(m.dir, filename), []byte(content), 0o644)
+}
+
+func sanitizeFilename(s string) string {
+	replacer := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_", ":", "_")
+	return replacer.Replace(s)
+}
@@ -0,0 +1,20 @@
+// Package mailer sends the transactional emails EchoAuth triggers on a
+// user's behalf - password resets, email verification, account
+// invitations - behind a small interface so callers never touch SMTP (or
+// the filesystem, in tests) directly.
+package mailer
+
+import "time"
+
+// Mailer sends one of EchoAuth's transactional emails. SendPasswordReset,
+// SendEmailVerification, and SendInvitation take the destination address
+// and the URL the recipient should follow; SendLoginNotification instead
+// describes the sign-in itself, since it has no link for the recipient to
+// act on. The subject and body all come from the templates bundled in this
+// package.
+type Mailer interface {
+	SendPasswordReset(to, resetURL string) error
+	SendEmailVerification(to, verifyURL string) error
+	SendInvitation(to, inviteURL string) error
+	SendLoginNotification(to, deviceInfo, ip string, loginAt time.Time) error
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryTransport records every Message it's given instead of delivering
+// it, so tests can assert on exactly what a service tried to send without
+// standing up SMTP, SendGrid, or SES.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	Messages []Message
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Send(ctx context.Context, msg Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Messages = append(t.Messages, msg)
+	return nil
+}
+
+// Last returns the most recently sent Message, or the zero Message if none
+// has been sent yet.
+func (t *MemoryTransport) Last() Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.Messages) == 0 {
+		return Message{}
+	}
+	return t.Messages[len(t.Messages)-1]
+}
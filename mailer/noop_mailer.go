@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"time"
+
+	"EchoAuth/utils/logger"
+)
+
+// NoopMailer discards every message, logging what would have been sent. It
+// is the default Mailer when SMTP isn't configured, so the auth flows that
+// depend on a Mailer keep working in development - just without actually
+// emailing anyone.
+type NoopMailer struct{}
+
+func NewNoopMailer() *NoopMailer {
+	return &NoopMailer{}
+}
+
+func (m *NoopMailer) SendPasswordReset(to, resetURL string) error {
+	m.log("password reset", to, resetURL)
+	return nil
+}
+
+func (m *NoopMailer) SendEmailVerification(to, verifyURL string) error {
+	m.log("email verification", to, verifyURL)
+	return nil
+}
+
+func (m *NoopMailer) SendInvitation(to, inviteURL string) error {
+	m.log("invitation", to, inviteURL)
+	return nil
+}
+
+func (m *NoopMailer) SendLoginNotification(to, deviceInfo, ip string, loginAt time.Time) error {
+	m.log("login notification", to, deviceInfo+" "+ip+" "+loginAt.Format(time.RFC1123))
+	return nil
+}
+
+func (m *NoopMailer) log(kind, to, url string) {
+	logger.GetLogger("mailer").Warn().
+		Str("to", to).
+		Str("url", url).
+		Msgf("SMTP is not configured; discarding %s email", kind)
+}
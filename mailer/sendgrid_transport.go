@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+// SendGridTransport delivers mail through SendGrid's HTTP API instead of
+// SMTP, so it works in environments (e.g. serverless, or networks that
+// block outbound port 25/587) where a direct SMTP connection isn't an
+// option.
+type SendGridTransport struct {
+	client *sendgrid.Client
+}
+
+func NewSendGridTransport(apiKey string) *SendGridTransport {
+	return &SendGridTransport{client: sendgrid.NewSendClient(apiKey)}
+}
+
+func (t *SendGridTransport) Send(ctx context.Context, msg Message) error {
+	from := mail.NewEmail("", msg.From)
+	to := mail.NewEmail("", msg.To)
+	sgMsg := mail.NewSingleEmail(from, msg.Subject, to, msg.Text, msg.HTML)
+
+	resp, err := t.client.SendWithContext(ctx, sgMsg)
+	if err != nil {
+		return fmt.Errorf("sendgrid: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid: unexpected status %d: %s", resp.StatusCode, resp.Body)
+	}
+	return nil
+}
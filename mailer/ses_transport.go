@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+// SESClient is the subset of the SESv2 client SESTransport needs, so tests
+// can substitute a fake instead of talking to AWS.
+type SESClient interface {
+	SendEmail(ctx context.Context, params *sesv2.SendEmailInput, optFns ...func(*sesv2.Options)) (*sesv2.SendEmailOutput, error)
+}
+
+// SESTransport delivers mail through AWS Simple Email Service's v2 API.
+type SESTransport struct {
+	client SESClient
+}
+
+func NewSESTransport(client SESClient) *SESTransport {
+	return &SESTransport{client: client}
+}
+
+func (t *SESTransport) Send(ctx context.Context, msg Message) error {
+	_, err := t.client.SendEmail(ctx, &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(msg.From),
+		Destination: &types.Destination{
+			ToAddresses: []string{msg.To},
+		},
+		Content: &types.EmailContent{
+			Simple: &types.Message{
+				Subject: &types.Content{Data: aws.String(msg.Subject)},
+				Body: &types.Body{
+					Text: &types.Content{Data: aws.String(msg.Text)},
+					Html: &types.Content{Data: aws.String(msg.HTML)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("ses: %w", err)
+	}
+	return nil
+}
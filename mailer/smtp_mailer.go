@@ -0,0 +1,115 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/smtp"
+)
+
+// SMTPTLSMode selects how an SMTPTransport secures its connection.
+type SMTPTLSMode string
+
+const (
+	// SMTPTLSStartTLS upgrades a plaintext connection with STARTTLS when the
+	// server advertises it (net/smtp.SendMail's default behavior) - the
+	// right mode for the conventional submission port 587.
+	SMTPTLSStartTLS SMTPTLSMode = "starttls"
+	// SMTPTLSImplicit dials straight into TLS before speaking SMTP at all,
+	// for servers on the legacy SMTPS port 465 that never speak plaintext.
+	SMTPTLSImplicit SMTPTLSMode = "tls"
+	// SMTPTLSNone sends over an unencrypted connection with no STARTTLS
+	// attempt, for a local/dev relay that doesn't support TLS at all.
+	SMTPTLSNone SMTPTLSMode = "none"
+)
+
+// SMTPConfig is the connection and sender info an SMTPTransport needs. An
+// empty Username disables authentication, for local/relay SMTP servers
+// that don't require it. An empty TLSMode defaults to SMTPTLSStartTLS.
+type SMTPConfig struct {
+	Host         string
+	Port         int
+	Username     string
+	Password     string
+	From         string
+	TLSMode      SMTPTLSMode
+	TemplatesDir string // optional: overrides the bundled templates
+}
+
+// SMTPTransport delivers mail through a real SMTP server via net/smtp,
+// secured according to config.TLSMode.
+type SMTPTransport struct {
+	config SMTPConfig
+}
+
+func NewSMTPTransport(config SMTPConfig) *SMTPTransport {
+	return &SMTPTransport{config: config}
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, msg Message) error {
+	raw, err := buildMIMEMessage(msg)
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
+	}
+
+	addr := fmt.Sprintf("%s:%d", t.config.Host, t.config.Port)
+	var auth smtp.Auth
+	if t.config.Username != "" {
+		auth = smtp.PlainAuth("", t.config.Username, t.config.Password, t.config.Host)
+	}
+
+	if t.config.TLSMode == SMTPTLSImplicit {
+		return t.sendImplicitTLS(addr, auth, msg, raw)
+	}
+	return smtp.SendMail(addr, auth, msg.From, []string{msg.To}, raw)
+}
+
+// sendImplicitTLS delivers msg over a connection that's already TLS before
+// any SMTP command is sent, for servers (e.g. legacy port 465) that don't
+// support STARTTLS's plaintext-then-upgrade handshake at all.
+func (t *SMTPTransport) sendImplicitTLS(addr string, auth smtp.Auth, msg Message, raw []byte) error {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: t.config.Host})
+	if err != nil {
+		return fmt.Errorf("dial tls: %w", err)
+	}
+	defer conn.Close()
+
+	client, err := smtp.NewClient(conn, t.config.Host)
+	if err != nil {
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(msg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// NewSMTPMailer is a convenience constructor for the common case of sending
+// the bundled (or on-disk, via config.TemplatesDir) templates straight over
+// SMTP.
+func NewSMTPMailer(config SMTPConfig) *TemplatedMailer {
+	return NewTemplatedMailer(NewSMTPTransport(config), config.From, config.TemplatesDir)
+}
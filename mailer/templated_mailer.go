@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TemplatedMailer is the Mailer every real transport uses: it renders the
+// bundled (or on-disk, via templatesDir) text/HTML templates for each email
+// kind and hands the result to a Transport, so every provider shares
+// identical rendering logic and only differs in how the message is
+// delivered.
+type TemplatedMailer struct {
+	transport    Transport
+	from         string
+	templatesDir string
+	templates    templateSet
+}
+
+// NewTemplatedMailer builds a Mailer that renders templates and delivers
+// them through transport. An empty templatesDir uses the templates bundled
+// with this package.
+func NewTemplatedMailer(transport Transport, from, templatesDir string) *TemplatedMailer {
+	return &TemplatedMailer{
+		transport:    transport,
+		from:         from,
+		templatesDir: templatesDir,
+		templates:    templateSet{dir: templatesDir},
+	}
+}
+
+func (m *TemplatedMailer) SendPasswordReset(to, resetURL string) error {
+	return m.send(to, "password_reset", templateData{ActionURL: resetURL})
+}
+
+func (m *TemplatedMailer) SendEmailVerification(to, verifyURL string) error {
+	return m.send(to, "email_verify", templateData{ActionURL: verifyURL})
+}
+
+func (m *TemplatedMailer) SendInvitation(to, inviteURL string) error {
+	return m.send(to, "invitation", templateData{ActionURL: inviteURL})
+}
+
+func (m *TemplatedMailer) SendLoginNotification(to, deviceInfo, ip string, loginAt time.Time) error {
+	return m.send(to, "login_alert", templateData{DeviceInfo: deviceInfo, IP: ip, LoginAt: loginAt.Format(time.RFC1123)})
+}
+
+func (m *TemplatedMailer) send(to, templateName string, data templateData) error {
+	rendered, err := m.templates.render(templateName, data)
+	if err != nil {
+		return fmt.Errorf("render %s template: %w", templateName, err)
+	}
+
+	return m.transport.Send(context.Background(), Message{
+		From:    m.from,
+		To:      to,
+		Subject: rendered.subject,
+		Text:    rendered.text,
+		HTML:    rendered.html,
+	})
+}
@@ -0,0 +1,100 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	texttemplate "text/template"
+)
+
+// templateFS holds the default templates shipped with EchoAuth. Operators
+// who want different wording can point a Mailer at an on-disk directory
+// with the same file names instead (see templateSet.dir).
+//
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// templateData is the set of values every email template can reference.
+// ActionURL is set for the link-driven templates (password_reset,
+// email_verify, invitation); DeviceInfo/IP/LoginAt are set for login_alert
+// instead, which has no link to follow.
+type templateData struct {
+	ActionURL  string
+	DeviceInfo string
+	IP         string
+	LoginAt    string
+}
+
+// renderedMessage is an email rendered in both formats a mail client might
+// want: text for clients that don't render HTML, html for ones that do.
+type renderedMessage struct {
+	subject string
+	text    string
+	html    string
+}
+
+var templateSubjects = map[string]string{
+	"password_reset": "Reset your password",
+	"email_verify":   "Verify your email address",
+	"invitation":     "You've been invited to EchoAuth",
+	"login_alert":    "New sign-in to your EchoAuth account",
+}
+
+// templateSet renders the named email templates, either from the bundled
+// defaults or, if dir is set, from files of the same name on disk.
+type templateSet struct {
+	dir string
+}
+
+func (t templateSet) source() (fs.FS, error) {
+	if t.dir == "" {
+		return templateFS, nil
+	}
+	return os.DirFS(t.dir), nil
+}
+
+func (t templateSet) render(name string, data templateData) (renderedMessage, error) {
+	source, err := t.source()
+	if err != nil {
+		return renderedMessage{}, err
+	}
+
+	textPath := templatePath(t.dir, name, "txt")
+	textTmpl, err := texttemplate.ParseFS(source, textPath)
+	if err != nil {
+		return renderedMessage{}, err
+	}
+	var textBuf bytes.Buffer
+	if err := textTmpl.Execute(&textBuf, data); err != nil {
+		return renderedMessage{}, err
+	}
+
+	htmlPath := templatePath(t.dir, name, "html")
+	htmlTmpl, err := htmltemplate.ParseFS(source, htmlPath)
+	if err != nil {
+		return renderedMessage{}, err
+	}
+	var htmlBuf bytes.Buffer
+	if err := htmlTmpl.Execute(&htmlBuf, data); err != nil {
+		return renderedMessage{}, err
+	}
+
+	return renderedMessage{
+		subject: templateSubjects[name],
+		text:    textBuf.String(),
+		html:    htmlBuf.String(),
+	}, nil
+}
+
+// templatePath builds the path ParseFS should load: relative to dir when
+// reading an operator override from disk, under templates/ when reading
+// the embedded defaults.
+func templatePath(dir, name, ext string) string {
+	filename := name + "." + ext + ".tmpl"
+	if dir == "" {
+		return "templates/" + filename
+	}
+	return filename
+}
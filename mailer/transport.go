@@ -0,0 +1,21 @@
+package mailer
+
+import "context"
+
+// Message is an already-rendered email, ready to hand to a Transport. It
+// carries both the text and HTML bodies a TemplatedMailer rendered so a
+// Transport never needs to know about templates, only how to deliver mail.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Transport delivers a rendered Message through one concrete provider -
+// SMTP, an HTTP email API, or (in tests) memory. Swapping providers is a
+// matter of swapping the Transport a TemplatedMailer was built with.
+type Transport interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -41,6 +41,21 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		// Add claims to request context
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, "user_id", claims.UserID)
+		ctx = context.WithValue(ctx, "is_admin", claims.IsAdmin)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// AdminOnly rejects any request whose access token doesn't carry the
+// is_admin claim. It must run after Authenticate, which is what populates
+// the is_admin value this reads from the request context.
+func AdminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value("is_admin").(bool)
+		if !isAdmin {
+			response.JSONError(w, "Admin privileges required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(r.Context()))
+	})
+}
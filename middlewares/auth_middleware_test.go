@@ -11,7 +11,7 @@ import (
 
 type mockAuthService struct{}
 
-func (m *mockAuthService) Register(email, password, firstName, lastName string) error {
+func (m *mockAuthService) Register(ctx context.Context, email, password, firstName, lastName string) error {
 	return nil
 }
 
@@ -28,7 +28,7 @@ func (m *mockAuthService) ValidateToken(token string) (*models.TokenClaims, erro
 	return nil, errors.New("invalid token")
 }
 
-func (m *mockAuthService) Logout(token string) error {
+func (m *mockAuthService) Logout(ctx context.Context, token string) error {
 	if token == "valid-token" {
 		return nil
 	}
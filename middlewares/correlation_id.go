@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// CorrelationID propagates the X-Request-ID header onto the request
+// context as "correlation_id", generating one if the caller didn't send it,
+// so every audit event and log line emitted while handling this request can
+// be tied back to the originating call. The same value is echoed back on
+// the response so a caller who didn't send one can still correlate it.
+func CorrelationID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		correlationID := r.Header.Get("X-Request-ID")
+		if correlationID == "" {
+			correlationID = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", correlationID)
+
+		ctx := context.WithValue(r.Context(), "correlation_id", correlationID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
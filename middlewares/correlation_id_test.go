@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelationIDGeneratesWhenMissing(t *testing.T) {
+	var gotFromContext string
+	handler := CorrelationID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotFromContext, _ = r.Context().Value("correlation_id").(string)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.NotEmpty(t, gotFromContext)
+	assert.Equal(t, gotFromContext, w.Header().Get("X-Request-ID"))
+}
+
+func TestCorrelationIDPropagatesIncomingHeader(t *testing.T) {
+	var gotFromContext string
+	handler := CorrelationID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotFromContext, _ = r.Context().Value("correlation_id").(string)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "incoming-id-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, "incoming-id-123", gotFromContext)
+	assert.Equal(t, "incoming-id-123", w.Header().Get("X-Request-ID"))
+}
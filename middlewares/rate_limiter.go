@@ -0,0 +1,637 @@
+package middlewares
+
+import (
+	"EchoAuth/config"
+	"EchoAuth/repositories"
+	"EchoAuth/services"
+	"EchoAuth/utils/metrics"
+	"EchoAuth/utils/response"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// dimensionsForRoute lists the identity dimensions ForRoute enforces for a
+// given route, each resolved and limited independently (see
+// dimensionKey) - a request is rejected if any one of them has tripped.
+// Routes not listed here (invitation) predate the dimension scheme and still
+// use the single-limiter routeLimiters path.
+var dimensionsForRoute = map[string][]string{
+	"login":          {"login_by_ip", "login_by_email"},
+	"register":       {"register_by_ip"},
+	"refresh":        {"refresh_by_user"},
+	"password_reset": {"password_reset_by_ip", "password_reset_by_email"},
+}
+
+// defaultDimensionPolicies is used for any dimension cfg.RateLimit.RateLimits
+// leaves unset or sets to an unparseable spec.
+var defaultDimensionPolicies = map[string]string{
+	"login_by_ip":             "5/5m",
+	"login_by_email":          "5/5m",
+	"register_by_ip":          "5/1h",
+	"refresh_by_user":         "30/1m",
+	"password_reset_by_ip":    "20/1h",
+	"password_reset_by_email": "5/1h",
+	"global_by_ip":            "100/1m",
+}
+
+// KeyFunc derives the rate-limit key for a request along one custom
+// dimension registered via RegisterPolicy, returning ok=false when it can't
+// be resolved for this request (e.g. the caller isn't authenticated yet), in
+// which case that dimension is skipped for this request rather than
+// enforced - the same "fail open on this one dimension" behavior the
+// built-in dimensions get from dimensionKey.
+type KeyFunc func(r *http.Request) (string, bool)
+
+// Policy binds a custom dimension's rate limit to how its key is derived,
+// for RegisterPolicy callers that want to limit a route along something
+// other than the built-in IP/email/user dimensions - most commonly the
+// authenticated user ID once AuthMiddleware has attached it to the request
+// context (see KeyByUserID).
+type Policy struct {
+	Dimension string
+	KeyFunc   KeyFunc
+	Limit     services.RateLimitPolicy
+}
+
+// KeyByUserID is a KeyFunc that keys on the authenticated user ID
+// AuthMiddleware attaches to the request context, for a Policy that should
+// limit per-account rather than per-IP.
+func KeyByUserID(r *http.Request) (string, bool) {
+	userID := r.Context().Value("user_id")
+	if userID == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%v", userID), true
+}
+
+// RateLimiter enforces a loose, global per-IP request limit on every route,
+// plus tighter limits on specific, enumeration/brute-force-prone routes
+// (login, register, refresh, ...), each keyed along one or more identity
+// dimensions (routeDimensions) so a single account or address can't be
+// brute-forced just by spreading attempts across IPs, and a single IP can't
+// be used to hammer many different accounts.
+type RateLimiter struct {
+	redisClient *redis.Client
+
+	// mu guards every field below that UpdateConfig or RegisterPolicy can
+	// change after construction, since both can now run concurrently with
+	// request handling (UpdateConfig from config.Loader.Watch's reload
+	// goroutine, RegisterPolicy from route setup that may race a server
+	// already serving traffic in tests).
+	mu                sync.RWMutex
+	limiter           services.RateLimiter
+	dimensionLimiters map[string]services.RateLimiter
+	routeLimiters     map[string]services.RateLimiter
+	routeDimensions   map[string][]string
+	keyFuncs          map[string]KeyFunc
+	trustedProxies    []*net.IPNet
+	// failClosed rejects a request with 503 when its limiter errors, instead
+	// of the zero-value (and legacy) behavior of letting it through
+	// unchecked. Named so a zero-value RateLimiter{} - as built directly by
+	// existing unit tests - keeps failing open.
+	failClosed bool
+
+	tokenRepo repositories.TokenRepositoryInterface
+}
+
+// NewRateLimiter builds a RateLimiter from cfg.RateLimit. redisClient may be
+// nil, in which case every limit falls back to an in-process token bucket
+// (see services.NewRateLimiter). tokenRepo resolves the refresh_by_user
+// dimension's key, since /refresh runs before AuthMiddleware has attached a
+// user ID to the request context. Each dimension's policy comes from
+// cfg.RateLimit.RateLimits, falling back to defaultDimensionPolicies when
+// unset or unparseable.
+func NewRateLimiter(redisClient *redis.Client, cfg *config.Config, tokenRepo repositories.TokenRepositoryInterface) *RateLimiter {
+	rl := cfg.RateLimit
+
+	dimensionLimiters := make(map[string]services.RateLimiter, len(defaultDimensionPolicies))
+	for name, fallback := range defaultDimensionPolicies {
+		policy := services.MustParsePolicy(fallback)
+		if spec, ok := rl.RateLimits[name]; ok {
+			if parsed, err := services.ParsePolicy(spec); err == nil {
+				policy = parsed
+			}
+		}
+		dimensionLimiters[name] = services.NewRateLimiter(redisClient, policy)
+	}
+
+	routeDimensions := make(map[string][]string, len(dimensionsForRoute))
+	for route, dimensions := range dimensionsForRoute {
+		routeDimensions[route] = append([]string(nil), dimensions...)
+	}
+
+	return &RateLimiter{
+		redisClient:       redisClient,
+		limiter:           dimensionLimiters["global_by_ip"],
+		dimensionLimiters: dimensionLimiters,
+		routeLimiters: map[string]services.RateLimiter{
+			"invitation": newLimiter(redisClient, rl.Invitation, services.MustParsePolicy("10/1h")),
+		},
+		routeDimensions: routeDimensions,
+		keyFuncs:        make(map[string]KeyFunc),
+		tokenRepo:       tokenRepo,
+		trustedProxies:  parseCIDRs(rl.TrustedProxyCIDRs),
+		failClosed:      !rl.FailOpen,
+	}
+}
+
+// RegisterPolicy adds route to rl's dimension checks under policy.Dimension,
+// enforcing policy.Limit and keying each request via policy.KeyFunc.
+// Calling it again for a dimension already in use replaces that dimension's
+// limiter and KeyFunc.
+func (rl *RateLimiter) RegisterPolicy(route string, policy Policy) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.dimensionLimiters[policy.Dimension] = services.NewRateLimiter(rl.redisClient, policy.Limit)
+	rl.keyFuncs[policy.Dimension] = policy.KeyFunc
+	rl.routeDimensions[route] = append(rl.routeDimensions[route], policy.Dimension)
+}
+
+// UpdateConfig rebuilds every built-in dimension's and route's limiter from
+// cfg.RateLimit, the same way NewRateLimiter does, and swaps them in under
+// lock - so a policy edited in a hot-reloaded config file (or via SIGHUP)
+// takes effect without restarting the process. Dimensions and routes added
+// via RegisterPolicy (not driven by cfg.RateLimit) are left untouched.
+func (rl *RateLimiter) UpdateConfig(cfg *config.Config) {
+	rlCfg := cfg.RateLimit
+
+	dimensionLimiters := make(map[string]services.RateLimiter, len(defaultDimensionPolicies))
+	for name, fallback := range defaultDimensionPolicies {
+		policy := services.MustParsePolicy(fallback)
+		if spec, ok := rlCfg.RateLimits[name]; ok {
+			if parsed, err := services.ParsePolicy(spec); err == nil {
+				policy = parsed
+			}
+		}
+		dimensionLimiters[name] = services.NewRateLimiter(rl.redisClient, policy)
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for name, custom := range rl.dimensionLimiters {
+		if _, ok := defaultDimensionPolicies[name]; !ok {
+			dimensionLimiters[name] = custom
+		}
+	}
+	rl.dimensionLimiters = dimensionLimiters
+	rl.limiter = dimensionLimiters["global_by_ip"]
+	rl.routeLimiters = map[string]services.RateLimiter{
+		"invitation": newLimiter(rl.redisClient, rlCfg.Invitation, services.MustParsePolicy("10/1h")),
+	}
+	rl.trustedProxies = parseCIDRs(rlCfg.TrustedProxyCIDRs)
+	rl.failClosed = !rlCfg.FailOpen
+}
+
+func newLimiter(redisClient *redis.Client, window config.RateLimitWindowConfig, fallback services.RateLimitPolicy) services.RateLimiter {
+	maxAttempts := window.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = fallback.MaxAttempts
+	}
+	limitWindow := window.Window
+	if limitWindow <= 0 {
+		limitWindow = fallback.Window
+	}
+	return services.NewRateLimiter(redisClient, services.RateLimiterConfig{
+		MaxAttempts: maxAttempts,
+		Window:      limitWindow,
+		Strategy:    services.StrategySlidingWindow,
+	})
+}
+
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// RateLimit applies the global_by_ip limit to every request it wraps.
+func (rl *RateLimiter) RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := "rate_limit:global_by_ip:" + rl.clientIP(r)
+
+		rl.mu.RLock()
+		limiter := rl.limiter
+		rl.mu.RUnlock()
+
+		decision, err := limiter.Allow(key)
+		if err != nil {
+			rl.handleLimiterError(w, r, next)
+			return
+		}
+
+		setRateLimitHeaders(w, decision)
+		if !decision.Allowed {
+			recordRateLimitHit(w, "global_by_ip", decision)
+			response.JSONError(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ForRoute enforces route's limit(s). Routes with dimensions registered in
+// rl.routeDimensions (the built-in login/register/refresh, plus anything
+// added via RegisterPolicy) are checked along each of their dimensions
+// independently - e.g. login checks login_by_ip and login_by_email - and
+// rejected if any one has tripped. Any other route falls back to the older,
+// single-limiter rl.routeLimiters path (password_reset, invitation), or the
+// global limit if it isn't registered there either.
+func (rl *RateLimiter) ForRoute(route string) func(http.Handler) http.Handler {
+	rl.mu.RLock()
+	_, hasDimensions := rl.routeDimensions[route]
+	rl.mu.RUnlock()
+
+	if hasDimensions {
+		// routeDimensions[route] itself is only ever appended to by
+		// RegisterPolicy at setup, so re-reading it fresh inside
+		// forDimensions isn't necessary here - only the dimensionLimiters
+		// map entries it indexes need to reflect a later UpdateConfig.
+		rl.mu.RLock()
+		dimensions := rl.routeDimensions[route]
+		rl.mu.RUnlock()
+		return rl.forDimensions(route, dimensions)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rl.mu.RLock()
+			limiter, ok := rl.routeLimiters[route]
+			if !ok {
+				limiter = rl.limiter
+			}
+			rl.mu.RUnlock()
+
+			keys := rl.keysForRequest(route, r)
+
+			allowed := true
+			var binding services.Decision
+			bound := false
+			for _, key := range keys {
+				decision, err := limiter.Allow(key)
+				if err != nil {
+					rl.handleLimiterError(w, r, next)
+					return
+				}
+				if !bound || decision.Remaining < binding.Remaining {
+					binding = decision
+					bound = true
+				}
+				if !decision.Allowed {
+					allowed = false
+				}
+			}
+
+			setRateLimitHeaders(w, binding)
+			if !allowed {
+				recordRateLimitHit(w, route, binding)
+				response.JSONError(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forDimensions checks route's request against each of dimensions'
+// dimensionLimiters, keyed by rl.dimensionKey. A dimension whose key can't be
+// resolved (e.g. refresh_by_user for a token that doesn't exist) is skipped
+// rather than enforced, since the global_by_ip limit already backstops it.
+// A denial is labeled with the dimension that tripped, for the
+// rate_limit_hits_total{rule} metric and the Retry-After header.
+func (rl *RateLimiter) forDimensions(route string, dimensions []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var binding services.Decision
+			bound := false
+			deniedRule := ""
+
+			for _, dimension := range dimensions {
+				rl.mu.RLock()
+				limiter, ok := rl.dimensionLimiters[dimension]
+				rl.mu.RUnlock()
+				if !ok {
+					continue
+				}
+				key, ok := rl.dimensionKey(dimension, r)
+				if !ok {
+					continue
+				}
+
+				decision, err := limiter.Allow(key)
+				if err != nil {
+					rl.handleLimiterError(w, r, next)
+					return
+				}
+				if !bound || decision.Remaining < binding.Remaining {
+					binding = decision
+					bound = true
+				}
+				if !decision.Allowed && deniedRule == "" {
+					deniedRule = dimension
+				}
+			}
+
+			if bound {
+				setRateLimitHeaders(w, binding)
+			}
+			if deniedRule != "" {
+				recordRateLimitHit(w, deniedRule, binding)
+				response.JSONError(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// dimensionKey resolves the Redis key a dimension should be enforced
+// against for r, and whether it could be resolved at all. A dimension added
+// via RegisterPolicy is resolved through its registered KeyFunc; the
+// built-in dimensions are resolved below: *_by_ip key on the client IP;
+// login_by_email and password_reset_by_email key on a SHA-256 hash of the
+// email in the request body, so Redis never holds the plaintext address
+// (password_reset_by_email is unresolvable, and so skipped, for the confirm
+// step's body, which carries a token rather than an email); refresh_by_user
+// keys on the user ID owning the refresh token in the request body, resolved
+// via tokenRepo since /refresh runs before AuthMiddleware attaches a user ID
+// to the context.
+func (rl *RateLimiter) dimensionKey(dimension string, r *http.Request) (string, bool) {
+	rl.mu.RLock()
+	keyFunc, ok := rl.keyFuncs[dimension]
+	rl.mu.RUnlock()
+	if ok {
+		key, ok := keyFunc(r)
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("rate_limit:%s:%s", dimension, key), true
+	}
+
+	switch dimension {
+	case "login_by_ip", "register_by_ip", "password_reset_by_ip":
+		return fmt.Sprintf("rate_limit:%s:%s", dimension, rl.clientIP(r)), true
+	case "login_by_email", "password_reset_by_email":
+		email := peekBodyField(r, "email")
+		if email == "" {
+			return "", false
+		}
+		return fmt.Sprintf("rate_limit:%s:%s", dimension, hashEmail(email)), true
+	case "refresh_by_user":
+		if rl.tokenRepo == nil {
+			return "", false
+		}
+		token := peekBodyField(r, "refresh_token")
+		if token == "" {
+			return "", false
+		}
+		refreshToken, err := rl.tokenRepo.GetRefreshToken(token)
+		if err != nil || refreshToken == nil {
+			return "", false
+		}
+		return fmt.Sprintf("rate_limit:refresh_by_user:%d", refreshToken.UserID), true
+	default:
+		return "", false
+	}
+}
+
+// hashEmail returns the hex SHA-256 digest of email's lowercased form, so
+// the login_by_email Redis key doesn't store the address in the clear.
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return hex.EncodeToString(sum[:])
+}
+
+// keysForRequest returns every key ForRoute's legacy, non-dimensioned path
+// should enforce route's limit against: always the client IP, plus the
+// authenticated user ID when AuthMiddleware has already run, so brute-forcing
+// one account from many IPs, or many accounts from one IP, both still trip a
+// limit.
+func (rl *RateLimiter) keysForRequest(route string, r *http.Request) []string {
+	keys := []string{fmt.Sprintf("rate_limit:%s:ip:%s", route, rl.clientIP(r))}
+
+	if userID := r.Context().Value("user_id"); userID != nil {
+		keys = append(keys, fmt.Sprintf("rate_limit:%s:user:%v", route, userID))
+	}
+
+	return keys
+}
+
+// peekBodyField reads a single top-level string field out of a JSON request
+// body without consuming it, so the handler further down the chain can still
+// decode the full body itself. Other fields in the body (e.g. a "scopes"
+// array) are ignored rather than tripping the decode.
+func peekBodyField(r *http.Request, field string) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return ""
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+
+	var value string
+	if err := json.Unmarshal(payload[field], &value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// setRateLimitHeaders surfaces d on the response so clients can see their
+// limit, remaining attempts, and when the window resets without needing to
+// guess from a 429. Header names follow the IETF RateLimit header fields
+// draft (draft-ietf-httpapi-ratelimit-headers); Reset is seconds until the
+// window resets, consistent with Retry-After's delta-seconds form rather
+// than an absolute timestamp.
+func setRateLimitHeaders(w http.ResponseWriter, d services.Decision) {
+	reset := int(time.Until(d.ResetAt).Seconds())
+	if reset < 0 {
+		reset = 0
+	}
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(reset))
+}
+
+// recordRateLimitHit records a rate_limit_hits_total hit for rule and sets
+// Retry-After from d.ResetAt, the oldest entry still inside the window.
+func recordRateLimitHit(w http.ResponseWriter, rule string, d services.Decision) {
+	metrics.RecordRateLimitHit(rule)
+	retryAfter := int(time.Until(d.ResetAt).Seconds())
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+}
+
+// RateLimitMiddleware wraps next with limiter enforced against the key
+// keyFunc derives for each request, namespaced and labeled by rule (used for
+// the rate_limit_hits_total{rule} metric and to keep this limiter's Redis
+// keys from colliding with another rule reusing the same keyFunc). A request
+// keyFunc can't derive a key for (ok=false) passes through unchecked, the
+// same "fail open on this dimension" behavior RateLimiter.forDimensions
+// gives its own unresolvable dimensions. Unlike RateLimiter.ForRoute, this is
+// a standalone helper for a single ad-hoc limiter+key pairing - e.g. a
+// non-auth API route throttled per API key - that doesn't need registering
+// as a dimension on the shared RateLimiter.
+func RateLimitMiddleware(rule string, limiter services.RateLimiter, keyFunc KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := keyFunc(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			decision, err := limiter.Allow(fmt.Sprintf("rate_limit:%s:%s", rule, key))
+			if err != nil {
+				response.JSONError(w, "Rate limiter temporarily unavailable", http.StatusServiceUnavailable)
+				return
+			}
+
+			setRateLimitHeaders(w, decision)
+			if !decision.Allowed {
+				recordRateLimitHit(w, rule, decision)
+				response.JSONError(w, "Rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handleLimiterError runs when a limiter's Allow call itself errored (e.g.
+// Redis is unreachable), as opposed to it returning a normal not-allowed
+// Decision. rl.failClosed decides which way to err: true rejects the
+// request with 503 rather than risk a brute force running unchecked past a
+// limiter that's merely down; false (the zero value, and legacy behavior)
+// lets it through.
+func (rl *RateLimiter) handleLimiterError(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if !rl.failClosed {
+		next.ServeHTTP(w, r)
+		return
+	}
+	response.JSONError(w, "Rate limiter temporarily unavailable", http.StatusServiceUnavailable)
+}
+
+// clientIP returns the address the rate limiter should key on. When
+// RemoteAddr matches a configured trusted proxy CIDR, it walks
+// X-Forwarded-For from right to left, skipping entries that are themselves
+// trusted proxies, and returns the first one that isn't - the real client,
+// per RFC 7239's "closest to furthest" ordering. The standard Forwarded
+// header is preferred over X-Forwarded-For when both are present, since
+// it's the one an RFC 7239-aware proxy actually sets. Without a
+// trusted-proxy allowlist, or when every hop looks trusted, it falls back
+// to RemoteAddr itself; otherwise any client could blindly prepend its own
+// spoofed IP and dodge its own rate limit.
+func (rl *RateLimiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !rl.fromTrustedProxy(host) {
+		return host
+	}
+
+	var hops []string
+	if forwarded := r.Header.Get("Forwarded"); forwarded != "" {
+		hops = parseForwardedFor(forwarded)
+	} else if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops = strings.Split(xff, ",")
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := forwardedHop(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !rl.fromTrustedProxy(hop) {
+			return hop
+		}
+	}
+
+	return host
+}
+
+func (rl *RateLimiter) fromTrustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range rl.trustedProxies {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedForPattern extracts the value of the "for" parameter from one
+// RFC 7239 Forwarded header element, quoted or not.
+var forwardedForPattern = regexp.MustCompile(`(?i)for=("[^"]*"|[^;,\s]*)`)
+
+// parseForwardedFor returns each hop's claimed client address from an RFC
+// 7239 Forwarded header, in the same left-to-right, nearest-to-origin-last
+// order X-Forwarded-For uses, so both can be walked the same way.
+func parseForwardedFor(header string) []string {
+	var hops []string
+	for _, element := range strings.Split(header, ",") {
+		m := forwardedForPattern.FindStringSubmatch(element)
+		if m == nil {
+			continue
+		}
+		hops = append(hops, strings.Trim(m[1], `"`))
+	}
+	return hops
+}
+
+// forwardedHop strips a trailing :port and, for a bracketed IPv6 literal,
+// the brackets themselves from one Forwarded/X-Forwarded-For hop (e.g.
+// `[2001:db8::1]:8080` -> `2001:db8::1`). An obfuscated identifier
+// (RFC 7239 allows e.g. "_hidden" or "unknown" in place of a real address)
+// isn't a parseable host at all; it's returned unchanged, which makes
+// fromTrustedProxy reject it as untrusted and end the walk there, the same
+// as if that hop were an ordinary non-proxy client address.
+func forwardedHop(hop string) string {
+	hop = strings.TrimSpace(hop)
+	if strings.HasPrefix(hop, "[") {
+		if end := strings.Index(hop, "]"); end != -1 {
+			return hop[1:end]
+		}
+		return hop
+	}
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		return host
+	}
+	return hop
+}
@@ -1,9 +1,14 @@
 package middlewares
 
 import (
+	"EchoAuth/services"
+	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -14,9 +19,10 @@ type mockRateLimiterService struct {
 	mock.Mock
 }
 
-func (m *mockRateLimiterService) Allow(key string) (bool, error) {
+func (m *mockRateLimiterService) Allow(key string) (services.Decision, error) {
 	args := m.Called(key)
-	return args.Bool(0), args.Error(1)
+	decision, _ := args.Get(0).(services.Decision)
+	return decision, args.Error(1)
 }
 
 func (m *mockRateLimiterService) Reset(key string) error {
@@ -44,7 +50,7 @@ func TestRateLimiter_RateLimit(t *testing.T) {
 		{
 			name: "Request allowed",
 			setupMock: func(m *mockRateLimiterService) {
-				m.On("Allow", mock.Anything).Return(true, nil)
+				m.On("Allow", mock.Anything).Return(services.Decision{Allowed: true, Limit: 100, Remaining: 99}, nil)
 			},
 			remoteAddr:     "192.168.1.1:1234",
 			expectedStatus: http.StatusOK,
@@ -52,7 +58,7 @@ func TestRateLimiter_RateLimit(t *testing.T) {
 		{
 			name: "Rate limit exceeded",
 			setupMock: func(m *mockRateLimiterService) {
-				m.On("Allow", mock.Anything).Return(false, nil)
+				m.On("Allow", mock.Anything).Return(services.Decision{Allowed: false, Limit: 100}, nil)
 			},
 			remoteAddr:     "192.168.1.1:1234",
 			expectedStatus: http.StatusTooManyRequests,
@@ -60,7 +66,7 @@ func TestRateLimiter_RateLimit(t *testing.T) {
 		{
 			name: "Service error",
 			setupMock: func(m *mockRateLimiterService) {
-				m.On("Allow", mock.Anything).Return(false, assert.AnError)
+				m.On("Allow", mock.Anything).Return(services.Decision{}, assert.AnError)
 			},
 			remoteAddr:     "192.168.1.1:1234",
 			expectedStatus: http.StatusOK, // Should pass through on error
@@ -92,6 +98,172 @@ func TestRateLimiter_RateLimit(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_FailClosed(t *testing.T) {
+	limiter := &mockRateLimiterService{}
+	limiter.On("Allow", mock.Anything).Return(services.Decision{}, assert.AnError)
+
+	rateLimiter := &RateLimiter{
+		limiter:    limiter,
+		failClosed: true,
+	}
+
+	handler := rateLimiter.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	limiter.AssertExpectations(t)
+}
+
+func TestRateLimiter_RegisterPolicy(t *testing.T) {
+	limiter := &mockRateLimiterService{}
+	limiter.On("Allow", "rate_limit:export_by_user:42").
+		Return(services.Decision{Allowed: false, Limit: 10, Remaining: 0}, nil)
+
+	rateLimiter := &RateLimiter{
+		dimensionLimiters: map[string]services.RateLimiter{},
+		routeDimensions:   map[string][]string{},
+		keyFuncs:          map[string]KeyFunc{},
+	}
+	rateLimiter.RegisterPolicy("export", Policy{
+		Dimension: "export_by_user",
+		KeyFunc:   KeyByUserID,
+		Limit:     services.RateLimitPolicy{MaxAttempts: 10, Window: time.Minute},
+	})
+	rateLimiter.dimensionLimiters["export_by_user"] = limiter
+
+	handler := rateLimiter.ForRoute("export")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/export", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "user_id", 42))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	limiter.AssertExpectations(t)
+}
+
+func TestRateLimiter_PasswordResetDimensions(t *testing.T) {
+	emailLimiter := &mockRateLimiterService{}
+	emailLimiter.On("Allow", mock.Anything).Return(services.Decision{Allowed: false, Limit: 5, Remaining: 0}, nil)
+	ipLimiter := &mockRateLimiterService{}
+	ipLimiter.On("Allow", mock.Anything).Return(services.Decision{Allowed: true, Limit: 20, Remaining: 19}, nil)
+
+	rateLimiter := &RateLimiter{
+		dimensionLimiters: map[string]services.RateLimiter{
+			"password_reset_by_email": emailLimiter,
+			"password_reset_by_ip":    ipLimiter,
+		},
+		routeDimensions: map[string][]string{
+			"password_reset": {"password_reset_by_ip", "password_reset_by_email"},
+		},
+		keyFuncs: map[string]KeyFunc{},
+	}
+
+	handler := rateLimiter.ForRoute("password_reset")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	body := strings.NewReader(`{"email":"user@example.com"}`)
+	req := httptest.NewRequest(http.MethodPost, "/password-reset/request", body)
+	req.RemoteAddr = "192.168.1.1:1234"
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	emailLimiter.AssertExpectations(t)
+	ipLimiter.AssertExpectations(t)
+}
+
+func trustedProxyRateLimiter(cidrs ...string) *RateLimiter {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return &RateLimiter{trustedProxies: nets}
+}
+
+func TestRateLimiter_ClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		headers        map[string]string
+		want           string
+	}{
+		{
+			name:           "untrusted source spoofing X-Forwarded-For is ignored",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "203.0.113.5:1234",
+			headers:        map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			want:           "203.0.113.5",
+		},
+		{
+			name:           "trusted proxy's X-Forwarded-For is honored",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string]string{"X-Forwarded-For": "203.0.113.5"},
+			want:           "203.0.113.5",
+		},
+		{
+			name:           "trusted proxy hops are stripped from the right",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string]string{"X-Forwarded-For": "203.0.113.5, 10.0.0.2"},
+			want:           "203.0.113.5",
+		},
+		{
+			name:           "Forwarded header is preferred and its for= parsed",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string]string{"Forwarded": `for=203.0.113.5;proto=https, for=10.0.0.2`},
+			want:           "203.0.113.5",
+		},
+		{
+			name:           "Forwarded header handles bracketed IPv6",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string]string{"Forwarded": `for="[2001:db8::1]:8080"`},
+			want:           "2001:db8::1",
+		},
+		{
+			name:           "Forwarded header with an obfuscated identifier ends the walk there",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:1234",
+			headers:        map[string]string{"Forwarded": `for=_hidden, for=10.0.0.2`},
+			want:           "_hidden",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := trustedProxyRateLimiter(tt.trustedProxies...)
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			assert.Equal(t, tt.want, rl.clientIP(req))
+		})
+	}
+}
+
 func TestRateLimiter_WithHeaders(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -102,7 +274,7 @@ func TestRateLimiter_WithHeaders(t *testing.T) {
 		{
 			name: "X-Forwarded-For header",
 			setupMock: func(m *mockRateLimiterService) {
-				m.On("Allow", mock.Anything).Return(true, nil)
+				m.On("Allow", mock.Anything).Return(services.Decision{Allowed: true, Limit: 100, Remaining: 99}, nil)
 			},
 			headers: map[string]string{
 				"X-Forwarded-For": "10.0.0.1",
@@ -112,7 +284,7 @@ func TestRateLimiter_WithHeaders(t *testing.T) {
 		{
 			name: "X-Real-IP header",
 			setupMock: func(m *mockRateLimiterService) {
-				m.On("Allow", mock.Anything).Return(true, nil)
+				m.On("Allow", mock.Anything).Return(services.Decision{Allowed: true, Limit: 100, Remaining: 99}, nil)
 			},
 			headers: map[string]string{
 				"X-Real-IP": "10.0.0.1",
@@ -122,7 +294,7 @@ func TestRateLimiter_WithHeaders(t *testing.T) {
 		{
 			name: "Multiple X-Forwarded-For IPs",
 			setupMock: func(m *mockRateLimiterService) {
-				m.On("Allow", mock.Anything).Return(true, nil)
+				m.On("Allow", mock.Anything).Return(services.Decision{Allowed: true, Limit: 100, Remaining: 99}, nil)
 			},
 			headers: map[string]string{
 				"X-Forwarded-For": "10.0.0.1, 192.168.1.1",
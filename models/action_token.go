@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// TokenPurpose distinguishes the different single-use links EchoAuth emails
+// out, so TTL, revocation-on-use, and lookup can share one table and one
+// code path instead of being reimplemented per flow.
+type TokenPurpose string
+
+const (
+	TokenPurposeReset  TokenPurpose = "reset"
+	TokenPurposeVerify TokenPurpose = "verify"
+	TokenPurposeInvite TokenPurpose = "invite"
+	// TokenPurposeMFA backs the short-lived mfa_token a client exchanges
+	// for a completed login once AuthService.LoginWithRefresh reports
+	// ErrMFARequired for a user with TOTP enabled; see
+	// AuthService.IssueMFAToken/CompleteMFALogin.
+	TokenPurposeMFA TokenPurpose = "mfa_challenge"
+)
+
+// ActionToken is a single-use token tied to a user and a purpose: a
+// password reset link, an email-verification link, or an invitation. Only
+// a SHA-256 hash of the token value is ever persisted (see
+// ActionTokenRepository); Token itself holds the plaintext only in memory,
+// for the caller that just generated it to put in an email link.
+type ActionToken struct {
+	ID         uint              `json:"id" gorm:"primaryKey"`
+	UserID     uint              `json:"user_id"`
+	Token      string            `json:"-" gorm:"-"`
+	Purpose    TokenPurpose      `json:"purpose"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+	CreatedAt  time.Time         `json:"created_at"`
+	ConsumedAt *time.Time        `json:"consumed_at,omitempty"`
+	Payload    map[string]string `json:"payload,omitempty"`
+}
+
+// Expired reports whether the token is past its TTL as of now.
+func (t *ActionToken) Expired(now time.Time) bool {
+	return t.ExpiresAt.IsZero() || now.After(t.ExpiresAt)
+}
+
+// Consumed reports whether the token has already been redeemed.
+func (t *ActionToken) Consumed() bool {
+	return t.ConsumedAt != nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// AuditLog is a durable, per-user-queryable record of a security-relevant
+// action. It's written independently of whichever audit.Logger sink a
+// request path also logs to (stdout, a rotated file, a Redis stream), so a
+// user's own history survives regardless of how that sink is configured -
+// see repositories.AuditLogRepository and the GET /me/audit endpoint.
+type AuditLog struct {
+	ID        uint      `json:"id"`
+	EventType string    `json:"event_type"`
+	UserID    *uint     `json:"user_id,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Success   bool      `json:"success"`
+	Metadata  string    `json:"metadata,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -8,6 +8,15 @@ import (
 
 type TokenClaims struct {
 	UserID uint `json:"user_id"`
+	// IsAdmin mirrors the user's IsAdmin flag as of token issuance, so
+	// AdminOnly middleware can authorize a request from the token alone
+	// without a database round trip. A user promoted or demoted after a
+	// token was issued takes effect on that token's next refresh.
+	IsAdmin bool `json:"is_admin,omitempty"`
+	// Nonce echoes the value an OIDC client supplied to /oauth/authorize, so
+	// it can match the ID token to the authorization request it came from.
+	// Empty for tokens issued outside the OIDC authorization_code flow.
+	Nonce string `json:"nonce,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -31,3 +40,33 @@ func (c *TokenClaims) Valid() error {
 
 	return nil
 }
+
+// ClientClaims are the claims carried by a client_credentials access token:
+// unlike TokenClaims there is no human user, so the subject is the client
+// itself and UserID would always be zero.
+type ClientClaims struct {
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope,omitempty"`
+	jwt.StandardClaims
+}
+
+// Valid implements the jwt.Claims interface and adds custom validation.
+func (c *ClientClaims) Valid() error {
+	if err := c.StandardClaims.Valid(); err != nil {
+		return err
+	}
+
+	if c.ClientID == "" {
+		return errors.New("missing client ID")
+	}
+
+	if c.ExpiresAt == 0 {
+		return errors.New("missing expiry time")
+	}
+
+	if c.IssuedAt == 0 {
+		return errors.New("missing issued at time")
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package models
+
+// Introspection is an RFC 7662 token introspection response. Fields beyond
+// Active are omitted when the token is not active, per the RFC.
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+}
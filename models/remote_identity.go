@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// RemoteIdentity links a User to an account on an external identity
+// provider (OAuth, SAML, ...), so a login can be resolved to a User without
+// a password.
+type RemoteIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id"`
+	Provider  string    `json:"provider"`
+	SubjectID string    `json:"subject_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// EncryptedUpstreamRefreshToken holds the provider's refresh token
+	// (AES-GCM encrypted, see RemoteIdentityRepository), when the connector
+	// that created this identity requested offline access. Never
+	// serialized: it's only ever read back by the connector that needs to
+	// silently refresh the upstream session.
+	EncryptedUpstreamRefreshToken []byte `json:"-"`
+}
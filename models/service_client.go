@@ -0,0 +1,126 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ServiceClient is a downstream service authorized to call
+// POST /auth/introspect and, for clients with a non-empty AllowedGrantTypes,
+// the /oauth/token endpoint - identified by ClientID and authenticated with
+// a hashed secret, distinct from a human User, and from each other, so one
+// client's credentials can be rotated or revoked without affecting any
+// other resource server.
+type ServiceClient struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	ClientID          string     `json:"client_id" gorm:"uniqueIndex"`
+	HashedSecret      string     `json:"-"`
+	Name              string     `json:"name"`
+	RedirectURIs      []string   `json:"redirect_uris" gorm:"-"`
+	AllowedScopes     []string   `json:"allowed_scopes" gorm:"-"`
+	AllowedGrantTypes []string   `json:"allowed_grant_types" gorm:"-"`
+	CreatedAt         time.Time  `json:"created_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+}
+
+// RedirectURIsColumn returns RedirectURIs joined for storage in the
+// comma-separated redirect_uris column.
+func (c *ServiceClient) RedirectURIsColumn() string {
+	return strings.Join(c.RedirectURIs, ",")
+}
+
+// SetRedirectURIsFromColumn populates RedirectURIs from the comma-separated
+// redirect_uris column value.
+func (c *ServiceClient) SetRedirectURIsFromColumn(column string) {
+	if column == "" {
+		c.RedirectURIs = nil
+		return
+	}
+	c.RedirectURIs = strings.Split(column, ",")
+}
+
+// AllowedScopesColumn returns AllowedScopes joined for storage in the
+// comma-separated allowed_scopes column.
+func (c *ServiceClient) AllowedScopesColumn() string {
+	return strings.Join(c.AllowedScopes, ",")
+}
+
+// SetAllowedScopesFromColumn populates AllowedScopes from the
+// comma-separated allowed_scopes column value.
+func (c *ServiceClient) SetAllowedScopesFromColumn(column string) {
+	if column == "" {
+		c.AllowedScopes = nil
+		return
+	}
+	c.AllowedScopes = strings.Split(column, ",")
+}
+
+// AllowedGrantTypesColumn returns AllowedGrantTypes joined for storage in
+// the comma-separated allowed_grant_types column.
+func (c *ServiceClient) AllowedGrantTypesColumn() string {
+	return strings.Join(c.AllowedGrantTypes, ",")
+}
+
+// SetAllowedGrantTypesFromColumn populates AllowedGrantTypes from the
+// comma-separated allowed_grant_types column value.
+func (c *ServiceClient) SetAllowedGrantTypesFromColumn(column string) {
+	if column == "" {
+		c.AllowedGrantTypes = nil
+		return
+	}
+	c.AllowedGrantTypes = strings.Split(column, ",")
+}
+
+// AllowsRedirectURI reports whether uri is registered for this client. A
+// client with no registered redirect URIs allows none, rather than
+// matching anything.
+func (c *ServiceClient) AllowsRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScope reports whether scope is in the client's allowed scopes.
+func (c *ServiceClient) AllowsScope(scope string) bool {
+	for _, s := range c.AllowedScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsGrantType reports whether grantType is one this client is
+// registered to use.
+func (c *ServiceClient) AllowsGrantType(grantType string) bool {
+	for _, g := range c.AllowedGrantTypes {
+		if g == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// HashSecret hashes plaintext and stores it in the client
+func (c *ServiceClient) HashSecret(plaintext string) error {
+	hashed, err := defaultHasher.Hash(plaintext)
+	if err != nil {
+		return err
+	}
+	c.HashedSecret = hashed
+	return nil
+}
+
+// CheckSecret checks if the provided secret matches the hashed secret
+func (c *ServiceClient) CheckSecret(plaintext string) bool {
+	ok, err := defaultHasher.Verify(plaintext, c.HashedSecret)
+	return err == nil && ok
+}
+
+// Revoked reports whether the client's credentials have been revoked.
+func (c *ServiceClient) Revoked() bool {
+	return c.RevokedAt != nil
+}
@@ -1,16 +1,22 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type RefreshToken struct {
-	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ID uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	// Token is the bearer secret itself - only ever held in memory (to
+	// return to the client on issue, or to look itself up by hash) and
+	// never persisted; the repository stores only TokenHash.
+	Token      string     `json:"-" gorm:"-"`
+	TokenHash  string     `json:"-"`
 	UserID     uint       `json:"user_id"`
-	Token      string     `json:"token" gorm:"unique;not null"`
 	Used       bool       `json:"used" gorm:"default:false"`
+	UsedAt     *time.Time `json:"used_at"`
 	RevokedAt  *time.Time `json:"revoked_at"`
 	ExpiresAt  time.Time  `json:"expires_at"`
 	CreatedAt  time.Time  `json:"created_at"`
@@ -18,9 +24,80 @@ type RefreshToken struct {
 	PreviousID *uuid.UUID `json:"previous_id" gorm:"type:uuid"`
 	DeviceInfo string     `json:"device_info"`
 	IP         string     `json:"ip"`
+	Scopes     []string   `json:"scopes" gorm:"-"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	FamilyID   uuid.UUID  `json:"family_id" gorm:"type:uuid"`
+}
+
+// Session is the external, device-facing view of a refresh token: enough
+// for a "logged-in devices" UI to list and revoke a session without
+// exposing the token secret itself.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	DeviceInfo string    `json:"device_info"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// ScopesColumn returns Scopes joined for storage in the comma-separated
+// scopes column.
+func (rt *RefreshToken) ScopesColumn() string {
+	return strings.Join(rt.Scopes, ",")
+}
+
+// SetScopesFromColumn populates Scopes from the comma-separated scopes
+// column value.
+func (rt *RefreshToken) SetScopesFromColumn(column string) {
+	if column == "" {
+		rt.Scopes = nil
+		return
+	}
+	rt.Scopes = strings.Split(column, ",")
+}
+
+// HasScope reports whether scope is present on the token.
+func (rt *RefreshToken) HasScope(scope string) bool {
+	for _, s := range rt.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
 
 // IsValid checks if the refresh token is still valid
 func (rt *RefreshToken) IsValid() bool {
 	return !rt.Used && rt.RevokedAt == nil && rt.ExpiresAt.After(time.Now())
 }
+
+// RecentlyUsed reports whether the token was marked used within the last
+// graceWindow. RefreshToken uses this to let a client that rotated
+// successfully but never saw the response retry with the same (now used)
+// token instead of tripping reuse detection; a graceWindow <= 0 disables the
+// allowance entirely.
+func (rt *RefreshToken) RecentlyUsed(graceWindow time.Duration) bool {
+	return graceWindow > 0 && rt.UsedAt != nil && time.Since(*rt.UsedAt) <= graceWindow
+}
+
+// IdleExpired reports whether the token hasn't been used within idleTimeout,
+// even though its absolute ExpiresAt may still be in the future. A
+// idleTimeout <= 0 disables idle expiration entirely.
+func (rt *RefreshToken) IdleExpired(idleTimeout time.Duration) bool {
+	return idleTimeout > 0 && time.Since(rt.LastUsedAt) > idleTimeout
+}
+
+// EffectiveExpiresAt returns whichever of ExpiresAt and the idle deadline
+// (LastUsedAt+idleTimeout) comes first, so callers that surface a single
+// deadline (e.g. token introspection) reflect whichever limit would
+// actually end the session first.
+func (rt *RefreshToken) EffectiveExpiresAt(idleTimeout time.Duration) time.Time {
+	if idleTimeout <= 0 {
+		return rt.ExpiresAt
+	}
+	idleDeadline := rt.LastUsedAt.Add(idleTimeout)
+	if idleDeadline.Before(rt.ExpiresAt) {
+		return idleDeadline
+	}
+	return rt.ExpiresAt
+}
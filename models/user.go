@@ -1,40 +1,129 @@
 package models
 
 import (
+	"EchoAuth/password"
 	"EchoAuth/utils/validator"
+	"strings"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// defaultHasher is used whenever SetPasswordHasher hasn't been called, so
+// HashPassword/CheckPassword work out of the box in tests and other
+// contexts that don't wire up a config-driven policy.
+var defaultHasher password.Hasher = func() password.Hasher {
+	policy, err := password.NewPolicy(argon2idDefault, map[string]password.Hasher{
+		argon2idDefault: password.NewArgon2idHasher(password.DefaultArgon2idParams()),
+		"bcrypt":        password.NewBcryptHasher(10),
+		"scrypt":        password.NewScryptHasher(password.DefaultScryptParams()),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}()
+
+const argon2idDefault = "argon2id"
+
+// SetPasswordHasher overrides the Hasher used by HashPassword/CheckPassword.
+// Call it once at startup with a policy built from configuration; without
+// it, a safe set of defaults is used.
+func SetPasswordHasher(h password.Hasher) {
+	defaultHasher = h
+}
+
 type User struct {
 	ID                  uint           `json:"id" gorm:"primaryKey"`
 	Email               string         `json:"email" gorm:"uniqueIndex"`
 	Password            string         `json:"-"`
 	FirstName           string         `json:"first_name"`
 	LastName            string         `json:"last_name"`
-	PasswordResetToken  string         `json:"-" gorm:"uniqueIndex"`
-	ResetTokenExpiresAt time.Time      `json:"-"`
-	CreatedAt           time.Time      `json:"created_at"`
-	UpdatedAt           time.Time      `json:"updated_at"`
-	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
+	EmailVerified       bool           `json:"email_verified"`
+	IsAdmin             bool           `json:"is_admin"`
+	InvitedBy           *int64         `json:"invited_by,omitempty"`
+	PasswordChangedAt   time.Time      `json:"-"`
+	PasswordExpiresAt   *time.Time     `json:"-"`
+	FailedLoginAttempts int            `json:"-"`
+	LockedUntil         *time.Time     `json:"-"`
+	MFAEnabled          bool           `json:"mfa_enabled"`
+	// MFASecretEncrypted holds the user's TOTP secret (AES-GCM encrypted,
+	// see MFAService), set once EnrollTOTP runs and never serialized.
+	MFASecretEncrypted []byte `json:"-" gorm:"-"`
+	// MFARecoveryCodeHashes holds bcrypt hashes of the user's remaining
+	// single-use MFA recovery codes; see MFARecoveryCodesColumn.
+	MFARecoveryCodeHashes []string       `json:"-" gorm:"-"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	DeletedAt             gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// MFARecoveryCodesColumn returns MFARecoveryCodeHashes joined for storage in
+// the comma-separated mfa_recovery_codes column.
+func (u *User) MFARecoveryCodesColumn() string {
+	return strings.Join(u.MFARecoveryCodeHashes, ",")
+}
+
+// SetMFARecoveryCodesFromColumn populates MFARecoveryCodeHashes from the
+// comma-separated mfa_recovery_codes column value.
+func (u *User) SetMFARecoveryCodesFromColumn(column string) {
+	if column == "" {
+		u.MFARecoveryCodeHashes = nil
+		return
+	}
+	u.MFARecoveryCodeHashes = strings.Split(column, ",")
+}
+
+// PasswordExpired reports whether the user's current password is past its
+// max age and must be changed before a normal session can be issued.
+func (u *User) PasswordExpired(now time.Time) bool {
+	return u.PasswordExpiresAt != nil && now.After(*u.PasswordExpiresAt)
 }
 
 // HashPassword hashes the provided password and stores it in the user model
-func (u *User) HashPassword(password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (u *User) HashPassword(plaintext string) error {
+	hashed, err := defaultHasher.Hash(plaintext)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hashedPassword)
+	u.Password = hashed
 	return nil
 }
 
 // CheckPassword checks if the provided password matches the hashed password
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+func (u *User) CheckPassword(plaintext string) bool {
+	ok, err := defaultHasher.Verify(plaintext, u.Password)
+	return err == nil && ok
+}
+
+// NeedsPasswordRehash reports whether the user's stored hash was produced
+// by a different algorithm, or the same algorithm with weaker parameters,
+// than the current password policy - i.e. whether it should be replaced
+// with a fresh hash next time the plaintext password is available (e.g. on
+// successful login).
+func (u *User) NeedsPasswordRehash() bool {
+	return PasswordNeedsRehash(u.Password)
+}
+
+// PasswordNeedsRehash reports whether an encoded hash was produced by a
+// different algorithm, or the same algorithm with weaker parameters, than
+// the current password policy. Unlike NeedsPasswordRehash it doesn't need a
+// User instance, so an operator tool can sweep stored hashes (e.g. from a
+// backup or an export) without loading a full row.
+func PasswordNeedsRehash(hash string) bool {
+	return defaultHasher.NeedsRehash(hash)
+}
+
+// RecordPasswordChange stamps PasswordChangedAt with now and computes the
+// next PasswordExpiresAt from maxAge. A zero maxAge disables expiration.
+func (u *User) RecordPasswordChange(now time.Time, maxAge time.Duration) {
+	u.PasswordChangedAt = now
+	if maxAge <= 0 {
+		u.PasswordExpiresAt = nil
+		return
+	}
+	expiresAt := now.Add(maxAge)
+	u.PasswordExpiresAt = &expiresAt
 }
 
 // Validate performs validation on the user model
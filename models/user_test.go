@@ -199,3 +199,82 @@ func TestUserTimestamps(t *testing.T) {
 		t.Error("UpdatedAt was not set correctly")
 	}
 }
+
+func TestUserRecordPasswordChange(t *testing.T) {
+	u := &User{}
+	now := time.Now()
+
+	u.RecordPasswordChange(now, 90*24*time.Hour)
+	if u.PasswordChangedAt != now {
+		t.Errorf("PasswordChangedAt = %v, want %v", u.PasswordChangedAt, now)
+	}
+	if u.PasswordExpiresAt == nil || !u.PasswordExpiresAt.Equal(now.Add(90*24*time.Hour)) {
+		t.Errorf("PasswordExpiresAt = %v, want %v", u.PasswordExpiresAt, now.Add(90*24*time.Hour))
+	}
+
+	u.RecordPasswordChange(now, 0)
+	if u.PasswordExpiresAt != nil {
+		t.Error("expected PasswordExpiresAt to be nil when maxAge is 0")
+	}
+}
+
+func TestUserPasswordExpired(t *testing.T) {
+	u := &User{}
+	now := time.Now()
+
+	if u.PasswordExpired(now) {
+		t.Error("expected password with no expiry to never be reported expired")
+	}
+
+	past := now.Add(-time.Hour)
+	u.PasswordExpiresAt = &past
+	if !u.PasswordExpired(now) {
+		t.Error("expected password past its expiry to be reported expired")
+	}
+
+	future := now.Add(time.Hour)
+	u.PasswordExpiresAt = &future
+	if u.PasswordExpired(now) {
+		t.Error("expected password not yet past its expiry to not be reported expired")
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	u := &User{}
+	if err := u.HashPassword("password123"); err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	if PasswordNeedsRehash(u.Password) {
+		t.Error("PasswordNeedsRehash() = true for a hash just produced by the current policy")
+	}
+	if !PasswordNeedsRehash("$2a$10$invalidbcrypthashdoesnotmatter") {
+		t.Error("PasswordNeedsRehash() = false for a legacy bcrypt hash")
+	}
+	if !PasswordNeedsRehash("not a valid hash") {
+		t.Error("PasswordNeedsRehash() = false for an unparseable hash")
+	}
+}
+
+// FuzzHashPassword checks that HashPassword/CheckPassword round-trip for
+// arbitrary input, and that CheckPassword never accepts a different
+// password than the one that was hashed.
+func FuzzHashPassword(f *testing.F) {
+	f.Add("password123")
+	f.Add("")
+	f.Add("verylongpasswordthatismorethan72characters123456789012345678901234567890")
+	f.Add("p@ssw0rd!💥")
+
+	f.Fuzz(func(t *testing.T, password string) {
+		u := &User{}
+		if err := u.HashPassword(password); err != nil {
+			t.Fatalf("HashPassword() error = %v", err)
+		}
+		if !u.CheckPassword(password) {
+			t.Fatalf("CheckPassword(%q) = false after hashing the same password", password)
+		}
+		if password != "" && u.CheckPassword(password+"x") {
+			t.Fatalf("CheckPassword(%q) = true for a different password", password+"x")
+		}
+	})
+}
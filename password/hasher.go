@@ -0,0 +1,18 @@
+// Package password hashes and verifies user passwords behind a pluggable
+// Hasher interface, so the hashing algorithm and its cost parameters can be
+// changed over time without a schema migration: every hash is self
+// describing, encoded in PHC string format
+// ($<algorithm>$<params>$<salt>$<hash>).
+package password
+
+// Hasher hashes and verifies passwords for one algorithm.
+type Hasher interface {
+	// Hash returns a new PHC-formatted encoded hash of password.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encodedHash.
+	Verify(password, encodedHash string) (bool, error)
+	// NeedsRehash reports whether encodedHash was produced by a different
+	// algorithm, or by this algorithm with different parameters, than
+	// this Hasher is currently configured with.
+	NeedsRehash(encodedHash string) bool
+}
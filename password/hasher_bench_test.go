@@ -0,0 +1,77 @@
+package password
+
+import "testing"
+
+const benchPassword = "correct horse battery staple"
+
+func BenchmarkArgon2idHash(b *testing.B) {
+	h := NewArgon2idHasher(DefaultArgon2idParams())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.Hash(benchPassword); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkArgon2idVerify(b *testing.B) {
+	h := NewArgon2idHasher(DefaultArgon2idParams())
+	hash, err := h.Hash(benchPassword)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.Verify(benchPassword, hash); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBcryptHash(b *testing.B) {
+	h := NewBcryptHasher(10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.Hash(benchPassword); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBcryptVerify(b *testing.B) {
+	h := NewBcryptHasher(10)
+	hash, err := h.Hash(benchPassword)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.Verify(benchPassword, hash); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScryptHash(b *testing.B) {
+	h := NewScryptHasher(DefaultScryptParams())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.Hash(benchPassword); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkScryptVerify(b *testing.B) {
+	h := NewScryptHasher(DefaultScryptParams())
+	hash, err := h.Hash(benchPassword)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.Verify(benchPassword, hash); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
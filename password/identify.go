@@ -0,0 +1,23 @@
+package password
+
+import "strings"
+
+// identify returns the algorithm id of an encoded hash (one of argon2idID,
+// scryptID, bcryptID), or "" if the format isn't recognized.
+func identify(encodedHash string) string {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	switch parts[1] {
+	case argon2idID:
+		return argon2idID
+	case scryptID:
+		return scryptID
+	case "2a", "2b", "2y":
+		return bcryptID
+	default:
+		return ""
+	}
+}
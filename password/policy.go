@@ -0,0 +1,41 @@
+package password
+
+import "fmt"
+
+// Policy selects one Hasher to encode new passwords with, while dispatching
+// verification (and rehash checks) to whichever registered Hasher matches
+// an existing hash's algorithm. This is how a hash created under an older
+// algorithm or cost keeps verifying after the default changes.
+type Policy struct {
+	defaultAlgorithm string
+	hashers          map[string]Hasher
+}
+
+// NewPolicy builds a Policy that hashes new passwords with the Hasher
+// registered under defaultAlgorithm.
+func NewPolicy(defaultAlgorithm string, hashers map[string]Hasher) (*Policy, error) {
+	if _, ok := hashers[defaultAlgorithm]; !ok {
+		return nil, fmt.Errorf("password: no hasher registered for default algorithm %q", defaultAlgorithm)
+	}
+	return &Policy{defaultAlgorithm: defaultAlgorithm, hashers: hashers}, nil
+}
+
+func (p *Policy) Hash(pw string) (string, error) {
+	return p.hashers[p.defaultAlgorithm].Hash(pw)
+}
+
+func (p *Policy) Verify(pw, encodedHash string) (bool, error) {
+	alg := identify(encodedHash)
+	h, ok := p.hashers[alg]
+	if !ok {
+		return false, fmt.Errorf("password: unsupported or unrecognized hash format")
+	}
+	return h.Verify(pw, encodedHash)
+}
+
+// NeedsRehash reports whether encodedHash should be replaced with a fresh
+// hash from the current default Hasher - either because it was produced by
+// a different algorithm, or the same algorithm with different parameters.
+func (p *Policy) NeedsRehash(encodedHash string) bool {
+	return p.hashers[p.defaultAlgorithm].NeedsRehash(encodedHash)
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"EchoAuth/database"
+	"EchoAuth/models"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ActionTokenRepository stores the single-use tokens behind password-reset,
+// email-verification, and invitation links in one table, keyed by purpose,
+// so TTL and revocation-on-use are implemented once instead of per flow.
+// Only a SHA-256 hash of each token is ever persisted.
+type ActionTokenRepository interface {
+	Create(token *models.ActionToken) error
+	FindByToken(token string, purpose models.TokenPurpose) (*models.ActionToken, error)
+	// Consume atomically marks token redeemed and returns the record as it
+	// was just before redemption, or ErrNotFound if it doesn't exist, is
+	// already consumed, or never existed - so two concurrent redemptions of
+	// the same token can never both succeed.
+	Consume(token string, purpose models.TokenPurpose) (*models.ActionToken, error)
+	DeleteByUserAndPurpose(userID uint, purpose models.TokenPurpose) error
+	Delete(token string) error
+	// CleanupExpired deletes every token (of any purpose) past its
+	// ExpiresAt, whether or not it was ever consumed, so the table doesn't
+	// grow unbounded with abandoned reset/verify/invite links.
+	CleanupExpired() error
+}
+
+type actionTokenRepository struct {
+	db *database.DB
+}
+
+func NewActionTokenRepository(db *database.DB) ActionTokenRepository {
+	return &actionTokenRepository{db: db}
+}
+
+func hashActionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create replaces any existing token of the same purpose for the user
+// before inserting, so a user can only ever have one outstanding token per
+// purpose (requesting a new reset link invalidates the previous one).
+func (r *actionTokenRepository) Create(token *models.ActionToken) error {
+	return r.db.WithTx(context.Background(), func(tx *sql.Tx) error {
+		deleteQuery := fmt.Sprintf(`DELETE FROM tokens WHERE user_id = %s AND purpose = %s`,
+			r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2))
+		if _, err := tx.Exec(deleteQuery, token.UserID, token.Purpose); err != nil {
+			return err
+		}
+
+		var payload []byte
+		if len(token.Payload) > 0 {
+			var err error
+			payload, err = json.Marshal(token.Payload)
+			if err != nil {
+				return err
+			}
+		}
+
+		token.CreatedAt = time.Now()
+		query := fmt.Sprintf(`
+			INSERT INTO tokens (token_hash, user_id, purpose, expires_at, created_at, payload)
+			VALUES (%s)
+			RETURNING id`, placeholders(r.db, 6))
+
+		return tx.QueryRow(query, hashActionToken(token.Token), token.UserID, token.Purpose,
+			token.ExpiresAt, token.CreatedAt, payload).Scan(&token.ID)
+	})
+}
+
+func (r *actionTokenRepository) FindByToken(token string, purpose models.TokenPurpose) (*models.ActionToken, error) {
+	actionToken := &models.ActionToken{}
+	var payload sql.RawBytes
+	query := fmt.Sprintf(`
+		SELECT id, user_id, purpose, expires_at, created_at, consumed_at, payload
+		FROM tokens
+		WHERE token_hash = %s AND purpose = %s AND consumed_at IS NULL`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2))
+
+	err := r.db.QueryRow(query, hashActionToken(token), purpose).Scan(
+		&actionToken.ID, &actionToken.UserID, &actionToken.Purpose,
+		&actionToken.ExpiresAt, &actionToken.CreatedAt, &actionToken.ConsumedAt, &payload)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalActionTokenPayload(payload, actionToken); err != nil {
+		return nil, err
+	}
+	return actionToken, nil
+}
+
+func (r *actionTokenRepository) Consume(token string, purpose models.TokenPurpose) (*models.ActionToken, error) {
+	actionToken := &models.ActionToken{}
+	var payload sql.RawBytes
+	query := fmt.Sprintf(`
+		UPDATE tokens
+		SET consumed_at = %s
+		WHERE token_hash = %s AND purpose = %s AND consumed_at IS NULL
+		RETURNING id, user_id, purpose, expires_at, created_at, consumed_at, payload`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2), r.db.Dialect.Placeholder(3))
+
+	err := r.db.QueryRow(query, time.Now(), hashActionToken(token), purpose).Scan(
+		&actionToken.ID, &actionToken.UserID, &actionToken.Purpose,
+		&actionToken.ExpiresAt, &actionToken.CreatedAt, &actionToken.ConsumedAt, &payload)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalActionTokenPayload(payload, actionToken); err != nil {
+		return nil, err
+	}
+	return actionToken, nil
+}
+
+func unmarshalActionTokenPayload(raw sql.RawBytes, token *models.ActionToken) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, &token.Payload)
+}
+
+// DeleteByUserAndPurpose revokes any outstanding token of the given purpose
+// for a user, e.g. after they change their password through some other path.
+func (r *actionTokenRepository) DeleteByUserAndPurpose(userID uint, purpose models.TokenPurpose) error {
+	query := fmt.Sprintf(`DELETE FROM tokens WHERE user_id = %s AND purpose = %s`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2))
+	_, err := r.db.Exec(query, userID, purpose)
+	return err
+}
+
+// Delete removes a single token by value, so it can't be redeemed even if it
+// was never consumed.
+func (r *actionTokenRepository) Delete(token string) error {
+	query := fmt.Sprintf(`DELETE FROM tokens WHERE token_hash = %s`, r.db.Dialect.Placeholder(1))
+	_, err := r.db.Exec(query, hashActionToken(token))
+	return err
+}
+
+// CleanupExpired deletes every expired token regardless of purpose or
+// whether it was ever consumed.
+func (r *actionTokenRepository) CleanupExpired() error {
+	query := fmt.Sprintf(`DELETE FROM tokens WHERE expires_at < %s`, r.db.Dialect.Placeholder(1))
+	_, err := r.db.Exec(query, time.Now())
+	return err
+}
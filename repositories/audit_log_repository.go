@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"EchoAuth/database"
+	"EchoAuth/models"
+	"database/sql"
+)
+
+// AuditLogRepository persists the durable audit trail behind GET
+// /me/audit, distinct from audit.Logger's pluggable, fire-and-forget event
+// sinks (stdout/file/redis) - this is the one sink a user can query their
+// own history back out of.
+type AuditLogRepository interface {
+	Create(log *models.AuditLog) error
+	// FindByUserID returns userID's most recent audit log entries, newest
+	// first, bounded to limit rows.
+	FindByUserID(userID uint, limit int) ([]*models.AuditLog, error)
+}
+
+type auditLogRepository struct {
+	db *database.DB
+}
+
+func NewAuditLogRepository(db *database.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) Create(log *models.AuditLog) error {
+	var metadata sql.NullString
+	if log.Metadata != "" {
+		metadata = sql.NullString{String: log.Metadata, Valid: true}
+	}
+
+	query := `
+		INSERT INTO audit_logs (event_type, user_id, email, ip, user_agent, success, metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(query, log.EventType, log.UserID, log.Email, log.IP, log.UserAgent, log.Success, metadata).
+		Scan(&log.ID, &log.CreatedAt)
+}
+
+func (r *auditLogRepository) FindByUserID(userID uint, limit int) ([]*models.AuditLog, error) {
+	query := `
+		SELECT id, event_type, user_id, email, ip, user_agent, success, metadata, created_at
+		FROM audit_logs
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []*models.AuditLog
+	for rows.Next() {
+		log := &models.AuditLog{}
+		var userID sql.NullInt64
+		var email, ip, userAgent, metadata sql.NullString
+
+		if err := rows.Scan(&log.ID, &log.EventType, &userID, &email, &ip, &userAgent, &log.Success, &metadata, &log.CreatedAt); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			uid := uint(userID.Int64)
+			log.UserID = &uid
+		}
+		log.Email = email.String
+		log.IP = ip.String
+		log.UserAgent = userAgent.String
+		log.Metadata = metadata.String
+		logs = append(logs, log)
+	}
+	return logs, rows.Err()
+}
@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"EchoAuth/database"
+	"EchoAuth/keys"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"time"
+)
+
+// KeySetRepository persists the active and retired signing keys, encrypting
+// the private key material at rest with AES-GCM. Keys are marshaled with
+// PKCS8 rather than the RSA-only PKCS1, since a key's algorithm (RS256 or
+// ES256) isn't known to this package ahead of decoding it.
+type KeySetRepository struct {
+	db         *database.DB
+	encryptKey []byte
+}
+
+// NewKeySetRepository builds a KeySetRepository. encryptKey must be 32 bytes
+// (AES-256); callers typically derive it from config.KeyEncryptionKey.
+func NewKeySetRepository(db *database.DB, encryptKey []byte) *KeySetRepository {
+	return &KeySetRepository{db: db, encryptKey: encryptKey}
+}
+
+// Load returns the persisted key set, or (nil, nil) if none has been saved
+// yet so the caller can generate an initial one.
+func (r *KeySetRepository) Load() (*keys.PrivateKeySet, error) {
+	rows, err := r.db.Query(`
+		SELECT kid, algorithm, private_key, retired, not_before, expires_at
+		FROM signing_keys
+		ORDER BY not_before DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	set := &keys.PrivateKeySet{}
+	found := false
+	for rows.Next() {
+		var (
+			kid        string
+			algorithm  string
+			ciphertext []byte
+			retired    bool
+			notBefore  time.Time
+			expiresAt  sql.NullTime
+		)
+		if err := rows.Scan(&kid, &algorithm, &ciphertext, &retired, &notBefore, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key row: %w", err)
+		}
+
+		privateKey, err := r.decrypt(ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt signing key %s: %w", kid, err)
+		}
+
+		key := &keys.PrivateKey{Kid: kid, Alg: algorithm, Key: privateKey, NotBefore: notBefore}
+		if expiresAt.Valid {
+			key.ExpiresAt = expiresAt.Time
+		}
+
+		found = true
+		if retired {
+			set.Retired = append(set.Retired, key)
+		} else {
+			set.Active = key
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	return set, nil
+}
+
+// Save replaces the persisted key set with set, deleting keys that are no
+// longer present (i.e. have fully aged out of the JWKS).
+func (r *KeySetRepository) Save(set *keys.PrivateKeySet) error {
+	return r.db.WithTx(context.Background(), func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM signing_keys`); err != nil {
+			return fmt.Errorf("failed to clear signing keys: %w", err)
+		}
+
+		insertQuery := fmt.Sprintf(`
+			INSERT INTO signing_keys (kid, algorithm, private_key, retired, not_before, expires_at)
+			VALUES (%s)`, placeholders(r.db, 6))
+
+		insert := func(key *keys.PrivateKey, retired bool) error {
+			ciphertext, err := r.encrypt(key.Key)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt signing key %s: %w", key.Kid, err)
+			}
+			var expiresAt interface{}
+			if !key.ExpiresAt.IsZero() {
+				expiresAt = key.ExpiresAt
+			}
+			_, err = tx.Exec(insertQuery,
+				key.Kid, key.Alg, ciphertext, retired, key.NotBefore, expiresAt)
+			return err
+		}
+
+		if set.Active != nil {
+			if err := insert(set.Active, false); err != nil {
+				return err
+			}
+		}
+		for _, k := range set.Retired {
+			if err := insert(k, true); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *KeySetRepository) encrypt(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	block, err := aes.NewCipher(r.encryptKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (r *KeySetRepository) decrypt(ciphertext []byte) (crypto.Signer, error) {
+	block, err := aes.NewCipher(r.encryptKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, body := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	block2, _ := pem.Decode(plaintext)
+	if block2 == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block2.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("decoded private key does not support signing")
+	}
+	return signer, nil
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"EchoAuth/database"
+)
+
+// PasswordHistoryRepository stores the last N bcrypt password hashes for a
+// user so ValidatePasswordAgainstHistory can reject reuse.
+type PasswordHistoryRepository interface {
+	Add(userID uint, passwordHash string) error
+	Recent(userID uint, limit int) ([]string, error)
+}
+
+type passwordHistoryRepository struct {
+	db *database.DB
+}
+
+func NewPasswordHistoryRepository(db *database.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+// Add records a new password hash in the user's history.
+func (r *passwordHistoryRepository) Add(userID uint, passwordHash string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO password_history (user_id, password_hash)
+		VALUES ($1, $2)`,
+		userID, passwordHash)
+	return err
+}
+
+// Recent returns up to limit of the user's most recent password hashes,
+// most recent first.
+func (r *passwordHistoryRepository) Recent(userID uint, limit int) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT password_hash
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`,
+		userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
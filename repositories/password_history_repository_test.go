@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPasswordHistoryTest() (PasswordHistoryRepository, func()) {
+	testDB.Exec("DELETE FROM password_history")
+
+	repo := NewPasswordHistoryRepository(rawDB())
+
+	return repo, func() {
+		testDB.Exec("DELETE FROM password_history")
+	}
+}
+
+func TestPasswordHistoryAddAndRecent(t *testing.T) {
+	repo, cleanup := setupPasswordHistoryTest()
+	defer cleanup()
+
+	userID := uint(1)
+	assert.NoError(t, repo.Add(userID, "hash-1"))
+	assert.NoError(t, repo.Add(userID, "hash-2"))
+	assert.NoError(t, repo.Add(userID, "hash-3"))
+
+	recent, err := repo.Recent(userID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hash-3", "hash-2"}, recent)
+}
+
+func TestPasswordHistoryRecentEmpty(t *testing.T) {
+	repo, cleanup := setupPasswordHistoryTest()
+	defer cleanup()
+
+	recent, err := repo.Recent(uint(999), 5)
+	assert.NoError(t, err)
+	assert.Empty(t, recent)
+}
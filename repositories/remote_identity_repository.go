@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"EchoAuth/database"
+	"EchoAuth/models"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+)
+
+type RemoteIdentityRepository interface {
+	Create(identity *models.RemoteIdentity) error
+	FindByProviderAndSubject(provider, subjectID string) (*models.RemoteIdentity, error)
+	Delete(userID uint, provider string) error
+	// UpdateUpstreamRefreshToken encrypts refreshToken and persists it for
+	// the identity matching userID/provider, for a connector that requested
+	// offline access and needs to silently refresh the upstream session
+	// later. An empty refreshToken clears any previously stored one.
+	UpdateUpstreamRefreshToken(userID uint, provider, refreshToken string) error
+	// DecryptUpstreamRefreshToken returns the plaintext upstream refresh
+	// token stored for identity, or "" if none was stored.
+	DecryptUpstreamRefreshToken(identity *models.RemoteIdentity) (string, error)
+}
+
+// remoteIdentityRepository encrypts the upstream refresh token at rest with
+// AES-GCM, the same scheme KeySetRepository uses for signing keys, since
+// both are long-lived secrets a database dump shouldn't hand an attacker in
+// plaintext.
+type remoteIdentityRepository struct {
+	db         *database.DB
+	encryptKey []byte
+}
+
+// NewRemoteIdentityRepository builds a RemoteIdentityRepository. encryptKey
+// must be 32 bytes (AES-256); callers typically derive it from
+// config.KeyEncryptionKey, the same key KeySetRepository uses.
+func NewRemoteIdentityRepository(db *database.DB, encryptKey []byte) RemoteIdentityRepository {
+	return &remoteIdentityRepository{
+		db:         db,
+		encryptKey: encryptKey,
+	}
+}
+
+func (r *remoteIdentityRepository) Create(identity *models.RemoteIdentity) error {
+	now := time.Now()
+	identity.CreatedAt = now
+	identity.UpdatedAt = now
+
+	query := `
+		INSERT INTO remote_identities (user_id, provider, subject_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	return r.db.QueryRow(query,
+		identity.UserID, identity.Provider, identity.SubjectID,
+		identity.CreatedAt, identity.UpdatedAt).Scan(&identity.ID)
+}
+
+func (r *remoteIdentityRepository) FindByProviderAndSubject(provider, subjectID string) (*models.RemoteIdentity, error) {
+	identity := &models.RemoteIdentity{}
+	var encryptedRefreshToken sql.RawBytes
+	query := `
+		SELECT id, user_id, provider, subject_id, created_at, updated_at, encrypted_upstream_refresh_token
+		FROM remote_identities
+		WHERE provider = $1 AND subject_id = $2`
+
+	err := r.db.QueryRow(query, provider, subjectID).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.SubjectID,
+		&identity.CreatedAt, &identity.UpdatedAt, &encryptedRefreshToken)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(encryptedRefreshToken) > 0 {
+		identity.EncryptedUpstreamRefreshToken = append([]byte(nil), encryptedRefreshToken...)
+	}
+	return identity, nil
+}
+
+func (r *remoteIdentityRepository) Delete(userID uint, provider string) error {
+	query := `DELETE FROM remote_identities WHERE user_id = $1 AND provider = $2`
+
+	result, err := r.db.Exec(query, userID, provider)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *remoteIdentityRepository) UpdateUpstreamRefreshToken(userID uint, provider, refreshToken string) error {
+	var ciphertext []byte
+	if refreshToken != "" {
+		var err error
+		ciphertext, err = r.encrypt(refreshToken)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt upstream refresh token: %w", err)
+		}
+	}
+
+	query := `
+		UPDATE remote_identities
+		SET encrypted_upstream_refresh_token = $1, updated_at = $2
+		WHERE user_id = $3 AND provider = $4`
+
+	result, err := r.db.Exec(query, ciphertext, time.Now(), userID, provider)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *remoteIdentityRepository) DecryptUpstreamRefreshToken(identity *models.RemoteIdentity) (string, error) {
+	if len(identity.EncryptedUpstreamRefreshToken) == 0 {
+		return "", nil
+	}
+	return r.decrypt(identity.EncryptedUpstreamRefreshToken)
+}
+
+func (r *remoteIdentityRepository) encrypt(plaintext string) ([]byte, error) {
+	block, err := aes.NewCipher(r.encryptKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(plaintext), nil), nil
+}
+
+func (r *remoteIdentityRepository) decrypt(ciphertext []byte) (string, error) {
+	block, err := aes.NewCipher(r.encryptKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	nonce, body := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, body, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"EchoAuth/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRemoteIdentityTest() (*remoteIdentityRepository, func()) {
+	testDB.Exec("DELETE FROM remote_identities")
+	testDB.Exec("DELETE FROM users")
+
+	repo := &remoteIdentityRepository{db: rawDB()}
+
+	return repo, func() {
+		testDB.Exec("DELETE FROM remote_identities")
+		testDB.Exec("DELETE FROM users")
+	}
+}
+
+func createTestUserForRemoteIdentity(t *testing.T) *models.User {
+	userRepo := &userRepository{db: rawDB()}
+	user := &models.User{
+		Email:    "remote-identity-test@example.com",
+		Password: "hashed_password",
+	}
+	err := userRepo.Create(user)
+	assert.NoError(t, err)
+	return user
+}
+
+func TestRemoteIdentityCreate(t *testing.T) {
+	repo, cleanup := setupRemoteIdentityTest()
+	defer cleanup()
+
+	user := createTestUserForRemoteIdentity(t)
+
+	identity := &models.RemoteIdentity{
+		UserID:    user.ID,
+		Provider:  "google",
+		SubjectID: "google-subject-1",
+	}
+
+	err := repo.Create(identity)
+	assert.NoError(t, err)
+	assert.NotZero(t, identity.ID)
+}
+
+func TestRemoteIdentityFindByProviderAndSubject(t *testing.T) {
+	repo, cleanup := setupRemoteIdentityTest()
+	defer cleanup()
+
+	user := createTestUserForRemoteIdentity(t)
+	identity := &models.RemoteIdentity{
+		UserID:    user.ID,
+		Provider:  "github",
+		SubjectID: "github-subject-1",
+	}
+	err := repo.Create(identity)
+	assert.NoError(t, err)
+
+	found, err := repo.FindByProviderAndSubject("github", "github-subject-1")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, found.UserID)
+
+	_, err = repo.FindByProviderAndSubject("github", "does-not-exist")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRemoteIdentityDelete(t *testing.T) {
+	repo, cleanup := setupRemoteIdentityTest()
+	defer cleanup()
+
+	user := createTestUserForRemoteIdentity(t)
+	identity := &models.RemoteIdentity{
+		UserID:    user.ID,
+		Provider:  "google",
+		SubjectID: "google-subject-2",
+	}
+	err := repo.Create(identity)
+	assert.NoError(t, err)
+
+	err = repo.Delete(user.ID, "google")
+	assert.NoError(t, err)
+
+	_, err = repo.FindByProviderAndSubject("google", "google-subject-2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = repo.Delete(user.ID, "google")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"EchoAuth/database"
+	"EchoAuth/models"
+	"database/sql"
+	"time"
+)
+
+// ServiceClientRepository looks up registered OAuth clients: resource
+// servers allowed to call the token introspection endpoint, and clients
+// registered for the /oauth/token grants, distinguished by which of
+// AllowedGrantTypes/AllowedScopes/RedirectURIs they have set.
+type ServiceClientRepository interface {
+	FindByClientID(clientID string) (*models.ServiceClient, error)
+	Create(client *models.ServiceClient) error
+}
+
+type serviceClientRepository struct {
+	db *database.DB
+}
+
+func NewServiceClientRepository(db *database.DB) ServiceClientRepository {
+	return &serviceClientRepository{db: db}
+}
+
+func (r *serviceClientRepository) FindByClientID(clientID string) (*models.ServiceClient, error) {
+	client := &models.ServiceClient{}
+	var redirectURIs, allowedScopes, allowedGrantTypes string
+	query := `
+		SELECT id, client_id, hashed_secret, name, redirect_uris, allowed_scopes, allowed_grant_types, created_at, revoked_at
+		FROM service_clients
+		WHERE client_id = $1`
+
+	err := r.db.QueryRow(query, clientID).Scan(
+		&client.ID, &client.ClientID, &client.HashedSecret, &client.Name,
+		&redirectURIs, &allowedScopes, &allowedGrantTypes,
+		&client.CreatedAt, &client.RevokedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	client.SetRedirectURIsFromColumn(redirectURIs)
+	client.SetAllowedScopesFromColumn(allowedScopes)
+	client.SetAllowedGrantTypesFromColumn(allowedGrantTypes)
+	return client, nil
+}
+
+func (r *serviceClientRepository) Create(client *models.ServiceClient) error {
+	client.CreatedAt = time.Now()
+	query := `
+		INSERT INTO service_clients (client_id, hashed_secret, name, redirect_uris, allowed_scopes, allowed_grant_types, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`
+
+	return r.db.QueryRow(query,
+		client.ClientID, client.HashedSecret, client.Name,
+		client.RedirectURIsColumn(), client.AllowedScopesColumn(), client.AllowedGrantTypesColumn(),
+		client.CreatedAt).Scan(&client.ID)
+}
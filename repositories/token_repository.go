@@ -3,71 +3,155 @@ package repositories
 import (
 	"EchoAuth/database"
 	"EchoAuth/models"
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// hashRefreshToken returns the SHA-256 hex digest of a refresh token, the
+// only form ever persisted - so a database dump never exposes a bearer
+// token valid for a replay, mirroring the ActionTokenRepository pattern.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 type TokenRepositoryInterface interface {
-	CreateRefreshToken(userID uint, token string, expiresAt time.Time, deviceInfo, ip string) (*models.RefreshToken, error)
+	CreateRefreshToken(userID uint, token string, expiresAt time.Time, deviceInfo, ip string, scopes []string) (*models.RefreshToken, error)
 	GetRefreshToken(token string) (*models.RefreshToken, error)
-	RotateRefreshToken(currentToken *models.RefreshToken, newToken string, expiresAt time.Time) (*models.RefreshToken, error)
+	RotateRefreshToken(currentToken *models.RefreshToken, newToken string, expiresAt time.Time, scopes []string) (*models.RefreshToken, error)
+	TouchRefreshToken(token string, now time.Time) error
 	RevokeRefreshToken(token string) error
 	RevokeAllUserTokens(userID uint) error
-	CleanupExpiredTokens() error
+	RevokeAllFamilyTokens(familyID string) error
+	GetChain(tokenID uuid.UUID) ([]*models.RefreshToken, error)
+	RevokeFamily(tokenID uuid.UUID) error
+	DetectReuse(token *models.RefreshToken) (bool, error)
+	CleanupExpiredTokens(idleTimeout time.Duration) error
+	ListActiveSessions(userID uint) ([]*models.Session, error)
+	RevokeSession(userID uint, sessionID uuid.UUID) error
+	RevokeAllExcept(userID uint, keepToken string) error
+	CountActiveFamilies() (int, error)
 }
 
+// defaultMaxSimultaneousSessions is used when no limit is configured via
+// SetMaxSimultaneousSessions.
+const defaultMaxSimultaneousSessions = 5
+
+// maxChainDepth bounds how many rotations GetChain will walk in each
+// direction, so a corrupted or adversarially long PreviousID chain can't
+// turn a lookup into an unbounded loop.
+const maxChainDepth = 50
+
 type TokenRepository struct {
-	db *database.DB
+	db                      *database.DB
+	maxSimultaneousSessions int
 }
 
 func NewTokenRepository(db *database.DB) *TokenRepository {
-	return &TokenRepository{db: db}
+	return &TokenRepository{db: db, maxSimultaneousSessions: defaultMaxSimultaneousSessions}
 }
 
-// CreateRefreshToken creates a new refresh token for a user
-func (r *TokenRepository) CreateRefreshToken(userID uint, token string, expiresAt time.Time, deviceInfo, ip string) (*models.RefreshToken, error) {
+// SetMaxSimultaneousSessions overrides the number of active refresh tokens a
+// single user may hold at once. When CreateRefreshToken would exceed it, the
+// oldest active sessions are revoked first.
+func (r *TokenRepository) SetMaxSimultaneousSessions(limit int) {
+	r.maxSimultaneousSessions = limit
+}
+
+// CreateRefreshToken creates a new refresh token for a user, persisting the
+// scopes it was granted so a later refresh can only downscope, never widen.
+// If the user already holds maxSimultaneousSessions active sessions, the
+// oldest ones are revoked first so the new session always fits within the
+// limit; eviction and insertion happen in a single transaction to avoid
+// racing a concurrent login past the limit.
+func (r *TokenRepository) CreateRefreshToken(userID uint, token string, expiresAt time.Time, deviceInfo, ip string, scopes []string) (*models.RefreshToken, error) {
+	now := time.Now()
 	refreshToken := &models.RefreshToken{
 		ID:         uuid.New(),
 		UserID:     userID,
 		Token:      token,
+		TokenHash:  hashRefreshToken(token),
 		ExpiresAt:  expiresAt,
 		DeviceInfo: deviceInfo,
 		IP:         ip,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		Scopes:     scopes,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		LastUsedAt: now,
+		FamilyID:   uuid.New(),
 	}
 
-	query := `
-		INSERT INTO refresh_tokens (id, user_id, token, expires_at, device_info, ip, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	err := r.db.WithTx(context.Background(), func(tx *sql.Tx) error {
+		if r.maxSimultaneousSessions > 0 {
+			if err := evictOldestSessions(tx, r.db, userID, r.maxSimultaneousSessions-1, now); err != nil {
+				return err
+			}
+		}
 
-	_, err := r.db.Exec(query,
-		refreshToken.ID, refreshToken.UserID, refreshToken.Token,
-		refreshToken.ExpiresAt, refreshToken.DeviceInfo, refreshToken.IP,
-		refreshToken.CreatedAt, refreshToken.UpdatedAt)
+		query := fmt.Sprintf(`
+			INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, device_info, ip, scopes, last_used_at, created_at, updated_at, family_id)
+			VALUES (%s)`, placeholders(r.db, 11))
 
+		_, err := tx.Exec(query,
+			refreshToken.ID, refreshToken.UserID, refreshToken.TokenHash,
+			refreshToken.ExpiresAt, refreshToken.DeviceInfo, refreshToken.IP,
+			refreshToken.ScopesColumn(), refreshToken.LastUsedAt, refreshToken.CreatedAt, refreshToken.UpdatedAt,
+			refreshToken.FamilyID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 	return refreshToken, nil
 }
 
-// GetRefreshToken retrieves a refresh token by its token string
+// evictOldestSessions revokes the oldest active (non-revoked, non-expired,
+// unused) refresh tokens for userID until at most keep remain, so that
+// inserting one more session brings the user back to the configured limit.
+func evictOldestSessions(tx *sql.Tx, db *database.DB, userID uint, keep int, now time.Time) error {
+	if keep < 0 {
+		keep = 0
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE refresh_tokens
+		SET revoked_at = %[1]s, updated_at = %[1]s
+		WHERE id IN (
+			SELECT id FROM refresh_tokens
+			WHERE user_id = %[2]s AND used = false AND revoked_at IS NULL AND expires_at > %[1]s
+			ORDER BY created_at DESC
+			OFFSET %[3]s
+		)`, db.Dialect.Placeholder(1), db.Dialect.Placeholder(2), db.Dialect.Placeholder(3))
+
+	_, err := tx.Exec(query, now, userID, keep)
+	return err
+}
+
+// GetRefreshToken retrieves a refresh token by its token string, looking it
+// up by the SHA-256 hash persisted for it. The returned row's Token field is
+// left blank since only the hash is stored - callers that already have the
+// plaintext (every caller of this method does) don't need it echoed back.
 func (r *TokenRepository) GetRefreshToken(token string) (*models.RefreshToken, error) {
 	refreshToken := &models.RefreshToken{}
-	query := `
-		SELECT id, user_id, token, used, revoked_at, expires_at, created_at, updated_at,
-			previous_id, device_info, ip
+	var scopesColumn string
+	query := fmt.Sprintf(`
+		SELECT id, user_id, token_hash, used, used_at, revoked_at, expires_at, created_at, updated_at,
+			previous_id, device_info, ip, scopes, last_used_at, family_id
 		FROM refresh_tokens
-		WHERE token = $1`
+		WHERE token_hash = %s`, r.db.Dialect.Placeholder(1))
 
-	err := r.db.QueryRow(query, token).Scan(
-		&refreshToken.ID, &refreshToken.UserID, &refreshToken.Token,
-		&refreshToken.Used, &refreshToken.RevokedAt, &refreshToken.ExpiresAt,
+	err := r.db.QueryRow(query, hashRefreshToken(token)).Scan(
+		&refreshToken.ID, &refreshToken.UserID, &refreshToken.TokenHash,
+		&refreshToken.Used, &refreshToken.UsedAt, &refreshToken.RevokedAt, &refreshToken.ExpiresAt,
 		&refreshToken.CreatedAt, &refreshToken.UpdatedAt,
-		&refreshToken.PreviousID, &refreshToken.DeviceInfo, &refreshToken.IP)
+		&refreshToken.PreviousID, &refreshToken.DeviceInfo, &refreshToken.IP, &scopesColumn,
+		&refreshToken.LastUsedAt, &refreshToken.FamilyID)
 
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
@@ -75,73 +159,101 @@ func (r *TokenRepository) GetRefreshToken(token string) (*models.RefreshToken, e
 	if err != nil {
 		return nil, err
 	}
+	refreshToken.SetScopesFromColumn(scopesColumn)
 	return refreshToken, nil
 }
 
-// RotateRefreshToken marks the current token as used and creates a new one
-func (r *TokenRepository) RotateRefreshToken(currentToken *models.RefreshToken, newToken string, expiresAt time.Time) (*models.RefreshToken, error) {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback()
-
-	// Mark current token as used
-	updateQuery := `
-		UPDATE refresh_tokens
-		SET used = true, updated_at = $1
-		WHERE id = $2`
-
+// RotateRefreshToken marks the current token as used and creates a new one.
+// scopes is the set of scopes the new token should carry; callers must
+// ensure it is a subset of currentToken.Scopes before calling this.
+func (r *TokenRepository) RotateRefreshToken(currentToken *models.RefreshToken, newToken string, expiresAt time.Time, scopes []string) (*models.RefreshToken, error) {
 	now := time.Now()
-	_, err = tx.Exec(updateQuery, now, currentToken.ID)
-	if err != nil {
-		return nil, err
-	}
 
-	// Create new token with reference to the previous one
+	// Create new token with reference to the previous one, inheriting its
+	// family so the whole rotation chain can be revoked together if reuse
+	// of an earlier token in the family is ever detected.
 	newRefreshToken := &models.RefreshToken{
 		ID:         uuid.New(),
 		UserID:     currentToken.UserID,
 		Token:      newToken,
+		TokenHash:  hashRefreshToken(newToken),
 		ExpiresAt:  expiresAt,
 		PreviousID: &currentToken.ID,
 		DeviceInfo: currentToken.DeviceInfo,
 		IP:         currentToken.IP,
+		Scopes:     scopes,
 		CreatedAt:  now,
 		UpdatedAt:  now,
+		LastUsedAt: now,
+		FamilyID:   currentToken.FamilyID,
 	}
 
-	insertQuery := `
-		INSERT INTO refresh_tokens (id, user_id, token, expires_at, previous_id, device_info, ip, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	err := r.db.WithTx(context.Background(), func(tx *sql.Tx) error {
+		// Mark current token as used
+		updateQuery := fmt.Sprintf(`
+			UPDATE refresh_tokens
+			SET used = true, used_at = %[1]s, updated_at = %[1]s
+			WHERE id = %[2]s`, r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2))
+
+		if _, err := tx.Exec(updateQuery, now, currentToken.ID); err != nil {
+			return err
+		}
 
-	_, err = tx.Exec(insertQuery,
-		newRefreshToken.ID, newRefreshToken.UserID, newRefreshToken.Token,
-		newRefreshToken.ExpiresAt, newRefreshToken.PreviousID,
-		newRefreshToken.DeviceInfo, newRefreshToken.IP,
-		newRefreshToken.CreatedAt, newRefreshToken.UpdatedAt)
+		insertQuery := fmt.Sprintf(`
+			INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, previous_id, device_info, ip, scopes, last_used_at, created_at, updated_at, family_id)
+			VALUES (%s)`, placeholders(r.db, 12))
 
+		_, err := tx.Exec(insertQuery,
+			newRefreshToken.ID, newRefreshToken.UserID, newRefreshToken.TokenHash,
+			newRefreshToken.ExpiresAt, newRefreshToken.PreviousID,
+			newRefreshToken.DeviceInfo, newRefreshToken.IP, newRefreshToken.ScopesColumn(),
+			newRefreshToken.LastUsedAt, newRefreshToken.CreatedAt, newRefreshToken.UpdatedAt,
+			newRefreshToken.FamilyID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	err = tx.Commit()
+	return newRefreshToken, nil
+}
+
+// TouchRefreshToken records that token was just used, resetting the idle
+// timeout clock AuthService checks on its next refresh. It only touches
+// still-active tokens: a used or revoked token's last_used_at is left alone
+// since it can no longer be refreshed anyway.
+func (r *TokenRepository) TouchRefreshToken(token string, now time.Time) error {
+	query := fmt.Sprintf(`
+		UPDATE refresh_tokens
+		SET last_used_at = %[1]s, updated_at = %[1]s
+		WHERE token_hash = %[2]s AND used = false AND revoked_at IS NULL`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2))
+
+	result, err := r.db.Exec(query, now, hashRefreshToken(token))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return newRefreshToken, nil
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
 // RevokeRefreshToken marks a refresh token as revoked
 func (r *TokenRepository) RevokeRefreshToken(token string) error {
 	now := time.Now()
-	query := `
+	query := fmt.Sprintf(`
 		UPDATE refresh_tokens
-		SET revoked_at = $1, updated_at = $2
-		WHERE token = $3`
+		SET revoked_at = %s, updated_at = %s
+		WHERE token_hash = %s`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2), r.db.Dialect.Placeholder(3))
 
-	result, err := r.db.Exec(query, now, now, token)
+	result, err := r.db.Exec(query, now, now, hashRefreshToken(token))
 	if err != nil {
 		return err
 	}
@@ -161,21 +273,261 @@ func (r *TokenRepository) RevokeRefreshToken(token string) error {
 // RevokeAllUserTokens revokes all refresh tokens for a user
 func (r *TokenRepository) RevokeAllUserTokens(userID uint) error {
 	now := time.Now()
-	query := `
+	query := fmt.Sprintf(`
 		UPDATE refresh_tokens
-		SET revoked_at = $1, updated_at = $2
-		WHERE user_id = $3 AND revoked_at IS NULL`
+		SET revoked_at = %s, updated_at = %s
+		WHERE user_id = %s AND revoked_at IS NULL`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2), r.db.Dialect.Placeholder(3))
 
 	_, err := r.db.Exec(query, now, now, userID)
 	return err
 }
 
-// CleanupExpiredTokens removes expired and used tokens
-func (r *TokenRepository) CleanupExpiredTokens() error {
-	query := `
+// RevokeAllFamilyTokens revokes every refresh token descended from the same
+// original login as familyID. It's called when a token is presented after
+// already being used or revoked, since that's a strong signal the token was
+// stolen and the whole rotation chain must be treated as compromised.
+func (r *TokenRepository) RevokeAllFamilyTokens(familyID string) error {
+	now := time.Now()
+	query := fmt.Sprintf(`
+		UPDATE refresh_tokens
+		SET revoked_at = %s, updated_at = %s
+		WHERE family_id = %s AND revoked_at IS NULL`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2), r.db.Dialect.Placeholder(3))
+
+	_, err := r.db.Exec(query, now, now, familyID)
+	return err
+}
+
+// getByID fetches a refresh token by its primary key, for chain-walking.
+func (r *TokenRepository) getByID(id uuid.UUID) (*models.RefreshToken, error) {
+	refreshToken := &models.RefreshToken{}
+	var scopesColumn string
+	query := fmt.Sprintf(`
+		SELECT id, user_id, token_hash, used, used_at, revoked_at, expires_at, created_at, updated_at,
+			previous_id, device_info, ip, scopes, last_used_at, family_id
+		FROM refresh_tokens
+		WHERE id = %s`, r.db.Dialect.Placeholder(1))
+
+	err := r.db.QueryRow(query, id).Scan(
+		&refreshToken.ID, &refreshToken.UserID, &refreshToken.TokenHash,
+		&refreshToken.Used, &refreshToken.UsedAt, &refreshToken.RevokedAt, &refreshToken.ExpiresAt,
+		&refreshToken.CreatedAt, &refreshToken.UpdatedAt,
+		&refreshToken.PreviousID, &refreshToken.DeviceInfo, &refreshToken.IP, &scopesColumn,
+		&refreshToken.LastUsedAt, &refreshToken.FamilyID)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	refreshToken.SetScopesFromColumn(scopesColumn)
+	return refreshToken, nil
+}
+
+// findChildOf returns the refresh token that was rotated from id, if any.
+func (r *TokenRepository) findChildOf(id uuid.UUID) (*models.RefreshToken, error) {
+	refreshToken := &models.RefreshToken{}
+	var scopesColumn string
+	query := fmt.Sprintf(`
+		SELECT id, user_id, token_hash, used, used_at, revoked_at, expires_at, created_at, updated_at,
+			previous_id, device_info, ip, scopes, last_used_at, family_id
+		FROM refresh_tokens
+		WHERE previous_id = %s
+		ORDER BY created_at ASC
+		LIMIT 1`, r.db.Dialect.Placeholder(1))
+
+	err := r.db.QueryRow(query, id).Scan(
+		&refreshToken.ID, &refreshToken.UserID, &refreshToken.TokenHash,
+		&refreshToken.Used, &refreshToken.UsedAt, &refreshToken.RevokedAt, &refreshToken.ExpiresAt,
+		&refreshToken.CreatedAt, &refreshToken.UpdatedAt,
+		&refreshToken.PreviousID, &refreshToken.DeviceInfo, &refreshToken.IP, &scopesColumn,
+		&refreshToken.LastUsedAt, &refreshToken.FamilyID)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	refreshToken.SetScopesFromColumn(scopesColumn)
+	return refreshToken, nil
+}
+
+// GetChain returns every refresh token in tokenID's rotation family, ordered
+// oldest to newest, by walking PreviousID backward (ancestors) and forward
+// (descendants) from tokenID. The walk in each direction is capped at
+// maxChainDepth hops so a corrupted chain can't become an unbounded loop.
+func (r *TokenRepository) GetChain(tokenID uuid.UUID) ([]*models.RefreshToken, error) {
+	start, err := r.getByID(tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ancestors []*models.RefreshToken
+	current := start
+	for i := 0; i < maxChainDepth && current.PreviousID != nil; i++ {
+		prev, err := r.getByID(*current.PreviousID)
+		if err != nil {
+			break
+		}
+		ancestors = append(ancestors, prev)
+		current = prev
+	}
+
+	var descendants []*models.RefreshToken
+	current = start
+	for i := 0; i < maxChainDepth; i++ {
+		next, err := r.findChildOf(current.ID)
+		if err != nil {
+			break
+		}
+		descendants = append(descendants, next)
+		current = next
+	}
+
+	chain := make([]*models.RefreshToken, 0, len(ancestors)+1+len(descendants))
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		chain = append(chain, ancestors[i])
+	}
+	chain = append(chain, start)
+	chain = append(chain, descendants...)
+	return chain, nil
+}
+
+// RevokeFamily revokes every refresh token in tokenID's rotation family. It
+// delegates to the family_id column (populated at creation and carried
+// through each rotation) rather than re-walking the PreviousID chain, since
+// family_id already identifies the same set in a single indexed query.
+func (r *TokenRepository) RevokeFamily(tokenID uuid.UUID) error {
+	token, err := r.getByID(tokenID)
+	if err != nil {
+		return err
+	}
+	return r.RevokeAllFamilyTokens(token.FamilyID.String())
+}
+
+// DetectReuse reports whether token has already been used or revoked and,
+// if so, revokes every token in its family (see RevokeFamily) before
+// returning true - the OAuth 2.0 Security BCP response to a refresh token
+// being replayed. token is the already-resolved row (as RotateRefreshToken
+// takes its currentToken) rather than a bare token string, so callers that
+// already hold it via GetRefreshToken don't pay for a second lookup.
+func (r *TokenRepository) DetectReuse(token *models.RefreshToken) (bool, error) {
+	if !token.Used && token.RevokedAt == nil {
+		return false, nil
+	}
+	if err := r.RevokeAllFamilyTokens(token.FamilyID.String()); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// CleanupExpiredTokens removes expired and used tokens, as well as tokens
+// that have sat unused longer than idleTimeout (0 disables idle pruning, so
+// only absolute expiry and used tokens are cleaned up).
+func (r *TokenRepository) CleanupExpiredTokens(idleTimeout time.Duration) error {
+	now := time.Now()
+
+	if idleTimeout <= 0 {
+		query := fmt.Sprintf(`
+			DELETE FROM refresh_tokens
+			WHERE expires_at < %s OR used = true`, r.db.Dialect.Placeholder(1))
+
+		_, err := r.db.Exec(query, now)
+		return err
+	}
+
+	query := fmt.Sprintf(`
 		DELETE FROM refresh_tokens
-		WHERE expires_at < $1 OR used = true`
+		WHERE expires_at < %s OR used = true OR last_used_at < %s`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2))
+
+	_, err := r.db.Exec(query, now, now.Add(-idleTimeout))
+	return err
+}
+
+// ListActiveSessions returns the user's active (non-revoked, non-expired,
+// unused) refresh tokens as sessions, most recently used first, for a
+// "logged-in devices" UI.
+func (r *TokenRepository) ListActiveSessions(userID uint) ([]*models.Session, error) {
+	query := fmt.Sprintf(`
+		SELECT id, device_info, ip, created_at, last_used_at
+		FROM refresh_tokens
+		WHERE user_id = %s AND used = false AND revoked_at IS NULL AND expires_at > %s
+		ORDER BY last_used_at DESC`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2))
+
+	rows, err := r.db.Query(query, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session := &models.Session{}
+		if err := rows.Scan(&session.ID, &session.DeviceInfo, &session.IP, &session.CreatedAt, &session.LastUsedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession revokes a single session belonging to userID. It returns
+// ErrNotFound if no active session with that ID belongs to the user, so a
+// caller can't use it to probe or revoke another user's sessions.
+func (r *TokenRepository) RevokeSession(userID uint, sessionID uuid.UUID) error {
+	now := time.Now()
+	query := fmt.Sprintf(`
+		UPDATE refresh_tokens
+		SET revoked_at = %[1]s, updated_at = %[1]s
+		WHERE id = %[2]s AND user_id = %[3]s AND revoked_at IS NULL`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2), r.db.Dialect.Placeholder(3))
+
+	result, err := r.db.Exec(query, now, sessionID, userID)
+	if err != nil {
+		return err
+	}
 
-	_, err := r.db.Exec(query, time.Now())
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RevokeAllExcept revokes every active session for userID except the one
+// backed by keepToken, so a user who suspects compromise can log out every
+// other device while staying logged in on the one they're using now.
+func (r *TokenRepository) RevokeAllExcept(userID uint, keepToken string) error {
+	now := time.Now()
+	query := fmt.Sprintf(`
+		UPDATE refresh_tokens
+		SET revoked_at = %[1]s, updated_at = %[1]s
+		WHERE user_id = %[2]s AND token_hash != %[3]s AND revoked_at IS NULL`,
+		r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2), r.db.Dialect.Placeholder(3))
+
+	_, err := r.db.Exec(query, now, userID, hashRefreshToken(keepToken))
 	return err
 }
+
+// CountActiveFamilies returns the number of distinct refresh-token families
+// with at least one still-active (non-revoked, non-expired, unused) token,
+// i.e. the number of logged-in sessions across every user, for
+// metrics.RecordActiveTokens.
+func (r *TokenRepository) CountActiveFamilies() (int, error) {
+	var count int
+	query := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT family_id)
+		FROM refresh_tokens
+		WHERE used = false AND revoked_at IS NULL AND expires_at > %s`, r.db.Dialect.Placeholder(1))
+
+	err := r.db.QueryRow(query, time.Now()).Scan(&count)
+	return count, err
+}
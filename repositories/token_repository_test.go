@@ -13,7 +13,7 @@ func setupTokenTest() (*TokenRepository, func()) {
 	// Clear the database before each test
 	testDB.Exec("DELETE FROM refresh_tokens")
 
-	repo := NewTokenRepository(testDB)
+	repo := NewTokenRepository(rawDB())
 
 	return repo, func() {
 		testDB.Exec("DELETE FROM refresh_tokens")
@@ -30,7 +30,7 @@ func TestCreateRefreshToken(t *testing.T) {
 	ip := "127.0.0.1"
 	expiresAt := time.Now().Add(24 * time.Hour)
 
-	refreshToken, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip)
+	refreshToken, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip, []string{"read", "write"})
 	assert.NoError(t, err)
 	assert.NotNil(t, refreshToken)
 	assert.Equal(t, token, refreshToken.Token)
@@ -39,6 +39,7 @@ func TestCreateRefreshToken(t *testing.T) {
 	assert.Equal(t, ip, refreshToken.IP)
 	assert.False(t, refreshToken.Used)
 	assert.Nil(t, refreshToken.RevokedAt)
+	assert.Equal(t, []string{"read", "write"}, refreshToken.Scopes)
 }
 
 func TestGetRefreshToken(t *testing.T) {
@@ -52,7 +53,7 @@ func TestGetRefreshToken(t *testing.T) {
 	ip := "127.0.0.1"
 	expiresAt := time.Now().Add(24 * time.Hour)
 
-	created, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip)
+	created, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip, []string{"read", "write"})
 	assert.NoError(t, err)
 
 	// Test getting the token
@@ -61,6 +62,7 @@ func TestGetRefreshToken(t *testing.T) {
 	assert.NotNil(t, found)
 	assert.Equal(t, created.ID, found.ID)
 	assert.Equal(t, token, found.Token)
+	assert.Equal(t, []string{"read", "write"}, found.Scopes)
 
 	// Test getting non-existent token
 	found, err = repo.GetRefreshToken("non-existent")
@@ -80,14 +82,14 @@ func TestRotateRefreshToken(t *testing.T) {
 	ip := "127.0.0.1"
 	expiresAt := time.Now().Add(24 * time.Hour)
 
-	currentToken, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip)
+	currentToken, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip, []string{"read", "write"})
 	assert.NoError(t, err)
 
 	// Rotate token
 	newToken := "new-test-token"
 	newExpiresAt := time.Now().Add(48 * time.Hour)
 
-	rotated, err := repo.RotateRefreshToken(currentToken, newToken, newExpiresAt)
+	rotated, err := repo.RotateRefreshToken(currentToken, newToken, newExpiresAt, []string{"read"})
 	assert.NoError(t, err)
 	assert.NotNil(t, rotated)
 	assert.Equal(t, newToken, rotated.Token)
@@ -95,11 +97,56 @@ func TestRotateRefreshToken(t *testing.T) {
 	assert.Equal(t, currentToken.DeviceInfo, rotated.DeviceInfo)
 	assert.Equal(t, currentToken.IP, rotated.IP)
 	assert.Equal(t, currentToken.ID, *rotated.PreviousID)
+	assert.Equal(t, []string{"read"}, rotated.Scopes)
 
 	// Verify old token is marked as used
 	oldToken, err := repo.GetRefreshToken(token)
 	assert.NoError(t, err)
 	assert.True(t, oldToken.Used)
+	assert.NotNil(t, oldToken.UsedAt)
+}
+
+func TestTouchRefreshToken(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	token := "test-token"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	_, err := repo.CreateRefreshToken(1, token, expiresAt, "test-device", "127.0.0.1", []string{"read"})
+	assert.NoError(t, err)
+
+	touchedAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	err = repo.TouchRefreshToken(token, touchedAt)
+	assert.NoError(t, err)
+
+	found, err := repo.GetRefreshToken(token)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, touchedAt, found.LastUsedAt, time.Second)
+
+	err = repo.TouchRefreshToken("non-existent", time.Now())
+	assert.Equal(t, ErrNotFound, err)
+}
+
+// TestTouchRefreshTokenRejectsUsedToken documents the used = false guard
+// AuthService.RefreshToken relies on to skip TouchRefreshToken on a
+// grace-window retry: a token already marked used - exactly the case for a
+// retry - can never satisfy it.
+func TestTouchRefreshTokenRejectsUsedToken(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	token := "test-token"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	current, err := repo.CreateRefreshToken(1, token, expiresAt, "test-device", "127.0.0.1", []string{"read"})
+	assert.NoError(t, err)
+
+	_, err = repo.RotateRefreshToken(current, "new-test-token", expiresAt, []string{"read"})
+	assert.NoError(t, err)
+
+	err = repo.TouchRefreshToken(token, time.Now())
+	assert.Equal(t, ErrNotFound, err)
 }
 
 func TestRevokeRefreshToken(t *testing.T) {
@@ -113,7 +160,7 @@ func TestRevokeRefreshToken(t *testing.T) {
 	ip := "127.0.0.1"
 	expiresAt := time.Now().Add(24 * time.Hour)
 
-	_, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip)
+	_, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip, []string{"read", "write"})
 	assert.NoError(t, err)
 
 	// Revoke token
@@ -138,7 +185,7 @@ func TestRevokeAllUserTokens(t *testing.T) {
 	// Create multiple tokens for the same user
 	tokens := []string{"token1", "token2", "token3"}
 	for _, token := range tokens {
-		_, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip)
+		_, err := repo.CreateRefreshToken(userID, token, expiresAt, deviceInfo, ip, []string{"read", "write"})
 		assert.NoError(t, err)
 	}
 
@@ -154,6 +201,134 @@ func TestRevokeAllUserTokens(t *testing.T) {
 	}
 }
 
+func TestRevokeAllFamilyTokens(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	userID := uint(1)
+	deviceInfo := "test-device"
+	ip := "127.0.0.1"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	original, err := repo.CreateRefreshToken(userID, "family-original", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	rotated, err := repo.RotateRefreshToken(original, "family-rotated", expiresAt, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, original.FamilyID, rotated.FamilyID)
+
+	_, err = repo.CreateRefreshToken(userID, "family-unrelated", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	err = repo.RevokeAllFamilyTokens(original.FamilyID.String())
+	assert.NoError(t, err)
+
+	for _, token := range []string{"family-original", "family-rotated"} {
+		found, err := repo.GetRefreshToken(token)
+		assert.NoError(t, err)
+		assert.NotNil(t, found.RevokedAt)
+	}
+
+	unrelated, err := repo.GetRefreshToken("family-unrelated")
+	assert.NoError(t, err)
+	assert.Nil(t, unrelated.RevokedAt)
+}
+
+func TestGetChain(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	userID := uint(1)
+	deviceInfo := "test-device"
+	ip := "127.0.0.1"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	first, err := repo.CreateRefreshToken(userID, "chain-1", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+	second, err := repo.RotateRefreshToken(first, "chain-2", expiresAt, nil)
+	assert.NoError(t, err)
+	third, err := repo.RotateRefreshToken(second, "chain-3", expiresAt, nil)
+	assert.NoError(t, err)
+
+	_, err = repo.CreateRefreshToken(userID, "chain-unrelated", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	// Walking from the middle of the chain should find both the ancestor
+	// and the descendant, in order, but not the unrelated token.
+	chain, err := repo.GetChain(second.ID)
+	assert.NoError(t, err)
+	assert.Len(t, chain, 3)
+	assert.Equal(t, first.ID, chain[0].ID)
+	assert.Equal(t, second.ID, chain[1].ID)
+	assert.Equal(t, third.ID, chain[2].ID)
+}
+
+func TestRevokeFamily(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	userID := uint(1)
+	deviceInfo := "test-device"
+	ip := "127.0.0.1"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	first, err := repo.CreateRefreshToken(userID, "revoke-chain-1", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+	_, err = repo.RotateRefreshToken(first, "revoke-chain-2", expiresAt, nil)
+	assert.NoError(t, err)
+
+	_, err = repo.CreateRefreshToken(userID, "revoke-chain-unrelated", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	err = repo.RevokeFamily(first.ID)
+	assert.NoError(t, err)
+
+	for _, token := range []string{"revoke-chain-1", "revoke-chain-2"} {
+		found, err := repo.GetRefreshToken(token)
+		assert.NoError(t, err)
+		assert.NotNil(t, found.RevokedAt)
+	}
+
+	unrelated, err := repo.GetRefreshToken("revoke-chain-unrelated")
+	assert.NoError(t, err)
+	assert.Nil(t, unrelated.RevokedAt)
+}
+
+func TestDetectReuse(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	userID := uint(1)
+	deviceInfo := "test-device"
+	ip := "127.0.0.1"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	first, err := repo.CreateRefreshToken(userID, "reuse-chain-1", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	reused, err := repo.DetectReuse(first)
+	assert.NoError(t, err)
+	assert.False(t, reused, "a fresh, unused token is not a reuse")
+
+	_, err = repo.RotateRefreshToken(first, "reuse-chain-2", expiresAt, nil)
+	assert.NoError(t, err)
+
+	// first is now marked used; presenting it again is the replay case.
+	used, err := repo.GetRefreshToken("reuse-chain-1")
+	assert.NoError(t, err)
+
+	reused, err = repo.DetectReuse(used)
+	assert.NoError(t, err)
+	assert.True(t, reused, "a used token being presented again is a reuse")
+
+	// The whole family, including the rotated descendant, must be revoked.
+	for _, token := range []string{"reuse-chain-1", "reuse-chain-2"} {
+		found, err := repo.GetRefreshToken(token)
+		assert.NoError(t, err)
+		assert.NotNil(t, found.RevokedAt)
+	}
+}
+
 func TestCleanupExpiredTokens(t *testing.T) {
 	repo, cleanup := setupTokenTest()
 	defer cleanup()
@@ -165,24 +340,24 @@ func TestCleanupExpiredTokens(t *testing.T) {
 	// Create expired token
 	expiredToken := "expired-token"
 	expiredAt := time.Now().Add(-24 * time.Hour)
-	_, err := repo.CreateRefreshToken(userID, expiredToken, expiredAt, deviceInfo, ip)
+	_, err := repo.CreateRefreshToken(userID, expiredToken, expiredAt, deviceInfo, ip, nil)
 	assert.NoError(t, err)
 
 	// Create valid token
 	validToken := "valid-token"
 	validExpiresAt := time.Now().Add(24 * time.Hour)
-	_, err = repo.CreateRefreshToken(userID, validToken, validExpiresAt, deviceInfo, ip)
+	_, err = repo.CreateRefreshToken(userID, validToken, validExpiresAt, deviceInfo, ip, nil)
 	assert.NoError(t, err)
 
 	// Create used token
 	usedToken := "used-token"
-	_, err = repo.CreateRefreshToken(userID, usedToken, validExpiresAt, deviceInfo, ip)
+	_, err = repo.CreateRefreshToken(userID, usedToken, validExpiresAt, deviceInfo, ip, nil)
 	assert.NoError(t, err)
 	err = testDB.Model(&models.RefreshToken{}).Where("token = ?", usedToken).Update("used", true).Error
 	assert.NoError(t, err)
 
-	// Cleanup expired and used tokens
-	err = repo.CleanupExpiredTokens()
+	// Cleanup expired and used tokens; idle pruning disabled
+	err = repo.CleanupExpiredTokens(0)
 	assert.NoError(t, err)
 
 	// Verify expired and used tokens are deleted
@@ -199,3 +374,155 @@ func TestCleanupExpiredTokens(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, validFound)
 }
+
+func TestCleanupExpiredTokensPrunesIdleTokens(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	userID := uint(1)
+	deviceInfo := "test-device"
+	ip := "127.0.0.1"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	// Token is absolutely valid but hasn't been used in a long time.
+	idleToken := "idle-token"
+	_, err := repo.CreateRefreshToken(userID, idleToken, expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+	err = testDB.Model(&models.RefreshToken{}).Where("token = ?", idleToken).
+		Update("last_used_at", time.Now().Add(-2*time.Hour)).Error
+	assert.NoError(t, err)
+
+	// Recently used token should survive the same sweep.
+	activeToken := "active-token"
+	_, err = repo.CreateRefreshToken(userID, activeToken, expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	err = repo.CleanupExpiredTokens(time.Hour)
+	assert.NoError(t, err)
+
+	_, err = repo.GetRefreshToken(idleToken)
+	assert.Error(t, err)
+	assert.Equal(t, ErrNotFound, err)
+
+	found, err := repo.GetRefreshToken(activeToken)
+	assert.NoError(t, err)
+	assert.NotNil(t, found)
+}
+
+func TestCreateRefreshTokenEvictsOldestSessionsOverLimit(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+	repo.SetMaxSimultaneousSessions(2)
+
+	userID := uint(1)
+	deviceInfo := "test-device"
+	ip := "127.0.0.1"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	oldest, err := repo.CreateRefreshToken(userID, "token-oldest", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+	_, err = repo.CreateRefreshToken(userID, "token-middle", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	// A third session should evict the oldest, keeping only the two most recent.
+	_, err = repo.CreateRefreshToken(userID, "token-newest", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	evicted, err := repo.GetRefreshToken("token-oldest")
+	assert.NoError(t, err)
+	assert.NotNil(t, evicted.RevokedAt)
+	assert.Equal(t, oldest.ID, evicted.ID)
+
+	middle, err := repo.GetRefreshToken("token-middle")
+	assert.NoError(t, err)
+	assert.Nil(t, middle.RevokedAt)
+
+	newest, err := repo.GetRefreshToken("token-newest")
+	assert.NoError(t, err)
+	assert.Nil(t, newest.RevokedAt)
+}
+
+func TestListActiveSessions(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	userID := uint(1)
+	otherUserID := uint(2)
+	deviceInfo := "test-device"
+	ip := "127.0.0.1"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	active, err := repo.CreateRefreshToken(userID, "token-active", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	revoked, err := repo.CreateRefreshToken(userID, "token-revoked", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.RevokeRefreshToken(revoked.Token))
+
+	_, err = repo.CreateRefreshToken(otherUserID, "token-other-user", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	sessions, err := repo.ListActiveSessions(userID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, active.ID, sessions[0].ID)
+}
+
+func TestRevokeSession(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	userID := uint(1)
+	otherUserID := uint(2)
+	deviceInfo := "test-device"
+	ip := "127.0.0.1"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	session, err := repo.CreateRefreshToken(userID, "token-session", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	// Another user can't revoke this session.
+	err = repo.RevokeSession(otherUserID, session.ID)
+	assert.Equal(t, ErrNotFound, err)
+
+	err = repo.RevokeSession(userID, session.ID)
+	assert.NoError(t, err)
+
+	found, err := repo.GetRefreshToken(session.Token)
+	assert.NoError(t, err)
+	assert.NotNil(t, found.RevokedAt)
+}
+
+func TestRevokeAllExcept(t *testing.T) {
+	repo, cleanup := setupTokenTest()
+	defer cleanup()
+
+	userID := uint(1)
+	otherUserID := uint(2)
+	deviceInfo := "test-device"
+	ip := "127.0.0.1"
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	keep, err := repo.CreateRefreshToken(userID, "token-keep", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	other, err := repo.CreateRefreshToken(userID, "token-other", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	otherUserToken, err := repo.CreateRefreshToken(otherUserID, "token-other-user", expiresAt, deviceInfo, ip, nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.RevokeAllExcept(userID, keep.Token))
+
+	found, err := repo.GetRefreshToken(keep.Token)
+	assert.NoError(t, err)
+	assert.Nil(t, found.RevokedAt)
+
+	found, err = repo.GetRefreshToken(other.Token)
+	assert.NoError(t, err)
+	assert.NotNil(t, found.RevokedAt)
+
+	found, err = repo.GetRefreshToken(otherUserToken.Token)
+	assert.NoError(t, err)
+	assert.Nil(t, found.RevokedAt)
+}
@@ -5,6 +5,8 @@ import (
 	"EchoAuth/models"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -18,7 +20,6 @@ type UserRepository interface {
 	FindByID(id uint) (*models.User, error)
 	Update(user *models.User) error
 	Delete(id uint) error
-	FindByResetToken(token string) (*models.User, error)
 }
 
 type userRepository struct {
@@ -31,36 +32,70 @@ func NewUserRepository(db *database.DB) UserRepository {
 	}
 }
 
+// placeholders returns n positional bind-parameter markers in db's dialect,
+// comma-joined, starting at $1/?.
+func placeholders(db *database.DB, n int) string {
+	marks := make([]string, n)
+	for i := range marks {
+		marks[i] = db.Dialect.Placeholder(i + 1)
+	}
+	return strings.Join(marks, ", ")
+}
+
 func (r *userRepository) Create(user *models.User) error {
 	now := time.Now()
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
-	query := `
-		INSERT INTO users (email, password, first_name, last_name, password_reset_token, 
-			reset_token_expires_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id`
-
-	err := r.db.QueryRow(query,
+	args := []interface{}{
 		user.Email, user.Password, user.FirstName, user.LastName,
-		user.PasswordResetToken, user.ResetTokenExpiresAt,
-		user.CreatedAt, user.UpdatedAt).Scan(&user.ID)
+		user.EmailVerified, user.IsAdmin, user.InvitedBy,
+		user.PasswordChangedAt, user.PasswordExpiresAt,
+		user.FailedLoginAttempts, user.LockedUntil,
+		user.MFAEnabled, user.MFASecretEncrypted, user.MFARecoveryCodesColumn(),
+		user.CreatedAt, user.UpdatedAt,
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO users (email, password, first_name, last_name, email_verified, is_admin, invited_by,
+			password_changed_at, password_expires_at, failed_login_attempts, locked_until,
+			mfa_enabled, mfa_secret_encrypted, mfa_recovery_codes, created_at, updated_at)
+		VALUES (%s)`, placeholders(r.db, len(args)))
+
+	if r.db.Dialect.SupportsReturning() {
+		return r.db.QueryRow(query+" RETURNING id", args...).Scan(&user.ID)
+	}
 
-	return err
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	user.ID = uint(id)
+	return nil
 }
 
 func (r *userRepository) FindByEmail(email string) (*models.User, error) {
 	user := &models.User{}
-	query := `
-		SELECT id, email, password, first_name, last_name, password_reset_token,
-			reset_token_expires_at, created_at, updated_at, deleted_at
+	var mfaSecret sql.RawBytes
+	var mfaRecoveryCodes string
+	query := fmt.Sprintf(`
+		SELECT id, email, password, first_name, last_name, email_verified, is_admin, invited_by,
+			password_changed_at, password_expires_at, failed_login_attempts, locked_until,
+			mfa_enabled, mfa_secret_encrypted, mfa_recovery_codes,
+			created_at, updated_at, deleted_at
 		FROM users
-		WHERE email = $1 AND deleted_at IS NULL`
+		WHERE email = %s AND deleted_at IS NULL`, r.db.Dialect.Placeholder(1))
 
 	err := r.db.QueryRow(query, email).Scan(
 		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
-		&user.PasswordResetToken, &user.ResetTokenExpiresAt,
+		&user.EmailVerified, &user.IsAdmin, &user.InvitedBy,
+		&user.PasswordChangedAt, &user.PasswordExpiresAt,
+		&user.FailedLoginAttempts, &user.LockedUntil,
+		&user.MFAEnabled, &mfaSecret, &mfaRecoveryCodes,
 		&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
 
 	if err == sql.ErrNoRows {
@@ -69,20 +104,31 @@ func (r *userRepository) FindByEmail(email string) (*models.User, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(mfaSecret) > 0 {
+		user.MFASecretEncrypted = append([]byte(nil), mfaSecret...)
+	}
+	user.SetMFARecoveryCodesFromColumn(mfaRecoveryCodes)
 	return user, nil
 }
 
 func (r *userRepository) FindByID(id uint) (*models.User, error) {
 	user := &models.User{}
-	query := `
-		SELECT id, email, password, first_name, last_name, password_reset_token,
-			reset_token_expires_at, created_at, updated_at, deleted_at
+	var mfaSecret sql.RawBytes
+	var mfaRecoveryCodes string
+	query := fmt.Sprintf(`
+		SELECT id, email, password, first_name, last_name, email_verified, is_admin, invited_by,
+			password_changed_at, password_expires_at, failed_login_attempts, locked_until,
+			mfa_enabled, mfa_secret_encrypted, mfa_recovery_codes,
+			created_at, updated_at, deleted_at
 		FROM users
-		WHERE id = $1 AND deleted_at IS NULL`
+		WHERE id = %s AND deleted_at IS NULL`, r.db.Dialect.Placeholder(1))
 
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
-		&user.PasswordResetToken, &user.ResetTokenExpiresAt,
+		&user.EmailVerified, &user.IsAdmin, &user.InvitedBy,
+		&user.PasswordChangedAt, &user.PasswordExpiresAt,
+		&user.FailedLoginAttempts, &user.LockedUntil,
+		&user.MFAEnabled, &mfaSecret, &mfaRecoveryCodes,
 		&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
 
 	if err == sql.ErrNoRows {
@@ -91,21 +137,34 @@ func (r *userRepository) FindByID(id uint) (*models.User, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(mfaSecret) > 0 {
+		user.MFASecretEncrypted = append([]byte(nil), mfaSecret...)
+	}
+	user.SetMFARecoveryCodesFromColumn(mfaRecoveryCodes)
 	return user, nil
 }
 
 func (r *userRepository) Update(user *models.User) error {
 	user.UpdatedAt = time.Now()
 
-	query := `
+	p := func(n int) string { return r.db.Dialect.Placeholder(n) }
+	query := fmt.Sprintf(`
 		UPDATE users
-		SET email = $1, password = $2, first_name = $3, last_name = $4,
-			password_reset_token = $5, reset_token_expires_at = $6, updated_at = $7
-		WHERE id = $8 AND deleted_at IS NULL`
+		SET email = %s, password = %s, first_name = %s, last_name = %s,
+			email_verified = %s, is_admin = %s, invited_by = %s,
+			password_changed_at = %s, password_expires_at = %s,
+			failed_login_attempts = %s, locked_until = %s,
+			mfa_enabled = %s, mfa_secret_encrypted = %s, mfa_recovery_codes = %s,
+			updated_at = %s
+		WHERE id = %s AND deleted_at IS NULL`,
+		p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8), p(9), p(10), p(11), p(12), p(13), p(14), p(15), p(16))
 
 	result, err := r.db.Exec(query,
 		user.Email, user.Password, user.FirstName, user.LastName,
-		user.PasswordResetToken, user.ResetTokenExpiresAt,
+		user.EmailVerified, user.IsAdmin, user.InvitedBy,
+		user.PasswordChangedAt, user.PasswordExpiresAt,
+		user.FailedLoginAttempts, user.LockedUntil,
+		user.MFAEnabled, user.MFASecretEncrypted, user.MFARecoveryCodesColumn(),
 		user.UpdatedAt, user.ID)
 
 	if err != nil {
@@ -126,10 +185,10 @@ func (r *userRepository) Update(user *models.User) error {
 
 func (r *userRepository) Delete(id uint) error {
 	now := time.Now()
-	query := `
+	query := fmt.Sprintf(`
 		UPDATE users
-		SET deleted_at = $1
-		WHERE id = $2 AND deleted_at IS NULL`
+		SET deleted_at = %s
+		WHERE id = %s`, r.db.Dialect.Placeholder(1), r.db.Dialect.Placeholder(2))
 
 	result, err := r.db.Exec(query, now, id)
 	if err != nil {
@@ -147,25 +206,3 @@ func (r *userRepository) Delete(id uint) error {
 
 	return nil
 }
-
-func (r *userRepository) FindByResetToken(token string) (*models.User, error) {
-	user := &models.User{}
-	query := `
-		SELECT id, email, password, first_name, last_name, password_reset_token,
-			reset_token_expires_at, created_at, updated_at, deleted_at
-		FROM users
-		WHERE password_reset_token = $1 AND deleted_at IS NULL`
-
-	err := r.db.QueryRow(query, token).Scan(
-		&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
-		&user.PasswordResetToken, &user.ResetTokenExpiresAt,
-		&user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
-
-	if err == sql.ErrNoRows {
-		return nil, ErrNotFound
-	}
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
-}
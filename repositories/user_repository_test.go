@@ -1,21 +1,62 @@
 package repositories
 
 import (
+	"EchoAuth/database"
 	"EchoAuth/models"
 	"fmt"
 	"os"
 	"testing"
 
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-var testDB *gorm.DB
+// defaultTestDatabaseURL is used when DATABASE_URL isn't set, matching the
+// postgres service docker-compose/CI has always started this suite against.
+const defaultTestDatabaseURL = "host=localhost user=postgres password=postgres dbname=auth_test_db port=5433 sslmode=disable"
+
+var (
+	testDB      *gorm.DB
+	testDialect database.Dialect
+)
+
+// gormDialector picks the gorm driver matching dialect, so TestMain can open
+// whatever backend DATABASE_URL names instead of assuming Postgres - the
+// same dialects database.InitDB supports in production.
+func gormDialector(dialect database.Dialect, dsn string) (gorm.Dialector, error) {
+	switch dialect {
+	case database.DialectSQLite:
+		return sqlite.Open(dsn), nil
+	case database.DialectMySQL:
+		return mysql.Open(dsn), nil
+	case database.DialectPostgres, database.DialectCockroach:
+		return postgres.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported test database dialect %q", dialect)
+	}
+}
 
 func TestMain(m *testing.M) {
-	// Setup test database
-	dsn := "host=localhost user=postgres password=postgres dbname=auth_test_db port=5433 sslmode=disable"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		databaseURL = defaultTestDatabaseURL
+	}
+
+	_, dialect, dsn, err := database.ParseDatabaseURL(databaseURL)
+	if err != nil {
+		fmt.Printf("Failed to parse DATABASE_URL: %v\n", err)
+		os.Exit(1)
+	}
+
+	dialector, err := gormDialector(dialect, dsn)
+	if err != nil {
+		fmt.Printf("Failed to set up test database: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		fmt.Printf("Failed to connect to test database: %v\n", err)
 		os.Exit(1)
@@ -29,6 +70,7 @@ func TestMain(m *testing.M) {
 	}
 
 	testDB = db
+	testDialect = dialect
 
 	// Run tests
 	code := m.Run()
@@ -42,11 +84,24 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// rawDB wraps testDB's *sql.DB with the dialect TestMain resolved from
+// DATABASE_URL, for repository constructors that take *database.DB rather
+// than gorm's - so they generate the placeholder syntax (and RETURNING
+// behavior) the DATABASE_URL the CI matrix set actually expects, instead of
+// always behaving like Postgres.
+func rawDB() *database.DB {
+	sqlDB, err := testDB.DB()
+	if err != nil {
+		panic(err)
+	}
+	return &database.DB{DB: sqlDB, Dialect: testDialect}
+}
+
 func setupTest() (*userRepository, func()) {
 	// Clear the database before each test
 	testDB.Exec("DELETE FROM users")
 
-	repo := &userRepository{db: testDB}
+	repo := &userRepository{db: rawDB()}
 
 	return repo, func() {
 		testDB.Exec("DELETE FROM users")
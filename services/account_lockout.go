@@ -1,6 +1,8 @@
 package services
 
 import (
+	"EchoAuth/repositories"
+	"EchoAuth/utils/logger"
 	"context"
 	"errors"
 	"time"
@@ -12,23 +14,70 @@ var (
 	ErrAccountLocked = errors.New("account is locked due to too many failed attempts")
 )
 
+// AccountLockoutService throttles login attempts per email and,
+// independently, per source IP. Every failed attempt sets a short,
+// exponentially increasing cooldown (baseCooldown, 2*baseCooldown,
+// 4*baseCooldown, ... capped at maxCooldown); once maxAttempts failures
+// land within attemptExpiry, the account is locked instead, for a duration
+// that itself grows with each successive lockout (lockoutDurations[0],
+// lockoutDurations[1], ..., capped at the last entry) rather than the same
+// fixed window every time. The escalation level driving that growth is
+// tracked in lockout_level:<email> and decays back to zero after
+// lockoutLevelDecay of no further lockouts. Counters live in Redis so they
+// expire on their own; if SetUserRepo has been called, a lock is also
+// persisted to the user row's LockedUntil so it survives a Redis flush and
+// is visible to anything reading the user directly.
+//
+// ipMaxAttempts/ipAttemptExpiry/ipLockDuration run a second, independent
+// counter keyed by source IP rather than email, so a single hostile IP
+// spraying many different usernames gets blocked even though no individual
+// username ever reaches maxAttempts. ipMaxAttempts <= 0 disables IP-scoped
+// throttling, which is also the zero value, so an AccountLockoutService
+// built outside NewAccountLockoutService keeps its previous email-only
+// behavior.
 type AccountLockoutService struct {
 	redis         *redis.Client
+	userRepo      repositories.UserRepository
 	maxAttempts   int
+	baseCooldown  time.Duration
+	maxCooldown   time.Duration
 	lockDuration  time.Duration
 	attemptExpiry time.Duration
+
+	lockoutDurations  []time.Duration
+	lockoutLevelDecay time.Duration
+
+	ipMaxAttempts   int
+	ipAttemptExpiry time.Duration
+	ipLockDuration  time.Duration
 }
 
 func NewAccountLockoutService(redis *redis.Client) *AccountLockoutService {
 	return &AccountLockoutService{
 		redis:         redis,
 		maxAttempts:   5,                // Lock after 5 failed attempts
-		lockDuration:  15 * time.Minute, // Lock for 15 minutes
+		baseCooldown:  1 * time.Second,  // First cooldown is 1s, doubling each attempt
+		maxCooldown:   30 * time.Second, // Cooldown never exceeds 30s
+		lockDuration:  15 * time.Minute, // Fallback lockout length if lockoutDurations is empty
 		attemptExpiry: 1 * time.Hour,    // Reset attempts after 1 hour
+
+		lockoutDurations:  []time.Duration{1 * time.Minute, 5 * time.Minute, 15 * time.Minute, 1 * time.Hour},
+		lockoutLevelDecay: 24 * time.Hour, // A day of no lockouts resets the escalation level
+
+		ipMaxAttempts:   20, // A single IP gets more room than one email, since it covers every username it tries
+		ipAttemptExpiry: 1 * time.Hour,
+		ipLockDuration:  15 * time.Minute,
 	}
 }
 
-// RecordFailedAttempt increments the failed attempt counter for an email
+// SetUserRepo enables persisting a lockout to the user row's LockedUntil
+// column. It is optional: without it, lockout state lives in Redis only.
+func (s *AccountLockoutService) SetUserRepo(repo repositories.UserRepository) {
+	s.userRepo = repo
+}
+
+// RecordFailedAttempt increments the failed attempt counter for an email and
+// sets the next cooldown, escalating to a full lockout at maxAttempts.
 func (s *AccountLockoutService) RecordFailedAttempt(ctx context.Context, email string) error {
 	// Check if account is locked
 	locked, err := s.IsLocked(ctx, email)
@@ -49,21 +98,90 @@ func (s *AccountLockoutService) RecordFailedAttempt(ctx context.Context, email s
 		return err
 	}
 
-	// Check if account should be locked
 	attempts, err := s.redis.Get(ctx, attemptsKey).Int()
 	if err != nil {
 		return err
 	}
 
+	now := time.Now()
 	if attempts >= s.maxAttempts {
-		lockKey := "account_locked:" + email
-		err = s.redis.Set(ctx, lockKey, true, s.lockDuration).Err()
+		duration, err := s.nextLockoutDuration(ctx, email)
 		if err != nil {
 			return err
 		}
+		return s.lockFor(ctx, email, now, duration)
+	}
+
+	cooldown := s.baseCooldown << uint(attempts-1)
+	if cooldown > s.maxCooldown {
+		cooldown = s.maxCooldown
+	}
+	if cooldown <= 0 {
+		return nil
+	}
+	return s.lockFor(ctx, email, now, cooldown)
+}
+
+// nextLockoutDuration advances email's escalation level by one and returns
+// the duration it should be locked for. The level is capped at
+// len(lockoutDurations)-1 and expires after lockoutLevelDecay of no further
+// lockouts, so an account that stops triggering lockouts eventually returns
+// to the shortest duration. An empty lockoutDurations (an AccountLockoutService
+// built outside NewAccountLockoutService) falls back to the fixed
+// lockDuration used before this escalation existed.
+func (s *AccountLockoutService) nextLockoutDuration(ctx context.Context, email string) (time.Duration, error) {
+	if len(s.lockoutDurations) == 0 {
+		return s.lockDuration, nil
+	}
+
+	levelKey := "lockout_level:" + email
+	level, err := s.redis.Incr(ctx, levelKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if s.lockoutLevelDecay > 0 {
+		if err := s.redis.Expire(ctx, levelKey, s.lockoutLevelDecay).Err(); err != nil {
+			return 0, err
+		}
 	}
 
-	return nil
+	idx := int(level) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(s.lockoutDurations) {
+		idx = len(s.lockoutDurations) - 1
+	}
+	return s.lockoutDurations[idx], nil
+}
+
+// lockFor blocks further attempts from email until now+until, in Redis and,
+// if a user repo is configured, on the user row itself.
+func (s *AccountLockoutService) lockFor(ctx context.Context, email string, now time.Time, until time.Duration) error {
+	lockKey := "account_locked:" + email
+	if err := s.redis.Set(ctx, lockKey, true, until).Err(); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Warn().
+		Str("event", "lockout_trip").
+		Str("email", email).
+		Dur("locked_for", until).
+		Msg("account locked after repeated failed attempts")
+
+	if s.userRepo == nil {
+		return nil
+	}
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	lockedUntil := now.Add(until)
+	user.LockedUntil = &lockedUntil
+	return s.userRepo.Update(user)
 }
 
 // IsLocked checks if an account is currently locked
@@ -76,6 +194,49 @@ func (s *AccountLockoutService) IsLocked(ctx context.Context, email string) (boo
 	return exists == 1, nil
 }
 
+// IsIPLocked reports whether ip is currently blocked due to too many failed
+// attempts across any usernames, independent of any single account's own
+// lockout state. It always reports false while ipMaxAttempts <= 0, the
+// zero value, so IP-scoped throttling is opt-in.
+func (s *AccountLockoutService) IsIPLocked(ctx context.Context, ip string) (bool, error) {
+	if s.ipMaxAttempts <= 0 || ip == "" {
+		return false, nil
+	}
+	exists, err := s.redis.Exists(ctx, "ip_locked:"+ip).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists == 1, nil
+}
+
+// RecordFailedAttemptForIP increments ip's failed attempt counter,
+// independently of any email-scoped counter, and locks the IP once
+// ipMaxAttempts is reached - a credential-stuffing run spread across many
+// different usernames never trips any single account's counter, but does
+// trip this one. A no-op while ipMaxAttempts <= 0.
+func (s *AccountLockoutService) RecordFailedAttemptForIP(ctx context.Context, ip string) error {
+	if s.ipMaxAttempts <= 0 || ip == "" {
+		return nil
+	}
+
+	attemptsKey := "failed_attempts_ip:" + ip
+	pipe := s.redis.Pipeline()
+	pipe.Incr(ctx, attemptsKey)
+	pipe.Expire(ctx, attemptsKey, s.ipAttemptExpiry)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	attempts, err := s.redis.Get(ctx, attemptsKey).Int()
+	if err != nil {
+		return err
+	}
+	if attempts < s.ipMaxAttempts {
+		return nil
+	}
+	return s.redis.Set(ctx, "ip_locked:"+ip, true, s.ipLockDuration).Err()
+}
+
 // ResetAttempts resets the failed attempt counter for an email (e.g., after successful login)
 func (s *AccountLockoutService) ResetAttempts(ctx context.Context, email string) error {
 	attemptsKey := "failed_attempts:" + email
@@ -85,7 +246,26 @@ func (s *AccountLockoutService) ResetAttempts(ctx context.Context, email string)
 	pipe.Del(ctx, attemptsKey)
 	pipe.Del(ctx, lockKey)
 	_, err := pipe.Exec(ctx)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if s.userRepo == nil {
+		return nil
+	}
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	return s.userRepo.Update(user)
 }
 
 // GetRemainingAttempts returns the number of attempts remaining before account lockout
@@ -104,3 +284,44 @@ func (s *AccountLockoutService) GetRemainingAttempts(ctx context.Context, email
 	}
 	return remaining, nil
 }
+
+// LockoutStatus is a snapshot of an email's current lockout state, for a
+// caller (the login controller) that needs to explain a 423 response -
+// e.g. to set a Retry-After header - rather than just a locked/not-locked
+// boolean.
+type LockoutStatus struct {
+	Level             int
+	Locked            bool
+	NextAttemptAt     time.Time
+	RemainingAttempts int
+}
+
+// GetLockoutStatus reports email's current escalation level, whether it is
+// presently locked, when it may next attempt to log in, and how many failed
+// attempts remain before the next lockout.
+func (s *AccountLockoutService) GetLockoutStatus(ctx context.Context, email string) (LockoutStatus, error) {
+	var status LockoutStatus
+
+	level, err := s.redis.Get(ctx, "lockout_level:"+email).Int()
+	if err != nil && err != redis.Nil {
+		return status, err
+	}
+	status.Level = level
+
+	remaining, err := s.GetRemainingAttempts(ctx, email)
+	if err != nil {
+		return status, err
+	}
+	status.RemainingAttempts = remaining
+
+	ttl, err := s.redis.TTL(ctx, "account_locked:"+email).Result()
+	if err != nil {
+		return status, err
+	}
+	if ttl > 0 {
+		status.Locked = true
+		status.NextAttemptAt = time.Now().Add(ttl)
+	}
+
+	return status, nil
+}
@@ -2,66 +2,148 @@ package services
 
 import (
 	"EchoAuth/config"
+	"EchoAuth/keys"
 	"EchoAuth/models"
 	"EchoAuth/repositories"
+	"EchoAuth/utils/logger"
+	"EchoAuth/utils/metrics"
 	"EchoAuth/utils/validator"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrUserExists         = errors.New("user already exists")
-	ErrTokenBlacklisted   = errors.New("token is blacklisted")
+	ErrInvalidCredentials    = errors.New("invalid email or password")
+	ErrUserExists            = errors.New("user already exists")
+	ErrTokenBlacklisted      = errors.New("token is blacklisted")
+	ErrPasswordExpired       = errors.New("password has expired and must be changed")
+	ErrInvalidScope          = errors.New("requested scope exceeds the scope granted to this token")
+	ErrEmailNotVerified      = errors.New("email address has not been verified")
+	ErrRefreshTokenIdle      = errors.New("refresh token has not been used within the idle timeout")
+	ErrRefreshTokenReused    = errors.New("refresh token has already been used; all sessions in its family have been revoked")
+	ErrMFARequired           = errors.New("mfa verification required")
+	ErrRevocationUnavailable = errors.New("token revocation is not configured")
 )
 
+// mfaChallengeTokenTTL bounds how long a mfa_token from LoginWithRefresh
+// stays valid for a matching call to CompleteMFALogin, so a challenge left
+// unanswered can't be replayed long after the login attempt it belongs to.
+const mfaChallengeTokenTTL = 5 * time.Minute
+
+// refreshReuseGraceWindow is how long after a refresh token is rotated it
+// may still be presented again without tripping reuse detection, so a client
+// that rotated successfully but lost the response (a dropped connection, a
+// retried request) can recover by retrying with the same token instead of
+// having its whole session family revoked as compromised.
+const refreshReuseGraceWindow = 10 * time.Second
+
 type AuthServiceInterface interface {
-	Register(email, password, firstName, lastName string) error
-	LoginWithRefresh(email, password, deviceInfo, ip string) (string, string, error)
-	Logout(token string) error
+	Register(ctx context.Context, email, password, firstName, lastName string) error
+	LoginWithRefresh(ctx context.Context, email, password, deviceInfo, ip string, scopes []string) (string, string, error)
+	Logout(ctx context.Context, token string) error
 	ValidateToken(token string) (*models.TokenClaims, error)
-	RefreshToken(refreshToken, deviceInfo, ip string) (string, string, error)
+	RefreshToken(ctx context.Context, refreshToken, deviceInfo, ip string, scopes []string) (string, string, error)
 	GetJWTExpiry() time.Duration
 	GetUserByEmail(email string) (*models.User, error)
+	IssuePasswordChangeToken(email string) (string, error)
 }
 
 type AuthService struct {
-	userRepo      repositories.UserRepository
-	tokenRepo     repositories.TokenRepositoryInterface
-	jwtExpiry     time.Duration
-	refreshExpiry time.Duration
-	jwtSecret     string
-	lockoutSvc    *AccountLockoutService
-	redisClient   *redis.Client
+	userRepo             repositories.UserRepository
+	tokenRepo            repositories.TokenRepositoryInterface
+	jwtExpiry            time.Duration
+	refreshExpiry        time.Duration
+	refreshIdleTimeout   time.Duration
+	keyManager           keys.KeyManager
+	lockoutSvc           *AccountLockoutService
+	redisClient          *redis.Client
+	passwordMaxAge       time.Duration
+	passwordHistoryRepo  repositories.PasswordHistoryRepository
+	tokenRevocationSvc   *TokenRevocationService
+	actionTokenRepo      repositories.ActionTokenRepository
+	requireVerifiedEmail bool
+	tokenIssuer          string
+	tokenAudience        string
+	emailVerificationSvc *EmailVerificationService
+	mfaSvc               *MFAService
+	allowMultiLogin      bool
 }
 
-func NewAuthService(userRepo repositories.UserRepository, tokenRepo repositories.TokenRepositoryInterface, cfg *config.Config, lockoutSvc *AccountLockoutService, redisClient *redis.Client) *AuthService {
+func NewAuthService(userRepo repositories.UserRepository, tokenRepo repositories.TokenRepositoryInterface, cfg *config.Config, lockoutSvc *AccountLockoutService, redisClient *redis.Client, keyManager keys.KeyManager) *AuthService {
+	refreshExpiry := cfg.RefreshAbsoluteTimeout
+	if refreshExpiry <= 0 {
+		refreshExpiry = 30 * 24 * time.Hour
+	}
+
 	return &AuthService{
-		userRepo:      userRepo,
-		tokenRepo:     tokenRepo,
-		jwtExpiry:     cfg.JWTExpiry,
-		refreshExpiry: 30 * 24 * time.Hour, // 30 days
-		jwtSecret:     cfg.JWTSecret,
-		lockoutSvc:    lockoutSvc,
-		redisClient:   redisClient,
+		userRepo:             userRepo,
+		tokenRepo:            tokenRepo,
+		jwtExpiry:            cfg.JWTExpiry,
+		refreshExpiry:        refreshExpiry,
+		refreshIdleTimeout:   cfg.RefreshIdleTimeout,
+		keyManager:           keyManager,
+		lockoutSvc:           lockoutSvc,
+		redisClient:          redisClient,
+		passwordMaxAge:       cfg.PasswordMaxAge,
+		requireVerifiedEmail: cfg.RequireVerifiedEmail,
+		tokenIssuer:          cfg.PublicURL,
+		tokenAudience:        cfg.JWTAudience,
+		allowMultiLogin:      cfg.AllowMultiLogin,
 	}
 }
 
-func (s *AuthService) Register(email, password, firstName, lastName string) error {
+// SetPasswordHistoryRepo enables password-reuse checks when changing an
+// expired password. It is optional: without it, password expiration is
+// still enforced but reuse is not checked.
+func (s *AuthService) SetPasswordHistoryRepo(repo repositories.PasswordHistoryRepository) {
+	s.passwordHistoryRepo = repo
+}
+
+// SetTokenRevocationService enables revoking still-valid access tokens by
+// jti before their natural expiry. It is optional: without it, access
+// tokens can only be invalidated by waiting out their exp.
+func (s *AuthService) SetTokenRevocationService(svc *TokenRevocationService) {
+	s.tokenRevocationSvc = svc
+}
+
+// SetEmailVerificationService makes Register send a verification email to
+// every newly created user. It is optional: without it, new users are still
+// created with EmailVerified false, but nothing prompts them to confirm it.
+func (s *AuthService) SetEmailVerificationService(svc *EmailVerificationService) {
+	s.emailVerificationSvc = svc
+}
+
+// SetActionTokenRepo enables issuing password-change tokens through the
+// shared single-use token store. It is optional: without it,
+// IssuePasswordChangeToken falls back to returning an error.
+func (s *AuthService) SetActionTokenRepo(repo repositories.ActionTokenRepository) {
+	s.actionTokenRepo = repo
+}
+
+// SetMFAService makes LoginWithRefresh challenge a user who has TOTP
+// enabled instead of logging them straight in. It is optional: without it,
+// MFA enrollment has no effect on login.
+func (s *AuthService) SetMFAService(svc *MFAService) {
+	s.mfaSvc = svc
+}
+
+func (s *AuthService) Register(ctx context.Context, email, password, firstName, lastName string) error {
 	// Validate email
 	if err := validator.ValidateEmail(email); err != nil {
 		return err
 	}
 
-	// Validate password complexity
-	if err := validator.ValidatePassword(password); err != nil {
+	// Validate password complexity and strength
+	if err := validator.ValidatePasswordForUser(password, []string{email, firstName, lastName}); err != nil {
 		return err
 	}
 
@@ -85,8 +167,27 @@ func (s *AuthService) Register(email, password, firstName, lastName string) erro
 	if err := user.HashPassword(password); err != nil {
 		return err
 	}
+	user.RecordPasswordChange(time.Now(), s.passwordMaxAge)
 
-	return s.userRepo.Create(user)
+	if err := s.userRepo.Create(user); err != nil {
+		return err
+	}
+
+	if s.emailVerificationSvc != nil {
+		if _, err := s.emailVerificationSvc.GenerateVerificationToken(user.Email); err != nil {
+			// The account is already created; failing to send the
+			// verification email shouldn't fail registration itself.
+			logger.FromContext(ctx).Error().Err(err).Str("email", user.Email).Msg("Failed to send verification email on registration")
+		}
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("event", "register").
+		Uint("user_id", user.ID).
+		Str("outcome", "success").
+		Msg("user registered")
+
+	return nil
 }
 
 func (s *AuthService) Login(ctx context.Context, email, password string) (string, error) {
@@ -120,75 +221,111 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (string
 		return "", ErrInvalidCredentials
 	}
 
-	// Reset failed attempts on successful login
-	err = s.lockoutSvc.ResetAttempts(ctx, email)
-	if err != nil {
-		return "", err
+	if s.requireVerifiedEmail && !user.EmailVerified {
+		return "", ErrEmailNotVerified
 	}
 
-	// Generate JWT token
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["user_id"] = user.ID
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
-	claims["iat"] = time.Now().Unix()
+	s.rehashIfNeeded(user, password)
 
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	// Reset failed attempts on successful login
+	err = s.lockoutSvc.ResetAttempts(ctx, email)
 	if err != nil {
 		return "", err
 	}
 
-	return tokenString, nil
+	return s.GenerateToken(user.ID)
 }
 
 func (s *AuthService) ValidateToken(tokenString string) (*models.TokenClaims, error) {
-	// First check if token is blacklisted
-	ctx := context.Background()
-	exists, err := s.redisClient.Exists(ctx, fmt.Sprintf("blacklist:%s", tokenString)).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to check token blacklist: %w", err)
-	}
-	if exists == 1 {
-		return nil, ErrTokenBlacklisted
-	}
-
 	token, err := jwt.ParseWithClaims(tokenString, &models.TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		for _, pub := range s.keyManager.PublicKeys() {
+			if pub.Kid != kid {
+				continue
+			}
+			// Bind the verification key to the alg it was issued under, not
+			// just its kid, so a token can't be re-signed under a different
+			// algorithm than the one its key was generated for.
+			if token.Method.Alg() != pub.Alg {
+				return nil, fmt.Errorf("signing method %q does not match key %q algorithm %q", token.Method.Alg(), kid, pub.Alg)
+			}
+			return pub.Key, nil
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*models.TokenClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*models.TokenClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
 	}
 
-	return nil, errors.New("invalid token")
+	if s.tokenRevocationSvc != nil {
+		if claims.Id != "" {
+			revoked, err := s.tokenRevocationSvc.IsRevoked(claims.Id)
+			if err != nil {
+				return nil, err
+			}
+			if revoked {
+				return nil, ErrTokenBlacklisted
+			}
+		}
+
+		// Checked in addition to the per-jti blacklist above so a token
+		// issued before a RevokeAllForUser cutoff is rejected in one O(1)
+		// lookup, even if it was never individually tracked (e.g. it was
+		// issued before TokenRevocationService was wired in).
+		revokedSince, err := s.tokenRevocationSvc.IsRevokedSince(claims.UserID, time.Unix(claims.IssuedAt, 0))
+		if err != nil {
+			return nil, err
+		}
+		if revokedSince {
+			return nil, ErrTokenBlacklisted
+		}
+	}
+
+	return claims, nil
 }
 
-func (s *AuthService) Logout(token string) error {
-	// First validate the token
+// Logout invalidates token by jti rather than by storing the full JWT as a
+// blacklist key, the same scheme RevokeAny's access-token path uses, so a
+// blacklisted session costs one short Redis key instead of one the length
+// of a signed JWT. Without a configured TokenRevocationService, a logged
+// out access token remains valid until it naturally expires.
+func (s *AuthService) Logout(ctx context.Context, token string) error {
 	claims, err := s.ValidateToken(token)
 	if err != nil {
+		if errors.Is(err, ErrTokenBlacklisted) {
+			logger.FromContext(ctx).Warn().
+				Str("event", "logout").
+				Str("outcome", "blacklist_hit").
+				Msg("logout presented an already-blacklisted token")
+		}
 		return err
 	}
 
-	// Calculate token expiry
-	expiresAt := time.Unix(claims.ExpiresAt, 0)
-	ttl := time.Until(expiresAt)
-	if ttl <= 0 {
-		return nil // Token is already expired
+	if s.tokenRevocationSvc == nil || claims.Id == "" {
+		return nil
 	}
 
-	// Add token to blacklist with TTL matching token expiry
-	ctx := context.Background()
-	key := fmt.Sprintf("blacklist:%s", token)
-	err = s.redisClient.Set(ctx, key, true, ttl).Err()
-	if err != nil {
-		return fmt.Errorf("failed to blacklist token: %w", err)
+	if err := s.tokenRevocationSvc.Revoke(claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return err
 	}
 
+	logger.FromContext(ctx).Info().
+		Str("event", "logout").
+		Uint("user_id", claims.UserID).
+		Str("outcome", "success").
+		Msg("access token revoked on logout")
+
 	return nil
 }
 
@@ -200,35 +337,234 @@ func (s *AuthService) generateRefreshToken() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-func (s *AuthService) LoginWithRefresh(email, password string, deviceInfo, ip string) (string, string, error) {
+// IssuePasswordChangeToken generates a short-lived, single-purpose token for
+// a user whose password has expired, scoped only to setting a new password
+// via the existing password-reset endpoint.
+func (s *AuthService) IssuePasswordChangeToken(email string) (string, error) {
+	if s.actionTokenRepo == nil {
+		return "", errors.New("action token repository is not configured")
+	}
+
 	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.URLEncoding.EncodeToString(b)
+
+	actionToken := &models.ActionToken{
+		UserID:    user.ID,
+		Token:     token,
+		Purpose:   models.TokenPurposeReset,
+		ExpiresAt: time.Now().Add(15 * time.Minute),
+	}
+	if err := s.actionTokenRepo.Create(actionToken); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AuthService) LoginWithRefresh(ctx context.Context, email, password string, deviceInfo, ip string, scopes []string) (string, string, error) {
+	// The IP-scoped counter is checked independently of, and before, the
+	// per-email one: a hostile IP spraying many different usernames never
+	// trips any single email's counter, so it needs its own gate.
+	ipLocked, err := s.lockoutSvc.IsIPLocked(ctx, ip)
 	if err != nil {
 		return "", "", err
 	}
+	if ipLocked {
+		return "", "", ErrAccountLocked
+	}
+
+	locked, err := s.lockoutSvc.IsLocked(ctx, email)
+	if err != nil {
+		return "", "", err
+	}
+	if locked {
+		return "", "", ErrAccountLocked
+	}
+
+	logFailedLogin := func() {
+		logger.FromContext(ctx).Warn().
+			Str("event", "login").
+			Str("ip", ip).
+			Str("device_info", deviceInfo).
+			Str("outcome", "failure").
+			Msg("login failed")
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			_ = s.lockoutSvc.RecordFailedAttempt(ctx, email)
+			_ = s.lockoutSvc.RecordFailedAttemptForIP(ctx, ip)
+			logFailedLogin()
+		}
+		return "", "", err
+	}
 
 	if !user.CheckPassword(password) {
+		if lockErr := s.lockoutSvc.RecordFailedAttempt(ctx, email); lockErr != nil {
+			return "", "", lockErr
+		}
+		if lockErr := s.lockoutSvc.RecordFailedAttemptForIP(ctx, ip); lockErr != nil {
+			return "", "", lockErr
+		}
+		logFailedLogin()
 		return "", "", errors.New("invalid credentials")
 	}
 
-	// Generate access token
-	accessToken, err := s.GenerateToken(user.ID)
+	if user.PasswordExpired(time.Now()) {
+		return "", "", ErrPasswordExpired
+	}
+
+	if s.requireVerifiedEmail && !user.EmailVerified {
+		return "", "", ErrEmailNotVerified
+	}
+
+	s.rehashIfNeeded(user, password)
+
+	if err := s.lockoutSvc.ResetAttempts(ctx, email); err != nil {
+		return "", "", err
+	}
+
+	if s.mfaSvc != nil && user.MFAEnabled {
+		return "", "", ErrMFARequired
+	}
+
+	accessToken, refreshToken, err := s.issueTokens(ctx, user.ID, deviceInfo, ip, scopes)
+	if err != nil {
+		return "", "", err
+	}
+
+	logger.FromContext(ctx).Info().
+		Str("event", "login").
+		Uint("user_id", user.ID).
+		Str("ip", ip).
+		Str("device_info", deviceInfo).
+		Str("outcome", "success").
+		Msg("login succeeded")
+
+	return accessToken, refreshToken, nil
+}
+
+// IssueMFAToken generates the short-lived mfa_token a client exchanges for a
+// completed login via CompleteMFALogin, once LoginWithRefresh has reported
+// ErrMFARequired for email. It reuses the same single-use token store as
+// IssuePasswordChangeToken, under the reserved TokenPurposeMFA.
+func (s *AuthService) IssueMFAToken(email string) (string, error) {
+	if s.actionTokenRepo == nil {
+		return "", errors.New("action token repository is not configured")
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.URLEncoding.EncodeToString(b)
+
+	actionToken := &models.ActionToken{
+		UserID:    user.ID,
+		Token:     token,
+		Purpose:   models.TokenPurposeMFA,
+		ExpiresAt: time.Now().Add(mfaChallengeTokenTTL),
+	}
+	if err := s.actionTokenRepo.Create(actionToken); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CompleteMFALogin finishes a login challenged by ErrMFARequired: mfaToken
+// must be one issued by IssueMFAToken and not yet used or expired, and code
+// must be a valid TOTP (or recovery) code for the user it was issued to.
+func (s *AuthService) CompleteMFALogin(mfaToken, code, deviceInfo, ip string) (string, string, error) {
+	if s.actionTokenRepo == nil || s.mfaSvc == nil {
+		return "", "", errors.New("mfa is not configured")
+	}
+
+	actionToken, err := s.actionTokenRepo.Consume(mfaToken, models.TokenPurposeMFA)
+	if err != nil {
+		return "", "", errors.New("invalid or expired mfa token")
+	}
+	if actionToken.Expired(time.Now()) {
+		return "", "", errors.New("invalid or expired mfa token")
+	}
+
+	if err := s.mfaSvc.VerifyTOTP(actionToken.UserID, code); err != nil {
+		return "", "", err
+	}
+
+	return s.issueTokens(context.Background(), actionToken.UserID, deviceInfo, ip, nil)
+}
+
+// GetLockoutStatus reports email's current lockout escalation state, for a
+// caller that needs more than a locked/not-locked boolean - e.g. the login
+// controller setting a Retry-After header on a 423 response.
+func (s *AuthService) GetLockoutStatus(ctx context.Context, email string) (LockoutStatus, error) {
+	return s.lockoutSvc.GetLockoutStatus(ctx, email)
+}
+
+// rehashIfNeeded silently upgrades a user's stored password hash to the
+// current policy's algorithm/cost after a successful login with the
+// plaintext password, since that's the only moment the plaintext is
+// available. It is best-effort: a failure to re-hash or persist doesn't
+// fail the login that's already succeeded.
+func (s *AuthService) rehashIfNeeded(user *models.User, plaintext string) {
+	if !user.NeedsPasswordRehash() {
+		return
+	}
+	if err := user.HashPassword(plaintext); err != nil {
+		return
+	}
+	_ = s.userRepo.Update(user)
+}
+
+// IssueTokensForUser mints an access/refresh token pair for a user who has
+// already been authenticated by some other means (e.g. a federated login
+// scheme), without re-checking a password.
+func (s *AuthService) IssueTokensForUser(user *models.User, deviceInfo, ip string) (string, string, error) {
+	return s.issueTokens(context.Background(), user.ID, deviceInfo, ip, nil)
+}
+
+// issueTokens mints a fresh access/refresh token pair for a login that has
+// already been authenticated - by password, MFA, or an OIDC/OAuth exchange.
+// When allowMultiLogin is false, the user is limited to a single session, so
+// every prior refresh token is revoked first rather than left to be evicted
+// one-by-one as MaxSimultaneousSessions would otherwise do.
+func (s *AuthService) issueTokens(ctx context.Context, userID uint, deviceInfo, ip string, scopes []string) (string, string, error) {
+	if !s.allowMultiLogin {
+		if err := s.RevokeAllUserTokens(ctx, userID); err != nil {
+			return "", "", err
+		}
+	}
+
+	accessToken, err := s.GenerateToken(userID)
 	if err != nil {
 		return "", "", err
 	}
 
-	// Generate refresh token
 	refreshToken, err := s.generateRefreshToken()
 	if err != nil {
 		return "", "", err
 	}
 
-	// Store refresh token
 	_, err = s.tokenRepo.CreateRefreshToken(
-		user.ID,
+		userID,
 		refreshToken,
 		time.Now().Add(s.refreshExpiry),
 		deviceInfo,
 		ip,
+		scopes,
 	)
 	if err != nil {
 		return "", "", err
@@ -237,16 +573,79 @@ func (s *AuthService) LoginWithRefresh(email, password string, deviceInfo, ip st
 	return accessToken, refreshToken, nil
 }
 
-func (s *AuthService) RefreshToken(refreshToken, deviceInfo, ip string) (string, string, error) {
+// RefreshToken rotates a refresh token. requestedScopes, if non-empty, must
+// be a subset of the scopes already granted to the token being rotated; the
+// rotated token is downscoped to exactly that subset. An empty
+// requestedScopes carries the original scopes forward unchanged. Requesting
+// a scope the token was never granted returns ErrInvalidScope.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken, deviceInfo, ip string, requestedScopes []string) (string, string, error) {
 	// Get existing refresh token
 	token, err := s.tokenRepo.GetRefreshToken(refreshToken)
 	if err != nil {
 		return "", "", errors.New("invalid refresh token")
 	}
 
-	// Validate token
-	if !token.IsValid() {
-		return "", "", errors.New("refresh token is expired or revoked")
+	// A used token presented again within refreshReuseGraceWindow of its own
+	// rotation is treated as a legitimate retry - the client rotated
+	// successfully but never saw the response - and is allowed to rotate
+	// again. Outside that window, or if the token was already revoked,
+	// presenting it again means it was either replayed by an attacker who
+	// intercepted it, or the legitimate rotation chain branched after a
+	// theft. Either way, the whole family is treated as compromised and
+	// revoked so the legitimate user is forced to re-authenticate.
+	retrying := token.Used && token.RevokedAt == nil && token.ExpiresAt.After(time.Now()) && token.RecentlyUsed(refreshReuseGraceWindow)
+	if !retrying {
+		reused, err := s.tokenRepo.DetectReuse(token)
+		if err != nil {
+			return "", "", err
+		}
+		if reused {
+			metrics.RecordAuthAttempt("refresh", "reuse_detected")
+			metrics.RecordAuthenticationAttempt(false, "local")
+			metrics.RecordRefreshTokenReuse(token.UserID)
+
+			// Revoking the refresh token family stops the compromised chain
+			// from rotating again, but any access token already issued from
+			// it is still valid until it expires on its own - also cut
+			// those off immediately rather than waiting that out.
+			if s.tokenRevocationSvc != nil {
+				if err := s.tokenRevocationSvc.RevokeAllForUser(token.UserID); err != nil {
+					return "", "", err
+				}
+			}
+
+			logger.FromContext(ctx).Warn().
+				Str("event", "refresh").
+				Uint("user_id", token.UserID).
+				Str("ip", ip).
+				Str("device_info", deviceInfo).
+				Str("outcome", "reuse_detected").
+				Msg("refresh token reuse detected, family and access tokens revoked")
+			return "", "", ErrRefreshTokenReused
+		}
+
+		// Validate token
+		if !token.IsValid() {
+			return "", "", errors.New("refresh token is expired or revoked")
+		}
+	}
+
+	// Reject tokens that are still valid absolutely but have sat unused
+	// longer than the idle timeout, so a stolen refresh token can't be
+	// replayed weeks later just because its long absolute expiry hasn't
+	// passed yet.
+	if token.IdleExpired(s.refreshIdleTimeout) {
+		return "", "", ErrRefreshTokenIdle
+	}
+
+	newScopes := token.Scopes
+	if len(requestedScopes) > 0 {
+		for _, scope := range requestedScopes {
+			if !token.HasScope(scope) {
+				return "", "", ErrInvalidScope
+			}
+		}
+		newScopes = requestedScopes
 	}
 
 	// Generate new tokens
@@ -260,16 +659,40 @@ func (s *AuthService) RefreshToken(refreshToken, deviceInfo, ip string) (string,
 		return "", "", err
 	}
 
+	// Record that the current token was used right before retiring it, so
+	// its last_used_at reflects this refresh even once it's marked used.
+	// GetRefreshToken leaves token.Token blank (it only scans TokenHash), so
+	// TouchRefreshToken must be looked up by the plaintext refreshToken
+	// parameter, not token.Token. Skip this on a grace-window retry:
+	// TouchRefreshToken requires used = false, but a retry's token is
+	// already used from the rotation it's retrying, and RotateRefreshToken
+	// below re-timestamps it as part of marking it used anyway.
+	now := time.Now()
+	if !retrying {
+		if err := s.tokenRepo.TouchRefreshToken(refreshToken, now); err != nil {
+			return "", "", err
+		}
+	}
+
 	// Rotate refresh token
 	_, err = s.tokenRepo.RotateRefreshToken(
 		token,
 		newRefreshToken,
-		time.Now().Add(s.refreshExpiry),
+		now.Add(s.refreshExpiry),
+		newScopes,
 	)
 	if err != nil {
 		return "", "", err
 	}
 
+	logger.FromContext(ctx).Info().
+		Str("event", "refresh").
+		Uint("user_id", token.UserID).
+		Str("ip", ip).
+		Str("device_info", deviceInfo).
+		Str("outcome", "success").
+		Msg("refresh token rotated")
+
 	return accessToken, newRefreshToken, nil
 }
 
@@ -277,22 +700,249 @@ func (s *AuthService) RevokeToken(refreshToken string) error {
 	return s.tokenRepo.RevokeRefreshToken(refreshToken)
 }
 
-func (s *AuthService) RevokeAllUserTokens(userID uint) error {
-	return s.tokenRepo.RevokeAllUserTokens(userID)
+func (s *AuthService) RevokeAllUserTokens(ctx context.Context, userID uint) error {
+	if err := s.tokenRepo.RevokeAllUserTokens(userID); err != nil {
+		logger.FromContext(ctx).Error().
+			Err(err).
+			Str("event", "revoke_all").
+			Uint("user_id", userID).
+			Str("outcome", "failure").
+			Msg("failed to revoke user's refresh tokens")
+		return err
+	}
+	if s.tokenRevocationSvc != nil {
+		if err := s.tokenRevocationSvc.RevokeAllForUser(userID); err != nil {
+			logger.FromContext(ctx).Error().
+				Err(err).
+				Str("event", "revoke_all").
+				Uint("user_id", userID).
+				Str("outcome", "failure").
+				Msg("failed to revoke user's access tokens")
+			return err
+		}
+	}
+	logger.FromContext(ctx).Info().
+		Str("event", "revoke_all").
+		Uint("user_id", userID).
+		Str("outcome", "success").
+		Msg("all tokens revoked for user")
+	return nil
 }
 
+// LogoutWithRefresh revokes every token descended from the same login as
+// refreshToken, not just the one presented - logging out ends every session
+// spawned by that login, the same scope RevokeAny's refresh-token path
+// already uses.
 func (s *AuthService) LogoutWithRefresh(refreshToken string) error {
-	return s.RevokeToken(refreshToken)
+	token, err := s.tokenRepo.GetRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeFamily(token.ID)
+}
+
+// RevokeAny implements RFC 7009 style revocation: token may be either an
+// access or a refresh token. tokenTypeHint ("access_token" or
+// "refresh_token"), if given, is tried first; either way both token types
+// are attempted, since per the RFC an unrecognized or already-invalid token
+// must not cause an error response.
+func (s *AuthService) RevokeAny(token, tokenTypeHint string) error {
+	revokeAsAccess := func() error {
+		claims, err := s.ValidateToken(token)
+		if err != nil {
+			return err
+		}
+		if s.tokenRevocationSvc == nil || claims.Id == "" {
+			return nil
+		}
+		return s.tokenRevocationSvc.Revoke(claims.Id, time.Unix(claims.ExpiresAt, 0))
+	}
+	revokeAsRefresh := func() error {
+		refreshToken, err := s.tokenRepo.GetRefreshToken(token)
+		if err != nil {
+			return err
+		}
+		// A token presented for explicit revocation (as opposed to one
+		// caught by DetectReuse) is just as likely to mean "this device was
+		// lost" as "this token was compromised", but in both cases every
+		// token descended from the same login should stop working, not
+		// just the one the caller happened to send.
+		return s.tokenRepo.RevokeFamily(refreshToken.ID)
+	}
+
+	if tokenTypeHint == "access_token" {
+		if err := revokeAsAccess(); err == nil {
+			return nil
+		}
+		return revokeAsRefresh()
+	}
+
+	if err := revokeAsRefresh(); err == nil {
+		return nil
+	}
+	return revokeAsAccess()
+}
+
+// RevokeAccessToken lets an operator invalidate a specific access token by
+// jti alone - e.g. one surfaced in logs during an incident - without having
+// the raw token to present to RevokeAny. The token's own exp isn't known
+// from jti alone, so the revocation is held for jwtExpiry from now, the
+// longest any access token issued by this service can still be valid; it
+// expires from Redis the same way a normally-revoked token does.
+func (s *AuthService) RevokeAccessToken(jti string) error {
+	if s.tokenRevocationSvc == nil {
+		return ErrRevocationUnavailable
+	}
+	return s.tokenRevocationSvc.Revoke(jti, time.Now().Add(s.jwtExpiry))
+}
+
+// IntrospectToken implements RFC 7662 style introspection: token may be
+// either an access or a refresh token. An unrecognized, expired, or
+// otherwise invalid token is reported as inactive rather than as an error,
+// per the RFC - callers should treat any returned Introspection as
+// authoritative.
+func (s *AuthService) IntrospectToken(token string) (*models.Introspection, error) {
+	if claims, err := s.ValidateToken(token); err == nil {
+		return &models.Introspection{
+			Active:    true,
+			Subject:   claims.Subject,
+			ExpiresAt: claims.ExpiresAt,
+			IssuedAt:  claims.IssuedAt,
+			ClientID:  claims.Audience,
+			TokenType: "access_token",
+		}, nil
+	}
+
+	refreshToken, err := s.tokenRepo.GetRefreshToken(token)
+	if err != nil || !refreshToken.IsValid() || refreshToken.IdleExpired(s.refreshIdleTimeout) {
+		return &models.Introspection{Active: false}, nil
+	}
+
+	return &models.Introspection{
+		Active:    true,
+		Subject:   fmt.Sprintf("%d", refreshToken.UserID),
+		ExpiresAt: refreshToken.EffectiveExpiresAt(s.refreshIdleTimeout).Unix(),
+		IssuedAt:  refreshToken.CreatedAt.Unix(),
+		TokenType: "refresh_token",
+		Scope:     strings.Join(refreshToken.Scopes, " "),
+	}, nil
+}
+
+// signingMethodForAlg maps a KeyManager key's Alg to the jwt-go signing
+// method that can produce and verify it, defaulting to RS256 for keys
+// predating ES256 support (whose Alg may be empty).
+func signingMethodForAlg(alg string) jwt.SigningMethod {
+	if alg == "ES256" {
+		return jwt.SigningMethodES256
+	}
+	return jwt.SigningMethodRS256
 }
 
 func (s *AuthService) GenerateToken(userID uint) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["user_id"] = userID
-	claims["exp"] = time.Now().Add(s.jwtExpiry).Unix()
-	claims["iat"] = time.Now().Unix()
+	signer, err := s.keyManager.Signer()
+	if err != nil {
+		return "", err
+	}
+
+	// IsAdmin is looked up fresh rather than threaded through from callers
+	// that already have the user, so every GenerateToken call site gets the
+	// claim for free; a lookup failure just omits admin rights rather than
+	// failing an otherwise-valid token issuance.
+	var isAdmin bool
+	if user, err := s.userRepo.FindByID(userID); err == nil {
+		isAdmin = user.IsAdmin
+	}
+
+	jti := uuid.New().String()
+	now := time.Now()
+	expiresAt := now.Add(s.jwtExpiry)
+
+	token := jwt.NewWithClaims(signingMethodForAlg(signer.Alg), &models.TokenClaims{
+		UserID:  userID,
+		IsAdmin: isAdmin,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.tokenIssuer,
+			Audience:  s.tokenAudience,
+			Subject:   fmt.Sprintf("%d", userID),
+			ExpiresAt: expiresAt.Unix(),
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			Id:        jti,
+		},
+	})
+	token.Header["kid"] = signer.Kid
 
-	return token.SignedString([]byte(s.jwtSecret))
+	signed, err := token.SignedString(signer.Key)
+	if err != nil {
+		return "", err
+	}
+
+	if s.tokenRevocationSvc != nil {
+		if err := s.tokenRevocationSvc.TrackIssued(userID, jti, expiresAt); err != nil {
+			return "", err
+		}
+	}
+
+	return signed, nil
+}
+
+// GenerateIDToken issues an OIDC ID token for userID, carrying nonce as
+// supplied to /oauth/authorize so the client can bind it to the
+// authorization request it came from. It is signed and structured exactly
+// like GenerateToken's access token, since this deployment has no separate
+// userinfo-bearing claim set beyond what TokenClaims already carries.
+func (s *AuthService) GenerateIDToken(userID uint, nonce string) (string, error) {
+	signer, err := s.keyManager.Signer()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(signingMethodForAlg(signer.Alg), &models.TokenClaims{
+		UserID: userID,
+		Nonce:  nonce,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.tokenIssuer,
+			Audience:  s.tokenAudience,
+			Subject:   fmt.Sprintf("%d", userID),
+			ExpiresAt: now.Add(s.jwtExpiry).Unix(),
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			Id:        uuid.New().String(),
+		},
+	})
+	token.Header["kid"] = signer.Kid
+
+	return token.SignedString(signer.Key)
+}
+
+// IssueClientCredentialsToken issues an access token for the
+// client_credentials grant: there is no user behind it, so it carries
+// ClientClaims rather than TokenClaims, with clientID as both subject and
+// audience-scoped identity.
+func (s *AuthService) IssueClientCredentialsToken(clientID string, scopes []string) (string, error) {
+	signer, err := s.keyManager.Signer()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(signingMethodForAlg(signer.Alg), &models.ClientClaims{
+		ClientID: clientID,
+		Scope:    strings.Join(scopes, " "),
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.tokenIssuer,
+			Audience:  s.tokenAudience,
+			Subject:   clientID,
+			ExpiresAt: now.Add(s.jwtExpiry).Unix(),
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			Id:        uuid.New().String(),
+		},
+	})
+	token.Header["kid"] = signer.Kid
+
+	return token.SignedString(signer.Key)
 }
 
 func (s *AuthService) GetJWTExpiry() time.Duration {
@@ -302,3 +952,37 @@ func (s *AuthService) GetJWTExpiry() time.Duration {
 func (s *AuthService) GetUserByEmail(email string) (*models.User, error) {
 	return s.userRepo.FindByEmail(email)
 }
+
+func (s *AuthService) GetUserByID(id uint) (*models.User, error) {
+	return s.userRepo.FindByID(id)
+}
+
+// EnrollTOTP generates a TOTP secret and recovery codes for userID, via the
+// MFAService configured with SetMFAService.
+func (s *AuthService) EnrollTOTP(userID uint) (secret, otpauthURL string, recoveryCodes []string, err error) {
+	if s.mfaSvc == nil {
+		return "", "", nil, errors.New("mfa is not configured")
+	}
+	return s.mfaSvc.EnrollTOTP(userID)
+}
+
+// ConfirmTOTP activates MFA for userID, via the MFAService configured with
+// SetMFAService.
+func (s *AuthService) ConfirmTOTP(userID uint, code string) error {
+	if s.mfaSvc == nil {
+		return errors.New("mfa is not configured")
+	}
+	return s.mfaSvc.ConfirmTOTP(userID, code)
+}
+
+// VerifyTOTP checks code against userID's enrolled TOTP secret (or a
+// recovery code), via the MFAService configured with SetMFAService.
+// CompleteMFALogin is the usual caller; it's also exported directly for a
+// caller that has already authenticated the user some other way and just
+// needs to step them up to MFA (e.g. before a sensitive account change).
+func (s *AuthService) VerifyTOTP(userID uint, code string) error {
+	if s.mfaSvc == nil {
+		return errors.New("mfa is not configured")
+	}
+	return s.mfaSvc.VerifyTOTP(userID, code)
+}
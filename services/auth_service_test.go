@@ -2,6 +2,7 @@ package services
 
 import (
 	"EchoAuth/config"
+	"EchoAuth/keys"
 	"EchoAuth/models"
 	"EchoAuth/repositories"
 	"EchoAuth/utils/validator"
@@ -12,9 +13,33 @@ import (
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
+// memKeySetRepository is an in-memory keys.KeySetRepository for tests that
+// need a real RSA-backed KeyManager without touching a database.
+type memKeySetRepository struct {
+	set *keys.PrivateKeySet
+}
+
+func (r *memKeySetRepository) Load() (*keys.PrivateKeySet, error) {
+	return r.set, nil
+}
+
+func (r *memKeySetRepository) Save(set *keys.PrivateKeySet) error {
+	r.set = set
+	return nil
+}
+
+func newMockKeyManager() keys.KeyManager {
+	km, err := keys.NewKeyManager(&memKeySetRepository{}, 24*time.Hour, "RS256")
+	if err != nil {
+		panic(err)
+	}
+	return km
+}
+
 type mockUserRepository struct {
 	users map[uint]*models.User
 }
@@ -49,10 +74,6 @@ func (m *mockUserRepository) FindByID(id uint) (*models.User, error) {
 	return nil, repositories.ErrNotFound
 }
 
-func (m *mockUserRepository) FindByResetToken(token string) (*models.User, error) {
-	return nil, repositories.ErrNotFound
-}
-
 func (m *mockUserRepository) Update(user *models.User) error {
 	if _, exists := m.users[user.ID]; !exists {
 		return repositories.ErrNotFound
@@ -98,13 +119,15 @@ func newMockTokenRepository() repositories.TokenRepositoryInterface {
 	}
 }
 
-func (m *mockTokenRepository) CreateRefreshToken(userID uint, token string, expiresAt time.Time, deviceInfo, ip string) (*models.RefreshToken, error) {
+func (m *mockTokenRepository) CreateRefreshToken(userID uint, token string, expiresAt time.Time, deviceInfo, ip string, scopes []string) (*models.RefreshToken, error) {
 	refreshToken := &models.RefreshToken{
 		UserID:     userID,
 		Token:      token,
 		ExpiresAt:  expiresAt,
 		DeviceInfo: deviceInfo,
 		IP:         ip,
+		Scopes:     scopes,
+		FamilyID:   uuid.New(),
 	}
 	m.tokens[token] = refreshToken
 	return refreshToken, nil
@@ -117,9 +140,11 @@ func (m *mockTokenRepository) GetRefreshToken(token string) (*models.RefreshToke
 	return nil, repositories.ErrNotFound
 }
 
-func (m *mockTokenRepository) RotateRefreshToken(oldToken *models.RefreshToken, newToken string, expiresAt time.Time) (*models.RefreshToken, error) {
+func (m *mockTokenRepository) RotateRefreshToken(oldToken *models.RefreshToken, newToken string, expiresAt time.Time, scopes []string) (*models.RefreshToken, error) {
 	// Mark old token as used
+	now := time.Now()
 	oldToken.Used = true
+	oldToken.UsedAt = &now
 	m.tokens[oldToken.Token] = oldToken
 
 	// Create new token
@@ -129,11 +154,22 @@ func (m *mockTokenRepository) RotateRefreshToken(oldToken *models.RefreshToken,
 		ExpiresAt:  expiresAt,
 		DeviceInfo: oldToken.DeviceInfo,
 		IP:         oldToken.IP,
+		Scopes:     scopes,
+		FamilyID:   oldToken.FamilyID,
 	}
 	m.tokens[newToken] = refreshToken
 	return refreshToken, nil
 }
 
+func (m *mockTokenRepository) TouchRefreshToken(token string, now time.Time) error {
+	t, exists := m.tokens[token]
+	if !exists {
+		return repositories.ErrNotFound
+	}
+	t.LastUsedAt = now
+	return nil
+}
+
 func (m *mockTokenRepository) RevokeRefreshToken(token string) error {
 	if t, exists := m.tokens[token]; exists {
 		now := time.Now()
@@ -155,22 +191,115 @@ func (m *mockTokenRepository) RevokeAllUserTokens(userID uint) error {
 	return nil
 }
 
-func (m *mockTokenRepository) CleanupExpiredTokens() error {
+func (m *mockTokenRepository) RevokeAllFamilyTokens(familyID string) error {
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.FamilyID.String() == familyID {
+			t.RevokedAt = &now
+			m.tokens[t.Token] = t
+		}
+	}
+	return nil
+}
+
+func (m *mockTokenRepository) GetChain(tokenID uuid.UUID) ([]*models.RefreshToken, error) {
+	for _, t := range m.tokens {
+		if t.ID == tokenID {
+			return []*models.RefreshToken{t}, nil
+		}
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (m *mockTokenRepository) RevokeFamily(tokenID uuid.UUID) error {
+	var familyID uuid.UUID
+	found := false
+	for _, t := range m.tokens {
+		if t.ID == tokenID {
+			familyID = t.FamilyID
+			found = true
+			break
+		}
+	}
+	if !found {
+		return repositories.ErrNotFound
+	}
+	return m.RevokeAllFamilyTokens(familyID.String())
+}
+
+func (m *mockTokenRepository) DetectReuse(token *models.RefreshToken) (bool, error) {
+	if !token.Used && token.RevokedAt == nil {
+		return false, nil
+	}
+	return true, m.RevokeAllFamilyTokens(token.FamilyID.String())
+}
+
+func (m *mockTokenRepository) CleanupExpiredTokens(idleTimeout time.Duration) error {
+	now := time.Now()
 	for token, t := range m.tokens {
-		if t.ExpiresAt.Before(time.Now()) || t.Used || t.RevokedAt != nil {
+		idleExpired := idleTimeout > 0 && t.LastUsedAt.Before(now.Add(-idleTimeout))
+		if t.ExpiresAt.Before(now) || t.Used || t.RevokedAt != nil || idleExpired {
 			delete(m.tokens, token)
 		}
 	}
 	return nil
 }
 
+func (m *mockTokenRepository) ListActiveSessions(userID uint) ([]*models.Session, error) {
+	var sessions []*models.Session
+	for _, t := range m.tokens {
+		if t.UserID == userID && t.IsValid() {
+			sessions = append(sessions, &models.Session{
+				ID:         t.ID,
+				DeviceInfo: t.DeviceInfo,
+				IP:         t.IP,
+				CreatedAt:  t.CreatedAt,
+				LastUsedAt: t.LastUsedAt,
+			})
+		}
+	}
+	return sessions, nil
+}
+
+func (m *mockTokenRepository) RevokeSession(userID uint, sessionID uuid.UUID) error {
+	for _, t := range m.tokens {
+		if t.UserID == userID && t.ID == sessionID {
+			now := time.Now()
+			t.RevokedAt = &now
+			return nil
+		}
+	}
+	return repositories.ErrNotFound
+}
+
+func (m *mockTokenRepository) RevokeAllExcept(userID uint, keepToken string) error {
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.UserID == userID && t.Token != keepToken && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *mockTokenRepository) CountActiveFamilies() (int, error) {
+	families := make(map[uuid.UUID]struct{})
+	for _, t := range m.tokens {
+		if t.IsValid() {
+			families[t.FamilyID] = struct{}{}
+		}
+	}
+	return len(families), nil
+}
+
 func TestAuthServiceRegister(t *testing.T) {
 	repo := newMockUserRepository()
 	tokenRepo := repositories.NewTokenRepository(nil)
 	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiry: 24 * time.Hour}
 	lockoutService := newMockAccountLockoutService()
 	redisClient := newMockRedis()
-	service := NewAuthService(repo, tokenRepo, cfg, lockoutService, redisClient)
+	keyManager := newMockKeyManager()
+	service := NewAuthService(repo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
 
 	tests := []struct {
 		name      string
@@ -183,7 +312,7 @@ func TestAuthServiceRegister(t *testing.T) {
 		{
 			name:      "Valid registration",
 			email:     "test@example.com",
-			password:  "Password123!",
+			password:  "xK9$mQ2#vL7&wP4!",
 			firstName: "John",
 			lastName:  "Doe",
 			wantErr:   nil,
@@ -191,7 +320,7 @@ func TestAuthServiceRegister(t *testing.T) {
 		{
 			name:      "Empty email",
 			email:     "",
-			password:  "Password123!",
+			password:  "xK9$mQ2#vL7&wP4!",
 			firstName: "John",
 			lastName:  "Doe",
 			wantErr:   validator.ErrEmailEmpty,
@@ -199,7 +328,7 @@ func TestAuthServiceRegister(t *testing.T) {
 		{
 			name:      "Invalid email format",
 			email:     "invalid-email",
-			password:  "Password123!",
+			password:  "xK9$mQ2#vL7&wP4!",
 			firstName: "John",
 			lastName:  "Doe",
 			wantErr:   validator.ErrEmailInvalid,
@@ -207,7 +336,7 @@ func TestAuthServiceRegister(t *testing.T) {
 		{
 			name:      "Invalid email domain",
 			email:     "test@.com",
-			password:  "Password123!",
+			password:  "xK9$mQ2#vL7&wP4!",
 			firstName: "John",
 			lastName:  "Doe",
 			wantErr:   validator.ErrDomainInvalid,
@@ -231,7 +360,7 @@ func TestAuthServiceRegister(t *testing.T) {
 		{
 			name:      "Common password",
 			email:     "test@example.com",
-			password:  "password123",
+			password:  "Password1!",
 			firstName: "John",
 			lastName:  "Doe",
 			wantErr:   validator.ErrPasswordCommon,
@@ -239,7 +368,7 @@ func TestAuthServiceRegister(t *testing.T) {
 		{
 			name:      "User already exists",
 			email:     "existing@example.com",
-			password:  "Password123!",
+			password:  "xK9$mQ2#vL7&wP4!",
 			firstName: "John",
 			lastName:  "Doe",
 			wantErr:   ErrUserExists,
@@ -249,7 +378,7 @@ func TestAuthServiceRegister(t *testing.T) {
 	// Create an existing user for the "User already exists" test
 	existingUser := &models.User{
 		Email:     "existing@example.com",
-		Password:  "Password123!",
+		Password:  "xK9$mQ2#vL7&wP4!",
 		FirstName: "John",
 		LastName:  "Doe",
 	}
@@ -257,7 +386,7 @@ func TestAuthServiceRegister(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := service.Register(tt.email, tt.password, tt.firstName, tt.lastName)
+			err := service.Register(context.Background(), tt.email, tt.password, tt.firstName, tt.lastName)
 			if !errors.Is(err, tt.wantErr) {
 				t.Errorf("AuthService.Register() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -271,7 +400,8 @@ func TestAuthServiceLogin(t *testing.T) {
 	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiry: 24 * time.Hour}
 	lockoutService := newMockAccountLockoutService()
 	redisClient := newMockRedis()
-	service := NewAuthService(repo, tokenRepo, cfg, lockoutService, redisClient)
+	keyManager := newMockKeyManager()
+	service := NewAuthService(repo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
 
 	// Create a test user
 	testUser := &models.User{
@@ -329,7 +459,13 @@ func TestAuthServiceValidateToken(t *testing.T) {
 	cfg := &config.Config{JWTSecret: "test-secret", JWTExpiry: 24 * time.Hour}
 	lockoutService := newMockAccountLockoutService()
 	redisClient := newMockRedis()
-	service := NewAuthService(repo, tokenRepo, cfg, lockoutService, redisClient)
+	keyManager := newMockKeyManager()
+	service := NewAuthService(repo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
+
+	signer, err := keyManager.Signer()
+	if err != nil {
+		t.Fatalf("Signer() error = %v", err)
+	}
 
 	// Create a valid token
 	claims := &models.TokenClaims{
@@ -339,8 +475,9 @@ func TestAuthServiceValidateToken(t *testing.T) {
 			IssuedAt:  time.Now().Unix(),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	validToken, _ := token.SignedString([]byte(cfg.JWTSecret))
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = signer.Kid
+	validToken, _ := token.SignedString(signer.Key)
 
 	// Create an expired token
 	expiredClaims := &models.TokenClaims{
@@ -350,8 +487,9 @@ func TestAuthServiceValidateToken(t *testing.T) {
 			IssuedAt:  time.Now().Add(-time.Hour * 2).Unix(),
 		},
 	}
-	expiredToken := jwt.NewWithClaims(jwt.SigningMethodHS256, expiredClaims)
-	expiredTokenString, _ := expiredToken.SignedString([]byte(cfg.JWTSecret))
+	expiredToken := jwt.NewWithClaims(jwt.SigningMethodRS256, expiredClaims)
+	expiredToken.Header["kid"] = signer.Kid
+	expiredTokenString, _ := expiredToken.SignedString(signer.Key)
 
 	tests := []struct {
 		name    string
@@ -403,7 +541,8 @@ func TestAuthServiceLoginWithRefresh(t *testing.T) {
 	}
 	lockoutService := newMockAccountLockoutService()
 	redisClient := newMockRedis()
-	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient)
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
 
 	// Create a test user
 	testUser := &models.User{
@@ -451,7 +590,7 @@ func TestAuthServiceLoginWithRefresh(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			accessToken, refreshToken, err := service.LoginWithRefresh(tt.email, tt.password, tt.deviceInfo, tt.ip)
+			accessToken, refreshToken, err := service.LoginWithRefresh(context.Background(), tt.email, tt.password, tt.deviceInfo, tt.ip, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("AuthService.LoginWithRefresh() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -477,7 +616,8 @@ func TestAuthServiceRefreshToken(t *testing.T) {
 	}
 	lockoutService := newMockAccountLockoutService()
 	redisClient := newMockRedis()
-	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient)
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
 
 	// Create a test user
 	testUser := &models.User{
@@ -496,6 +636,7 @@ func TestAuthServiceRefreshToken(t *testing.T) {
 		ExpiresAt:  time.Now().Add(24 * time.Hour),
 		DeviceInfo: "Chrome on macOS",
 		IP:         "127.0.0.1",
+		Scopes:     []string{"read", "write"},
 	}
 	tokenRepo.(*mockTokenRepository).tokens[validToken.Token] = validToken
 
@@ -526,6 +667,7 @@ func TestAuthServiceRefreshToken(t *testing.T) {
 		token      string
 		deviceInfo string
 		ip         string
+		scopes     []string
 		wantErr    bool
 	}{
 		{
@@ -560,7 +702,7 @@ func TestAuthServiceRefreshToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			accessToken, newRefreshToken, err := service.RefreshToken(tt.token, tt.deviceInfo, tt.ip)
+			accessToken, newRefreshToken, err := service.RefreshToken(context.Background(), tt.token, tt.deviceInfo, tt.ip, tt.scopes)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("AuthService.RefreshToken() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -580,6 +722,247 @@ func TestAuthServiceRefreshToken(t *testing.T) {
 	}
 }
 
+func TestAuthServiceRefreshTokenScopeDownscoping(t *testing.T) {
+	userRepo := newMockUserRepository()
+	tokenRepo := newMockTokenRepository()
+	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		JWTExpiry: 24 * time.Hour,
+	}
+	lockoutService := newMockAccountLockoutService()
+	redisClient := newMockRedis()
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
+
+	grantedToken := &models.RefreshToken{
+		UserID:     1,
+		Token:      "granted-token",
+		ExpiresAt:  time.Now().Add(24 * time.Hour),
+		DeviceInfo: "Chrome on macOS",
+		IP:         "127.0.0.1",
+		Scopes:     []string{"read", "write"},
+	}
+
+	tests := []struct {
+		name          string
+		requestScopes []string
+		wantErr       error
+		wantNewScopes []string
+	}{
+		{
+			name:          "empty request inherits full scope",
+			requestScopes: nil,
+			wantNewScopes: []string{"read", "write"},
+		},
+		{
+			name:          "exact match is accepted",
+			requestScopes: []string{"read", "write"},
+			wantNewScopes: []string{"read", "write"},
+		},
+		{
+			name:          "valid subset is accepted",
+			requestScopes: []string{"read"},
+			wantNewScopes: []string{"read"},
+		},
+		{
+			name:          "scope not on the token is rejected",
+			requestScopes: []string{"read", "admin"},
+			wantErr:       ErrInvalidScope,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenRepo.(*mockTokenRepository).tokens[grantedToken.Token] = &models.RefreshToken{
+				UserID:     grantedToken.UserID,
+				Token:      grantedToken.Token,
+				ExpiresAt:  grantedToken.ExpiresAt,
+				DeviceInfo: grantedToken.DeviceInfo,
+				IP:         grantedToken.IP,
+				Scopes:     grantedToken.Scopes,
+			}
+
+			_, newRefreshToken, err := service.RefreshToken(context.Background(), grantedToken.Token, grantedToken.DeviceInfo, grantedToken.IP, tt.requestScopes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("AuthService.RefreshToken() error = %v, want %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil {
+				rotated, _ := tokenRepo.(*mockTokenRepository).GetRefreshToken(newRefreshToken)
+				if rotated == nil {
+					t.Fatal("expected rotated token to be stored")
+				}
+				if !equalScopes(rotated.Scopes, tt.wantNewScopes) {
+					t.Errorf("rotated token scopes = %v, want %v", rotated.Scopes, tt.wantNewScopes)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthServiceRefreshTokenIdleTimeout(t *testing.T) {
+	userRepo := newMockUserRepository()
+	tokenRepo := newMockTokenRepository()
+	cfg := &config.Config{
+		JWTSecret:          "test-secret",
+		JWTExpiry:          24 * time.Hour,
+		RefreshIdleTimeout: time.Hour,
+	}
+	lockoutService := newMockAccountLockoutService()
+	redisClient := newMockRedis()
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
+
+	tests := []struct {
+		name       string
+		lastUsedAt time.Time
+		wantErr    error
+	}{
+		{
+			name:       "recently used token is accepted",
+			lastUsedAt: time.Now().Add(-time.Minute),
+		},
+		{
+			name:       "token unused past the idle timeout is rejected",
+			lastUsedAt: time.Now().Add(-2 * time.Hour),
+			wantErr:    ErrRefreshTokenIdle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenRepo.(*mockTokenRepository).tokens["idle-test-token"] = &models.RefreshToken{
+				UserID:     1,
+				Token:      "idle-test-token",
+				ExpiresAt:  time.Now().Add(24 * time.Hour),
+				DeviceInfo: "Chrome on macOS",
+				IP:         "127.0.0.1",
+				LastUsedAt: tt.lastUsedAt,
+			}
+
+			_, _, err := service.RefreshToken(context.Background(), "idle-test-token", "Chrome on macOS", "127.0.0.1", nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("AuthService.RefreshToken() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthServiceRefreshTokenReuseDetection(t *testing.T) {
+	userRepo := newMockUserRepository()
+	tokenRepo := newMockTokenRepository()
+	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		JWTExpiry: 24 * time.Hour,
+	}
+	lockoutService := newMockAccountLockoutService()
+	redisClient := newMockRedis()
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
+
+	familyID := uuid.New()
+	original := &models.RefreshToken{
+		UserID:     1,
+		Token:      "original-token",
+		ExpiresAt:  time.Now().Add(24 * time.Hour),
+		DeviceInfo: "Chrome on macOS",
+		IP:         "127.0.0.1",
+		FamilyID:   familyID,
+	}
+	tokenRepo.(*mockTokenRepository).tokens[original.Token] = original
+
+	// Rotate once, as a legitimate client would.
+	_, rotatedToken, err := service.RefreshToken(context.Background(), original.Token, original.DeviceInfo, original.IP, nil)
+	if err != nil {
+		t.Fatalf("first RefreshToken() failed: %v", err)
+	}
+
+	// Push the rotation outside refreshReuseGraceWindow, so the replay below
+	// exercises reuse detection rather than the legitimate-retry allowance.
+	staleUsedAt := time.Now().Add(-refreshReuseGraceWindow - time.Second)
+	original.UsedAt = &staleUsedAt
+
+	// Present the original token again, simulating an attacker replaying an
+	// intercepted refresh token.
+	_, _, err = service.RefreshToken(context.Background(), original.Token, original.DeviceInfo, original.IP, nil)
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("second RefreshToken() error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	// Every descendant in the family, including the one the attacker was
+	// never able to use, must now be revoked.
+	for _, token := range []string{original.Token, rotatedToken} {
+		stored, err := tokenRepo.(*mockTokenRepository).GetRefreshToken(token)
+		if err != nil {
+			t.Fatalf("GetRefreshToken(%q) failed: %v", token, err)
+		}
+		if stored.RevokedAt == nil {
+			t.Errorf("token %q: expected RevokedAt to be set after reuse detection", token)
+		}
+	}
+}
+
+func TestAuthServiceRefreshTokenGraceWindowRetry(t *testing.T) {
+	userRepo := newMockUserRepository()
+	tokenRepo := newMockTokenRepository()
+	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		JWTExpiry: 24 * time.Hour,
+	}
+	lockoutService := newMockAccountLockoutService()
+	redisClient := newMockRedis()
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
+
+	original := &models.RefreshToken{
+		UserID:     1,
+		Token:      "original-token",
+		ExpiresAt:  time.Now().Add(24 * time.Hour),
+		DeviceInfo: "Chrome on macOS",
+		IP:         "127.0.0.1",
+		FamilyID:   uuid.New(),
+	}
+	tokenRepo.(*mockTokenRepository).tokens[original.Token] = original
+
+	_, firstRotation, err := service.RefreshToken(context.Background(), original.Token, original.DeviceInfo, original.IP, nil)
+	if err != nil {
+		t.Fatalf("first RefreshToken() failed: %v", err)
+	}
+
+	// Retry with the same (now used) token immediately, as a client would
+	// after losing the first response. It must succeed rather than trip
+	// reuse detection, and the family must remain intact.
+	_, secondRotation, err := service.RefreshToken(context.Background(), original.Token, original.DeviceInfo, original.IP, nil)
+	if err != nil {
+		t.Fatalf("retry within grace window failed: %v", err)
+	}
+	if secondRotation == firstRotation {
+		t.Error("expected the retry to mint a fresh refresh token")
+	}
+
+	for _, token := range []string{original.Token, firstRotation, secondRotation} {
+		stored, err := tokenRepo.(*mockTokenRepository).GetRefreshToken(token)
+		if err != nil {
+			t.Fatalf("GetRefreshToken(%q) failed: %v", token, err)
+		}
+		if stored.RevokedAt != nil {
+			t.Errorf("token %q: expected the family to survive a grace-window retry", token)
+		}
+	}
+}
+
+func equalScopes(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func TestAuthServiceRevokeToken(t *testing.T) {
 	userRepo := newMockUserRepository()
 	tokenRepo := newMockTokenRepository()
@@ -589,7 +972,8 @@ func TestAuthServiceRevokeToken(t *testing.T) {
 	}
 	lockoutService := newMockAccountLockoutService()
 	redisClient := newMockRedis()
-	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient)
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
 
 	// Create a valid refresh token
 	validToken := &models.RefreshToken{
@@ -634,3 +1018,132 @@ func TestAuthServiceRevokeToken(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthServiceAccessTokenRevocation(t *testing.T) {
+	userRepo := newMockUserRepository()
+	tokenRepo := newMockTokenRepository()
+	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		JWTExpiry: 24 * time.Hour,
+	}
+	lockoutService := newMockAccountLockoutService()
+	redisClient := newMockRedis()
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
+	service.SetTokenRevocationService(NewTokenRevocationService(redisClient))
+
+	accessToken, err := service.GenerateToken(1)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	if _, err := service.ValidateToken(accessToken); err != nil {
+		t.Fatalf("ValidateToken() before revocation error = %v", err)
+	}
+
+	if err := service.RevokeAny(accessToken, "access_token"); err != nil {
+		t.Fatalf("RevokeAny() error = %v", err)
+	}
+
+	if _, err := service.ValidateToken(accessToken); !errors.Is(err, ErrTokenBlacklisted) {
+		t.Errorf("ValidateToken() after revocation error = %v, want %v", err, ErrTokenBlacklisted)
+	}
+}
+
+func TestAuthServiceRevokeAnyRefreshTokenCascadesToWholeFamily(t *testing.T) {
+	userRepo := newMockUserRepository()
+	tokenRepo := newMockTokenRepository()
+	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		JWTExpiry: 24 * time.Hour,
+	}
+	lockoutService := newMockAccountLockoutService()
+	redisClient := newMockRedis()
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
+
+	original, err := tokenRepo.CreateRefreshToken(1, "original-token", time.Now().Add(time.Hour), "device", "127.0.0.1", nil)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	rotated, err := tokenRepo.RotateRefreshToken(original, "rotated-token", time.Now().Add(time.Hour), nil)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken() error = %v", err)
+	}
+
+	// Revoking the rotated (current) token must also kill the original it
+	// descended from, since both belong to the same family and a caller
+	// revoking one of them is treating the whole chain as compromised.
+	if err := service.RevokeAny("rotated-token", "refresh_token"); err != nil {
+		t.Fatalf("RevokeAny() error = %v", err)
+	}
+
+	if original.RevokedAt == nil {
+		t.Error("expected the original token's family member to be revoked")
+	}
+	if rotated.RevokedAt == nil {
+		t.Error("expected the presented token to be revoked")
+	}
+}
+
+func TestAuthServiceLogoutWithRefreshCascadesToWholeFamily(t *testing.T) {
+	userRepo := newMockUserRepository()
+	tokenRepo := newMockTokenRepository()
+	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		JWTExpiry: 24 * time.Hour,
+	}
+	lockoutService := newMockAccountLockoutService()
+	redisClient := newMockRedis()
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
+
+	original, err := tokenRepo.CreateRefreshToken(1, "original-token", time.Now().Add(time.Hour), "device", "127.0.0.1", nil)
+	if err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+	rotated, err := tokenRepo.RotateRefreshToken(original, "rotated-token", time.Now().Add(time.Hour), nil)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken() error = %v", err)
+	}
+
+	// Logging out with the current token must also kill the original it
+	// descended from, not just the one presented.
+	if err := service.LogoutWithRefresh("rotated-token"); err != nil {
+		t.Fatalf("LogoutWithRefresh() error = %v", err)
+	}
+
+	if original.RevokedAt == nil {
+		t.Error("expected the original token's family member to be revoked")
+	}
+	if rotated.RevokedAt == nil {
+		t.Error("expected the presented token to be revoked")
+	}
+}
+
+func TestAuthServiceRevokeAllUserTokensRevokesOutstandingAccessTokens(t *testing.T) {
+	userRepo := newMockUserRepository()
+	tokenRepo := newMockTokenRepository()
+	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		JWTExpiry: 24 * time.Hour,
+	}
+	lockoutService := newMockAccountLockoutService()
+	redisClient := newMockRedis()
+	keyManager := newMockKeyManager()
+	service := NewAuthService(userRepo, tokenRepo, cfg, lockoutService, redisClient, keyManager)
+	service.SetTokenRevocationService(NewTokenRevocationService(redisClient))
+
+	accessToken, err := service.GenerateToken(1)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	if err := service.RevokeAllUserTokens(context.Background(), 1); err != nil {
+		t.Fatalf("RevokeAllUserTokens() error = %v", err)
+	}
+
+	if _, err := service.ValidateToken(accessToken); !errors.Is(err, ErrTokenBlacklisted) {
+		t.Errorf("ValidateToken() after RevokeAllUserTokens error = %v, want %v", err, ErrTokenBlacklisted)
+	}
+}
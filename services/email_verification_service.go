@@ -0,0 +1,113 @@
+package services
+
+import (
+	"EchoAuth/mailer"
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+	"EchoAuth/utils/validator"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const emailVerificationTokenTTL = 24 * time.Hour
+
+type EmailVerificationService struct {
+	userRepo        repositories.UserRepository
+	actionTokenRepo repositories.ActionTokenRepository
+	mailer          mailer.Mailer
+	publicURL       string
+}
+
+func NewEmailVerificationService(userRepo repositories.UserRepository, actionTokenRepo repositories.ActionTokenRepository, mailer mailer.Mailer, publicURL string) *EmailVerificationService {
+	return &EmailVerificationService{
+		userRepo:        userRepo,
+		actionTokenRepo: actionTokenRepo,
+		mailer:          mailer,
+		publicURL:       publicURL,
+	}
+}
+
+// GenerateVerificationToken creates a verification token for the user with
+// the given email and emails them a link to confirm it.
+func (s *EmailVerificationService) GenerateVerificationToken(email string) (string, error) {
+	if err := validator.ValidateEmail(email); err != nil {
+		return "", err
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return "", errors.New("user not found")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	actionToken := &models.ActionToken{
+		UserID:    user.ID,
+		Token:     token,
+		Purpose:   models.TokenPurposeVerify,
+		ExpiresAt: time.Now().Add(emailVerificationTokenTTL),
+	}
+	if err := s.actionTokenRepo.Create(actionToken); err != nil {
+		return "", err
+	}
+
+	verifyURL := fmt.Sprintf("%s/verify-email?token=%s", s.publicURL, token)
+	if err := s.mailer.SendEmailVerification(email, verifyURL); err != nil {
+		log.Printf("Failed to send email verification email: %v", err)
+	}
+
+	return token, nil
+}
+
+// ValidateVerificationToken checks if the verification token is valid and
+// not expired.
+func (s *EmailVerificationService) ValidateVerificationToken(token string) (*models.User, error) {
+	if token == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	actionToken, err := s.actionTokenRepo.FindByToken(token, models.TokenPurposeVerify)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	if actionToken.Expired(time.Now()) {
+		return nil, errors.New("token expired")
+	}
+
+	user, err := s.userRepo.FindByID(actionToken.UserID)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	return user, nil
+}
+
+// ConfirmEmail marks the user's email as verified. The token is consumed
+// atomically before the user record is touched, so two concurrent requests
+// racing on the same link can't both succeed.
+func (s *EmailVerificationService) ConfirmEmail(token string) error {
+	actionToken, err := s.actionTokenRepo.Consume(token, models.TokenPurposeVerify)
+	if err != nil {
+		return errors.New("invalid token")
+	}
+	if actionToken.Expired(time.Now()) {
+		return errors.New("token expired")
+	}
+
+	user, err := s.userRepo.FindByID(actionToken.UserID)
+	if err != nil {
+		return errors.New("invalid token")
+	}
+
+	user.EmailVerified = true
+	return s.userRepo.Update(user)
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"EchoAuth/mailer"
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+	"EchoAuth/utils/validator"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const defaultInvitationTokenTTL = 7 * 24 * time.Hour
+
+var ErrAlreadyInvited = errors.New("user already exists")
+
+type InvitationService struct {
+	userRepo        repositories.UserRepository
+	actionTokenRepo repositories.ActionTokenRepository
+	mailer          mailer.Mailer
+	publicURL       string
+}
+
+func NewInvitationService(userRepo repositories.UserRepository, actionTokenRepo repositories.ActionTokenRepository, mailer mailer.Mailer, publicURL string) *InvitationService {
+	return &InvitationService{
+		userRepo:        userRepo,
+		actionTokenRepo: actionTokenRepo,
+		mailer:          mailer,
+		publicURL:       publicURL,
+	}
+}
+
+// CreateInvitation creates a placeholder user record for email and emails
+// them a link to accept the invitation and set their own password. The
+// placeholder's password is an unusable random value until AcceptInvitation
+// is called. If email already belongs to a user who never accepted a prior
+// invitation (EmailVerified is still false), a fresh invitation is issued to
+// that same placeholder user instead of failing, so a mistyped or lost
+// invite can simply be resent by inviting the address again. roles is
+// stashed on the invite token's payload so AcceptInvitation can read it back
+// once a future authorization scheme is ready to enforce it; it isn't
+// enforced anywhere yet. A zero ttl falls back to defaultInvitationTokenTTL.
+func (s *InvitationService) CreateInvitation(email string, roles []string, ttl time.Duration) (string, error) {
+	if err := validator.ValidateEmail(email); err != nil {
+		return "", err
+	}
+
+	existing, err := s.userRepo.FindByEmail(email)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return "", err
+	}
+
+	if existing != nil {
+		if existing.EmailVerified {
+			return "", ErrAlreadyInvited
+		}
+		return s.issueInvitation(existing, roles, ttl)
+	}
+
+	placeholderPassword, err := s.newToken()
+	if err != nil {
+		return "", err
+	}
+
+	user := &models.User{
+		Email: email,
+	}
+	if err := user.HashPassword(placeholderPassword); err != nil {
+		return "", err
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return "", err
+	}
+
+	return s.issueInvitation(user, roles, ttl)
+}
+
+// issueInvitation creates a new invite ActionToken for user and emails it to
+// them. Used both for brand-new invitees and for resending an invitation to
+// an existing but still-unverified placeholder user.
+func (s *InvitationService) issueInvitation(user *models.User, roles []string, ttl time.Duration) (string, error) {
+	token, err := s.newToken()
+	if err != nil {
+		return "", err
+	}
+
+	if ttl <= 0 {
+		ttl = defaultInvitationTokenTTL
+	}
+	actionToken := &models.ActionToken{
+		UserID:    user.ID,
+		Token:     token,
+		Purpose:   models.TokenPurposeInvite,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if len(roles) > 0 {
+		actionToken.Payload = map[string]string{"roles": strings.Join(roles, ",")}
+	}
+	if err := s.actionTokenRepo.Create(actionToken); err != nil {
+		return "", err
+	}
+
+	inviteURL := fmt.Sprintf("%s/invitations/%s", s.publicURL, token)
+	if err := s.mailer.SendInvitation(user.Email, inviteURL); err != nil {
+		log.Printf("Failed to send invitation email: %v", err)
+	}
+
+	return token, nil
+}
+
+// ValidateInvitation checks if the invitation token is valid and not expired.
+func (s *InvitationService) ValidateInvitation(token string) (*models.User, error) {
+	if token == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	actionToken, err := s.actionTokenRepo.FindByToken(token, models.TokenPurposeInvite)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	if actionToken.Expired(time.Now()) {
+		return nil, errors.New("token expired")
+	}
+
+	user, err := s.userRepo.FindByID(actionToken.UserID)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	return user, nil
+}
+
+// AcceptInvitation sets the invited user's name and chosen password, and
+// marks their email verified (the invitation link already proved receipt).
+// The token is consumed atomically right before the user record is
+// touched - after password validation, so a rejected password doesn't burn
+// the invite - so two concurrent requests racing on the same link can't
+// both succeed.
+func (s *InvitationService) AcceptInvitation(token, password, firstName, lastName string) error {
+	user, err := s.ValidateInvitation(token)
+	if err != nil {
+		return err
+	}
+
+	if err := validator.ValidatePasswordForUser(password, []string{user.Email, firstName, lastName}); err != nil {
+		return err
+	}
+
+	if _, err := s.actionTokenRepo.Consume(token, models.TokenPurposeInvite); err != nil {
+		return errors.New("invalid token")
+	}
+
+	if err := user.HashPassword(password); err != nil {
+		return err
+	}
+	user.FirstName = firstName
+	user.LastName = lastName
+	user.EmailVerified = true
+	user.RecordPasswordChange(time.Now(), 0)
+
+	return s.userRepo.Update(user)
+}
+
+func (s *InvitationService) newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPCredentials is the credentials type LDAPScheme expects from Login and
+// Create: the uid (or other configured naming attribute) and password a
+// client submits, as opposed to the DN LDAPDirectory itself resolves that
+// uid to before binding.
+type LDAPCredentials struct {
+	Username string
+	Password string
+}
+
+// LDAPIdentity is what an LDAPDirectory resolves a successful bind to: the
+// entry's DN (stored as the RemoteIdentity's SubjectID, since a directory's
+// uid is reassignable but its DN isn't) and the profile fields used to
+// provision a local user on first login.
+type LDAPIdentity struct {
+	DN        string
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+// LDAPDirectory authenticates a username/password pair against a directory
+// server, so LDAPScheme itself doesn't need to know whether that's done by
+// binding directly as the user's DN or by a search-then-bind against a
+// service account - see NewLDAPDirectory for the search-then-bind
+// implementation this package ships.
+type LDAPDirectory interface {
+	Authenticate(ctx context.Context, username, password string) (*LDAPIdentity, error)
+}
+
+// ErrLDAPInvalidCredentials is returned by LDAPDirectory.Authenticate (and
+// surfaced unchanged by LDAPScheme.Login) when the directory rejects the
+// bind - an unknown username or a wrong password are indistinguishable on
+// purpose, the same way ErrInvalidCredentials doesn't distinguish them for
+// NativeScheme.
+var ErrLDAPInvalidCredentials = errors.New("invalid LDAP username or password")
+
+// LDAPScheme logs a user in (or links/creates one on first login) by
+// authenticating against an external LDAP directory rather than a locally
+// stored password, identifying the account by a models.RemoteIdentity keyed
+// on the entry's DN - the same linking pattern OAuthScheme uses for a
+// federated provider's subject ID.
+type LDAPScheme struct {
+	directory          LDAPDirectory
+	userRepo           repositories.UserRepository
+	remoteIdentityRepo repositories.RemoteIdentityRepository
+}
+
+func NewLDAPScheme(directory LDAPDirectory, userRepo repositories.UserRepository, remoteIdentityRepo repositories.RemoteIdentityRepository) *LDAPScheme {
+	return &LDAPScheme{
+		directory:          directory,
+		userRepo:           userRepo,
+		remoteIdentityRepo: remoteIdentityRepo,
+	}
+}
+
+func (s *LDAPScheme) Name() string {
+	return "ldap"
+}
+
+// Login binds credentials.(LDAPCredentials) against the directory and
+// resolves the result to a local user, linking a new RemoteIdentity to a
+// matching-email user, or creating one, on first login.
+func (s *LDAPScheme) Login(ctx context.Context, credentials interface{}) (*models.User, error) {
+	creds, ok := credentials.(LDAPCredentials)
+	if !ok {
+		return nil, ErrInvalidCredentialsType
+	}
+
+	identity, err := s.directory.Authenticate(ctx, creds.Username, creds.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteIdentity, err := s.remoteIdentityRepo.FindByProviderAndSubject(s.Name(), identity.DN)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			return nil, err
+		}
+		return s.linkOrCreateUser(identity)
+	}
+	return s.userRepo.FindByID(remoteIdentity.UserID)
+}
+
+func (s *LDAPScheme) linkOrCreateUser(identity *LDAPIdentity) (*models.User, error) {
+	user, err := s.userRepo.FindByEmail(identity.Email)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			return nil, err
+		}
+		user = &models.User{
+			Email:     identity.Email,
+			FirstName: identity.FirstName,
+			LastName:  identity.LastName,
+		}
+		if err := s.userRepo.Create(user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.remoteIdentityRepo.Create(&models.RemoteIdentity{
+		UserID:    user.ID,
+		Provider:  s.Name(),
+		SubjectID: identity.DN,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Create links credentials.(LDAPCredentials) to user.ID, for a user who is
+// already authenticated and wants to add directory login as an additional
+// way to sign in, rather than have the resolved entry matched or
+// auto-provisioned against a different account.
+func (s *LDAPScheme) Create(user *models.User, credentials interface{}) (*models.User, error) {
+	creds, ok := credentials.(LDAPCredentials)
+	if !ok {
+		return nil, ErrInvalidCredentialsType
+	}
+
+	identity, err := s.directory.Authenticate(context.Background(), creds.Username, creds.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.remoteIdentityRepo.Create(&models.RemoteIdentity{
+		UserID:    user.ID,
+		Provider:  s.Name(),
+		SubjectID: identity.DN,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *LDAPScheme) Remove(user *models.User) error {
+	return s.remoteIdentityRepo.Delete(user.ID, s.Name())
+}
+
+// ldapDirectory is the search-then-bind LDAPDirectory this package ships:
+// it binds as a service account to search for the entry matching username,
+// then re-binds as that entry's own DN with the supplied password to verify
+// it - the standard pattern for directories (e.g. Active Directory) where
+// the login name isn't the DN itself.
+type ldapDirectory struct {
+	url            string
+	bindDN         string
+	bindPassword   string
+	userSearchBase string
+	userFilter     string
+}
+
+// NewLDAPDirectory builds an LDAPDirectory that dials url fresh for every
+// Authenticate call, binds as bindDN/bindPassword to search userSearchBase
+// for an entry matching userFilter (a filter template with one %s for the
+// submitted username, e.g. "(uid=%s)"), and binds as the result to verify
+// the password.
+func NewLDAPDirectory(url, bindDN, bindPassword, userSearchBase, userFilter string) LDAPDirectory {
+	return &ldapDirectory{
+		url:            url,
+		bindDN:         bindDN,
+		bindPassword:   bindPassword,
+		userSearchBase: userSearchBase,
+		userFilter:     userFilter,
+	}
+}
+
+func (d *ldapDirectory) Authenticate(ctx context.Context, username, password string) (*LDAPIdentity, error) {
+	conn, err := ldap.DialURL(d.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP directory: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(d.bindDN, d.bindPassword); err != nil {
+		return nil, fmt.Errorf("failed to bind service account: %w", err)
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		d.userSearchBase,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(d.userFilter, ldap.EscapeFilter(username)),
+		[]string{"mail", "givenName", "sn"},
+		nil,
+	)
+	result, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search LDAP directory: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	// An empty password makes this an RFC 4513 §5.1.2 "unauthenticated
+	// bind", which most directories (OpenLDAP, AD) happily accept
+	// regardless of entry.DN's real password - reject it before Bind so a
+	// known or guessed uid can't log in with no password at all.
+	if password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	return &LDAPIdentity{
+		DN:        entry.DN,
+		Email:     entry.GetAttributeValue("mail"),
+		FirstName: entry.GetAttributeValue("givenName"),
+		LastName:  entry.GetAttributeValue("sn"),
+	}, nil
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	mfaIssuer            = "EchoAuth"
+	mfaRecoveryCodeCount = 10
+)
+
+var (
+	ErrMFAInvalidCode = errors.New("invalid or expired MFA code")
+	ErrMFANotEnrolled = errors.New("MFA is not enrolled for this user")
+)
+
+// MFAService implements TOTP-based two-factor authentication (RFC 6238, 30s
+// step, 6 digits, ±1 window drift): EnrollTOTP generates a secret and
+// recovery codes, ConfirmTOTP activates it once the user proves possession
+// of the authenticator, and VerifyTOTP checks a login-time code against it.
+// Secrets are encrypted at rest with AES-GCM, the same scheme
+// RemoteIdentityRepository uses for upstream refresh tokens; recovery codes
+// are bcrypt-hashed and single-use, like password hashes.
+type MFAService struct {
+	userRepo   repositories.UserRepository
+	encryptKey []byte
+}
+
+// NewMFAService builds an MFAService. encryptKey must be 32 bytes (AES-256);
+// callers typically derive it from config.KeyEncryptionKey, the same key
+// RemoteIdentityRepository and KeySetRepository use.
+func NewMFAService(userRepo repositories.UserRepository, encryptKey []byte) *MFAService {
+	return &MFAService{userRepo: userRepo, encryptKey: encryptKey}
+}
+
+// EnrollTOTP generates a new TOTP secret and recovery codes for userID and
+// stores them pending confirmation via ConfirmTOTP. MFA isn't enabled until
+// then, so a half-finished enrollment never locks the user out of their
+// account. The returned secret and recoveryCodes are plaintext and must be
+// shown to the user now - only their encrypted/hashed forms are kept.
+func (s *MFAService) EnrollTOTP(userID uint) (secret, otpauthURL string, recoveryCodes []string, err error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      mfaIssuer,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	encryptedSecret, err := s.encrypt(key.Secret())
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	plainCodes, hashedCodes, err := generateRecoveryCodes()
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	user.MFASecretEncrypted = encryptedSecret
+	user.MFARecoveryCodeHashes = hashedCodes
+	if err := s.userRepo.Update(user); err != nil {
+		return "", "", nil, err
+	}
+
+	return key.Secret(), key.String(), plainCodes, nil
+}
+
+// ConfirmTOTP activates MFA for userID once code proves they've set up their
+// authenticator app with the secret from EnrollTOTP.
+func (s *MFAService) ConfirmTOTP(userID uint, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if len(user.MFASecretEncrypted) == 0 {
+		return ErrMFANotEnrolled
+	}
+
+	valid, err := s.validateCode(user, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrMFAInvalidCode
+	}
+
+	user.MFAEnabled = true
+	return s.userRepo.Update(user)
+}
+
+// VerifyTOTP checks code against userID's enrolled secret, falling back to
+// consuming a matching recovery code if code doesn't validate as a TOTP
+// code. It's the gate AuthService.CompleteMFALogin calls to finish a
+// challenged login.
+func (s *MFAService) VerifyTOTP(userID uint, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	valid, err := s.validateCode(user, code)
+	if err != nil {
+		return err
+	}
+	if valid {
+		return nil
+	}
+
+	if s.consumeRecoveryCode(user, code) {
+		return s.userRepo.Update(user)
+	}
+
+	return ErrMFAInvalidCode
+}
+
+func (s *MFAService) validateCode(user *models.User, code string) (bool, error) {
+	secret, err := s.decrypt(user.MFASecretEncrypted)
+	if err != nil {
+		return false, err
+	}
+	return totp.ValidateCustom(code, secret, time.Now(), totp.ValidateOpts{
+		Period:    30,
+		Skew:      1,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+}
+
+// consumeRecoveryCode reports whether code matches one of user's remaining
+// recovery codes, removing it from the list (so it can't be replayed again)
+// if so. The caller is responsible for persisting user afterward.
+func (s *MFAService) consumeRecoveryCode(user *models.User, code string) bool {
+	for i, hash := range user.MFARecoveryCodeHashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil {
+			user.MFARecoveryCodeHashes = append(user.MFARecoveryCodeHashes[:i], user.MFARecoveryCodeHashes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// generateRecoveryCodes returns mfaRecoveryCodeCount freshly generated
+// plaintext recovery codes alongside their bcrypt hashes for storage; the
+// plaintext codes are only ever shown to the caller once, at enrollment.
+func generateRecoveryCodes() (plaintext, hashed []string, err error) {
+	plaintext = make([]string, mfaRecoveryCodeCount)
+	hashed = make([]string, mfaRecoveryCodeCount)
+	for i := range plaintext {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := fmt.Sprintf("%x", b)
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		plaintext[i] = code
+		hashed[i] = string(hash)
+	}
+	return plaintext, hashed, nil
+}
+
+func (s *MFAService) encrypt(plaintext string) ([]byte, error) {
+	block, err := aes.NewCipher(s.encryptKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, []byte(plaintext), nil), nil
+}
+
+func (s *MFAService) decrypt(ciphertext []byte) (string, error) {
+	block, err := aes.NewCipher(s.encryptKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+	nonce, body := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, body, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"EchoAuth/models"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func TestMFAService_EnrollAndConfirmTOTP(t *testing.T) {
+	repo := newMockResetRepo()
+	user := &models.User{ID: 1, Email: "test@example.com"}
+	repo.Create(user)
+
+	service := NewMFAService(repo, []byte("01234567890123456789012345678901"))
+
+	secret, otpauthURL, recoveryCodes, err := service.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP() error = %v", err)
+	}
+	if secret == "" || otpauthURL == "" || len(recoveryCodes) == 0 {
+		t.Fatalf("EnrollTOTP() returned secret=%q otpauthURL=%q recoveryCodes=%v, want all populated", secret, otpauthURL, recoveryCodes)
+	}
+	if user.MFAEnabled {
+		t.Error("EnrollTOTP() should not enable MFA before ConfirmTOTP")
+	}
+
+	tests := []struct {
+		name    string
+		code    func() string
+		wantErr bool
+	}{
+		{
+			name: "Valid code",
+			code: func() string {
+				code, err := totp.GenerateCode(secret, time.Now())
+				if err != nil {
+					t.Fatalf("failed to generate TOTP code: %v", err)
+				}
+				return code
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid code",
+			code:    func() string { return "000000" },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user.MFAEnabled = false
+			err := service.ConfirmTOTP(user.ID, tt.code())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ConfirmTOTP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !user.MFAEnabled {
+				t.Error("ConfirmTOTP() should enable MFA once the code validates")
+			}
+		})
+	}
+}
+
+func TestMFAService_VerifyTOTP(t *testing.T) {
+	repo := newMockResetRepo()
+	user := &models.User{ID: 1, Email: "test@example.com"}
+	repo.Create(user)
+
+	service := NewMFAService(repo, []byte("01234567890123456789012345678901"))
+
+	secret, _, recoveryCodes, err := service.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP() error = %v", err)
+	}
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate TOTP code: %v", err)
+	}
+	if err := service.ConfirmTOTP(user.ID, code); err != nil {
+		t.Fatalf("ConfirmTOTP() error = %v", err)
+	}
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		other := &models.User{ID: 2, Email: "other@example.com"}
+		repo.Create(other)
+		if err := service.VerifyTOTP(other.ID, "123456"); err != ErrMFANotEnrolled {
+			t.Errorf("VerifyTOTP() error = %v, want %v", err, ErrMFANotEnrolled)
+		}
+	})
+
+	t.Run("Valid TOTP code", func(t *testing.T) {
+		code, err := totp.GenerateCode(secret, time.Now())
+		if err != nil {
+			t.Fatalf("failed to generate TOTP code: %v", err)
+		}
+		if err := service.VerifyTOTP(user.ID, code); err != nil {
+			t.Errorf("VerifyTOTP() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("Valid recovery code, single use", func(t *testing.T) {
+		recoveryCode := recoveryCodes[0]
+		if err := service.VerifyTOTP(user.ID, recoveryCode); err != nil {
+			t.Errorf("VerifyTOTP() error = %v, want nil", err)
+		}
+		if err := service.VerifyTOTP(user.ID, recoveryCode); err != ErrMFAInvalidCode {
+			t.Errorf("VerifyTOTP() on reused recovery code error = %v, want %v", err, ErrMFAInvalidCode)
+		}
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		if err := service.VerifyTOTP(user.ID, "not-a-real-code"); err != ErrMFAInvalidCode {
+			t.Errorf("VerifyTOTP() error = %v, want %v", err, ErrMFAInvalidCode)
+		}
+	})
+}
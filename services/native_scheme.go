@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+	"EchoAuth/utils/validator"
+)
+
+// NativeCredentials is the credentials type NativeScheme expects from
+// Create and Login.
+type NativeCredentials struct {
+	Email     string
+	Password  string
+	FirstName string
+	LastName  string
+}
+
+// NativeScheme is the original password-based authentication flow
+// (bcrypt hashing + complexity validation), wrapped behind the Scheme
+// interface so it can be registered alongside federated schemes.
+type NativeScheme struct {
+	userRepo       repositories.UserRepository
+	passwordMaxAge time.Duration
+}
+
+func NewNativeScheme(userRepo repositories.UserRepository, passwordMaxAge time.Duration) *NativeScheme {
+	return &NativeScheme{
+		userRepo:       userRepo,
+		passwordMaxAge: passwordMaxAge,
+	}
+}
+
+func (s *NativeScheme) Name() string {
+	return "native"
+}
+
+func (s *NativeScheme) Create(user *models.User, credentials interface{}) (*models.User, error) {
+	creds, ok := credentials.(NativeCredentials)
+	if !ok {
+		return nil, ErrInvalidCredentialsType
+	}
+
+	if err := validator.ValidateEmail(creds.Email); err != nil {
+		return nil, err
+	}
+	if err := validator.ValidatePasswordForUser(creds.Password, []string{creds.Email, creds.FirstName, creds.LastName}); err != nil {
+		return nil, err
+	}
+
+	existingUser, err := s.userRepo.FindByEmail(creds.Email)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	if existingUser != nil {
+		return nil, ErrUserExists
+	}
+
+	user.Email = creds.Email
+	user.FirstName = creds.FirstName
+	user.LastName = creds.LastName
+
+	if err := user.HashPassword(creds.Password); err != nil {
+		return nil, err
+	}
+	user.RecordPasswordChange(time.Now(), s.passwordMaxAge)
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *NativeScheme) Login(ctx context.Context, credentials interface{}) (*models.User, error) {
+	creds, ok := credentials.(NativeCredentials)
+	if !ok {
+		return nil, ErrInvalidCredentialsType
+	}
+
+	user, err := s.userRepo.FindByEmail(creds.Email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if !user.CheckPassword(creds.Password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// Remove clears the user's password, leaving the account in place for any
+// other schemes still linked to it.
+func (s *NativeScheme) Remove(user *models.User) error {
+	user.Password = ""
+	return s.userRepo.Update(user)
+}
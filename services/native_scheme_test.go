@@ -0,0 +1,97 @@
+package services
+
+import (
+	"EchoAuth/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNativeSchemeCreateAndLogin(t *testing.T) {
+	userRepo := newMockUserRepository()
+	scheme := NewNativeScheme(userRepo, 90*24*time.Hour)
+
+	if scheme.Name() != "native" {
+		t.Fatalf("expected scheme name 'native', got %q", scheme.Name())
+	}
+
+	created, err := scheme.Create(&models.User{}, NativeCredentials{
+		Email:     "jane@example.com",
+		Password:  "Str0ng!Passw0rd",
+		FirstName: "Jane",
+		LastName:  "Doe",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+	if created.Email != "jane@example.com" {
+		t.Fatalf("expected email to be set, got %q", created.Email)
+	}
+
+	user, err := scheme.Login(context.Background(), NativeCredentials{
+		Email:    "jane@example.com",
+		Password: "Str0ng!Passw0rd",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error logging in: %v", err)
+	}
+	if user.ID != created.ID {
+		t.Fatalf("expected login to resolve the created user")
+	}
+}
+
+func TestNativeSchemeCreateRejectsDuplicateEmail(t *testing.T) {
+	userRepo := newMockUserRepository()
+	scheme := NewNativeScheme(userRepo, 90*24*time.Hour)
+
+	creds := NativeCredentials{Email: "dup@example.com", Password: "Str0ng!Passw0rd"}
+	if _, err := scheme.Create(&models.User{}, creds); err != nil {
+		t.Fatalf("unexpected error on first create: %v", err)
+	}
+
+	if _, err := scheme.Create(&models.User{}, creds); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("expected ErrUserExists, got %v", err)
+	}
+}
+
+func TestNativeSchemeLoginRejectsWrongPassword(t *testing.T) {
+	userRepo := newMockUserRepository()
+	scheme := NewNativeScheme(userRepo, 90*24*time.Hour)
+
+	creds := NativeCredentials{Email: "jane@example.com", Password: "Str0ng!Passw0rd"}
+	if _, err := scheme.Create(&models.User{}, creds); err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+
+	_, err := scheme.Login(context.Background(), NativeCredentials{
+		Email:    "jane@example.com",
+		Password: "wrong-password",
+	})
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func TestNativeSchemeRemoveClearsPassword(t *testing.T) {
+	userRepo := newMockUserRepository()
+	scheme := NewNativeScheme(userRepo, 90*24*time.Hour)
+
+	creds := NativeCredentials{Email: "jane@example.com", Password: "Str0ng!Passw0rd"}
+	user, err := scheme.Create(&models.User{}, creds)
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+
+	if err := scheme.Remove(user); err != nil {
+		t.Fatalf("unexpected error removing scheme: %v", err)
+	}
+
+	stored, err := userRepo.FindByID(user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error fetching user: %v", err)
+	}
+	if stored.Password != "" {
+		t.Fatalf("expected password to be cleared, got %q", stored.Password)
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"EchoAuth/models"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// ErrOAuthStateInvalid is returned when a callback's state parameter doesn't
+// match anything ConnectorManager.StartLogin issued - expired, already
+// redeemed, or forged.
+var ErrOAuthStateInvalid = errors.New("oauth state is invalid or expired")
+
+// oauthStateTTL bounds how long a user has between hitting /start and
+// completing the provider's consent screen before the flow has to restart.
+const oauthStateTTL = 10 * time.Minute
+
+// ConnectorManager starts and completes PKCE-protected OAuth2 authorization
+// code flows for the providers registered with it, sitting in front of the
+// OAuthScheme instances the SchemeRegistry already dispatches Login to. It
+// exists because Login alone has no way to generate the authorization URL or
+// guard against CSRF/code-injection - that half of the flow happens before a
+// provider ever redirects back to FederationController.
+type ConnectorManager struct {
+	redis   *redis.Client
+	schemes map[string]*OAuthScheme
+}
+
+// NewConnectorManager builds a ConnectorManager with no providers registered;
+// call Register for each one before starting logins against it.
+func NewConnectorManager(redis *redis.Client) *ConnectorManager {
+	return &ConnectorManager{
+		redis:   redis,
+		schemes: make(map[string]*OAuthScheme),
+	}
+}
+
+// Register makes provider available to StartLogin/CompleteLogin, reusing the
+// same *OAuthScheme instance registered with the SchemeRegistry so both paths
+// exchange codes against an identical client registration.
+func (m *ConnectorManager) Register(scheme *OAuthScheme) {
+	m.schemes[scheme.Name()] = scheme
+}
+
+// oauthStatePayload is what StartLogin/StartLink stash under state for
+// CompleteLogin to recover: the PKCE verifier and OIDC nonce generated for
+// the flow, and the provider it was generated for, so a state value can't be
+// replayed against a different provider than the one it was issued for.
+// LinkUserID is set only by StartLink, for a flow that links the provider
+// identity to an already-authenticated user rather than logging one in.
+type oauthStatePayload struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	LinkUserID   *uint  `json:"link_user_id,omitempty"`
+}
+
+// StartLogin generates a CSRF state token and, for provider, a PKCE code
+// verifier/challenge pair, stashes them under the state for CompleteLogin to
+// recover, and returns the URL the caller should redirect the user's browser
+// to.
+func (m *ConnectorManager) StartLogin(ctx context.Context, provider string) (authURL, state string, err error) {
+	return m.start(ctx, provider, nil)
+}
+
+// StartLink is StartLogin for a user who is already authenticated and wants
+// to link provider to their account rather than log in with it - the state
+// it stores carries userID, so CompleteLogin links the resolved identity to
+// that user instead of resolving (or creating) one by email match.
+func (m *ConnectorManager) StartLink(ctx context.Context, provider string, userID uint) (authURL, state string, err error) {
+	return m.start(ctx, provider, &userID)
+}
+
+func (m *ConnectorManager) start(ctx context.Context, provider string, linkUserID *uint) (authURL, state string, err error) {
+	scheme, ok := m.schemes[provider]
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrSchemeNotFound, provider)
+	}
+
+	state, err = randomURLSafeString(32)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate oauth state: %w", err)
+	}
+	verifier, err := randomURLSafeString(32)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate pkce code verifier: %w", err)
+	}
+	challenge := pkceChallengeS256(verifier)
+
+	nonce, err := randomURLSafeString(32)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate oidc nonce: %w", err)
+	}
+
+	payload, err := json.Marshal(oauthStatePayload{Provider: provider, CodeVerifier: verifier, Nonce: nonce, LinkUserID: linkUserID})
+	if err != nil {
+		return "", "", err
+	}
+	if err := m.redis.Set(ctx, oauthStateKey(state), payload, oauthStateTTL).Err(); err != nil {
+		return "", "", fmt.Errorf("failed to store oauth state: %w", err)
+	}
+
+	authURL = scheme.Config().AuthCodeURL(state,
+		oauth2.SetAuthURLParam("code_challenge", challenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	return authURL, state, nil
+}
+
+// CompleteLogin redeems state (consuming it, so it can't be replayed),
+// verifies it was issued for provider, and exchanges code for the resolved
+// user via the matching OAuthScheme. When state was issued by StartLink
+// rather than StartLogin, the identity is linked to that flow's user instead
+// of being resolved by email match or auto-provisioned; linked reports which
+// of the two happened, so the caller knows whether to issue fresh tokens or
+// just confirm the link.
+func (m *ConnectorManager) CompleteLogin(ctx context.Context, provider, state, code string) (user *models.User, linked bool, err error) {
+	scheme, ok := m.schemes[provider]
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %s", ErrSchemeNotFound, provider)
+	}
+
+	raw, err := m.redis.GetDel(ctx, oauthStateKey(state)).Bytes()
+	if err == redis.Nil {
+		return nil, false, ErrOAuthStateInvalid
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to redeem oauth state: %w", err)
+	}
+
+	var stored oauthStatePayload
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return nil, false, fmt.Errorf("failed to decode oauth state: %w", err)
+	}
+	if stored.Provider != provider {
+		return nil, false, ErrOAuthStateInvalid
+	}
+
+	creds := OAuthCredentials{Code: code, CodeVerifier: stored.CodeVerifier, Nonce: stored.Nonce}
+	if stored.LinkUserID != nil {
+		user, err = scheme.Create(&models.User{ID: *stored.LinkUserID}, creds)
+		return user, true, err
+	}
+
+	user, err = scheme.Login(ctx, creds)
+	return user, false, err
+}
+
+func oauthStateKey(state string) string {
+	return "oauth_state:" + state
+}
+
+func randomURLSafeString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func pkceChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
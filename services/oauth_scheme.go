@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+
+	"golang.org/x/oauth2"
+)
+
+// OAuthUserInfo is the subset of a provider's profile response an
+// OAuthScheme needs to resolve or create a local user.
+type OAuthUserInfo struct {
+	SubjectID string
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+// OAuthUserInfoFetcher retrieves the authenticated user's profile from a
+// provider once an access token has been obtained, so OAuthScheme itself
+// stays provider-agnostic.
+type OAuthUserInfoFetcher interface {
+	FetchUserInfo(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (*OAuthUserInfo, error)
+}
+
+// OAuthCredentials is the credentials type OAuthScheme expects from Login:
+// the authorization code returned to the callback URL, plus the PKCE code
+// verifier generated when the flow was started, if any. CodeVerifier is
+// optional so a caller that doesn't use PKCE (or doesn't go through this
+// package's connector manager at all) still works. Nonce is the value
+// generated alongside it and only matters when an IDTokenVerifier is
+// configured - it's checked against the ID token's own nonce claim to
+// block a stolen ID token from a different flow being replayed here.
+type OAuthCredentials struct {
+	Code         string
+	CodeVerifier string
+	Nonce        string
+}
+
+// OAuthIDTokenClaims is the subset of a verified ID token's claims
+// OAuthScheme checks against the request that started the flow.
+type OAuthIDTokenClaims struct {
+	Subject string
+	Nonce   string
+}
+
+// OAuthIDTokenVerifier verifies an ID token's signature, issuer, audience,
+// and expiry, returning the claims OAuthScheme needs to cross-check. It's
+// optional on OAuthScheme: providers whose fetcher already authenticates
+// the profile response some other way (e.g. Google/GitHub's plain
+// userinfo endpoints) don't need one.
+type OAuthIDTokenVerifier interface {
+	Verify(ctx context.Context, rawIDToken string) (*OAuthIDTokenClaims, error)
+}
+
+// ErrOAuthIDTokenNonceMismatch is returned when a provider's ID token
+// nonce claim doesn't match the one generated when the flow started,
+// indicating the ID token wasn't issued for this authorization attempt.
+var ErrOAuthIDTokenNonceMismatch = errors.New("oauth id token nonce mismatch")
+
+// OAuthScheme logs a user in (or links/creates one on first login) via an
+// OAuth2 authorization-code exchange, identifying the account by a
+// models.RemoteIdentity rather than a password.
+type OAuthScheme struct {
+	provider           string
+	config             *oauth2.Config
+	fetcher            OAuthUserInfoFetcher
+	userRepo           repositories.UserRepository
+	remoteIdentityRepo repositories.RemoteIdentityRepository
+	idTokenVerifier    OAuthIDTokenVerifier
+}
+
+func NewOAuthScheme(provider string, config *oauth2.Config, fetcher OAuthUserInfoFetcher, userRepo repositories.UserRepository, remoteIdentityRepo repositories.RemoteIdentityRepository) *OAuthScheme {
+	return &OAuthScheme{
+		provider:           provider,
+		config:             config,
+		fetcher:            fetcher,
+		userRepo:           userRepo,
+		remoteIdentityRepo: remoteIdentityRepo,
+	}
+}
+
+func (s *OAuthScheme) Name() string {
+	return s.provider
+}
+
+// SetIDTokenVerifier enables ID token verification on Login: once set,
+// every exchange that returns an id_token has its signature, issuer,
+// audience, and nonce checked before the profile fetched from fetcher is
+// trusted. It's optional so providers configured without one (Google and
+// GitHub today) keep working exactly as before.
+func (s *OAuthScheme) SetIDTokenVerifier(verifier OAuthIDTokenVerifier) {
+	s.idTokenVerifier = verifier
+}
+
+// Config returns the oauth2.Config this scheme exchanges codes against, so
+// a caller building the authorization URL (e.g. oauth.ConnectorManager) can
+// reuse the same client registration instead of duplicating it.
+func (s *OAuthScheme) Config() *oauth2.Config {
+	return s.config
+}
+
+// Login exchanges the authorization code for a token, fetches the
+// provider's profile, and resolves it to a local user - linking a new
+// RemoteIdentity to a matching-email user, or creating one, on first login.
+func (s *OAuthScheme) Login(ctx context.Context, credentials interface{}) (*models.User, error) {
+	creds, ok := credentials.(OAuthCredentials)
+	if !ok {
+		return nil, ErrInvalidCredentialsType
+	}
+
+	info, token, err := s.exchangeAndFetch(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	identity, err := s.remoteIdentityRepo.FindByProviderAndSubject(s.provider, info.SubjectID)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			return nil, err
+		}
+		user, err = s.linkOrCreateUser(info)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		user, err = s.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.storeUpstreamRefreshToken(user.ID, token); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// exchangeAndFetch redeems creds.Code for a token, verifies its ID token
+// when an idTokenVerifier is configured, and fetches the provider's profile
+// for the account the token belongs to - the half of Login/Create that's
+// identical regardless of whether the resolved profile ends up linked to a
+// new user, an existing matching-email user, or a specific user passed to
+// Create.
+func (s *OAuthScheme) exchangeAndFetch(ctx context.Context, creds OAuthCredentials) (*OAuthUserInfo, *oauth2.Token, error) {
+	var exchangeOpts []oauth2.AuthCodeOption
+	if creds.CodeVerifier != "" {
+		exchangeOpts = append(exchangeOpts, oauth2.SetAuthURLParam("code_verifier", creds.CodeVerifier))
+	}
+	token, err := s.config.Exchange(ctx, creds.Code, exchangeOpts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to exchange oauth code: %w", err)
+	}
+
+	if s.idTokenVerifier != nil {
+		rawIDToken, _ := token.Extra("id_token").(string)
+		if rawIDToken == "" {
+			return nil, nil, errors.New("oauth exchange did not return an id token")
+		}
+		claims, err := s.idTokenVerifier.Verify(ctx, rawIDToken)
+		if err != nil {
+			return nil, nil, err
+		}
+		if claims.Nonce != creds.Nonce {
+			return nil, nil, ErrOAuthIDTokenNonceMismatch
+		}
+	}
+
+	info, err := s.fetcher.FetchUserInfo(ctx, s.config, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch oauth user info: %w", err)
+	}
+
+	return info, token, nil
+}
+
+// storeUpstreamRefreshToken persists token.RefreshToken for userID, if the
+// exchange returned one. The upstream refresh token is only present when
+// the provider was asked for offline access (e.g. Scopes includes
+// "access_type=offline" for Google); persisting it lets a future
+// silent-refresh attempt renew the upstream session without sending the
+// user through the browser redirect again. A provider that didn't return
+// one leaves whatever was previously stored untouched rather than clearing
+// it, since token rotation isn't guaranteed on every exchange.
+func (s *OAuthScheme) storeUpstreamRefreshToken(userID uint, token *oauth2.Token) error {
+	if token.RefreshToken == "" {
+		return nil
+	}
+	if err := s.remoteIdentityRepo.UpdateUpstreamRefreshToken(userID, s.provider, token.RefreshToken); err != nil {
+		return fmt.Errorf("failed to store upstream refresh token: %w", err)
+	}
+	return nil
+}
+
+func (s *OAuthScheme) linkOrCreateUser(info *OAuthUserInfo) (*models.User, error) {
+	user, err := s.userRepo.FindByEmail(info.Email)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			return nil, err
+		}
+		user = &models.User{
+			Email:     info.Email,
+			FirstName: info.FirstName,
+			LastName:  info.LastName,
+		}
+		if err := s.userRepo.Create(user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.remoteIdentityRepo.Create(&models.RemoteIdentity{
+		UserID:    user.ID,
+		Provider:  s.provider,
+		SubjectID: info.SubjectID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Create links credentials.(OAuthCredentials) to user.ID, for a user who is
+// already authenticated (e.g. via ConnectorManager.StartLink) and wants to
+// add this provider as an additional way to log in, rather than have the
+// resolved profile matched or auto-provisioned against a different account.
+func (s *OAuthScheme) Create(user *models.User, credentials interface{}) (*models.User, error) {
+	creds, ok := credentials.(OAuthCredentials)
+	if !ok {
+		return nil, ErrInvalidCredentialsType
+	}
+
+	info, token, err := s.exchangeAndFetch(context.Background(), creds)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.remoteIdentityRepo.Create(&models.RemoteIdentity{
+		UserID:    user.ID,
+		Provider:  s.provider,
+		SubjectID: info.SubjectID,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.storeUpstreamRefreshToken(user.ID, token); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.FindByID(user.ID)
+}
+
+func (s *OAuthScheme) Remove(user *models.User) error {
+	return s.remoteIdentityRepo.Delete(user.ID, s.provider)
+}
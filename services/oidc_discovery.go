@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// NewOIDCConfig discovers issuer's authorization, token, and userinfo
+// endpoints from its /.well-known/openid-configuration document and
+// returns an oauth2.Config built from them, plus the *oidc.Provider the
+// discovery resolved to. Unlike the Google/GitHub OAuthUserInfoFetchers,
+// which hardcode a known provider's endpoints, this lets any standards-
+// compliant OIDC provider (e.g. Keycloak, or a generic provider configured
+// purely from env) be wired up from an issuer URL alone.
+func NewOIDCConfig(ctx context.Context, issuerURL, clientID, clientSecret, redirectURL string, scopes []string) (*oauth2.Config, *oidc.Provider, error) {
+	provider, err := oidc.NewProvider(ctx, issuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover oidc issuer %s: %w", issuerURL, err)
+	}
+
+	config := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+
+	return config, provider, nil
+}
+
+// oidcIDTokenVerifier adapts an *oidc.IDTokenVerifier, scoped to a single
+// client ID, to OAuthIDTokenVerifier.
+type oidcIDTokenVerifier struct {
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCIDTokenVerifier builds an OAuthIDTokenVerifier that checks an ID
+// token's signature, issuer, audience, and expiry against provider and
+// clientID, for OAuthScheme.SetIDTokenVerifier.
+func NewOIDCIDTokenVerifier(provider *oidc.Provider, clientID string) OAuthIDTokenVerifier {
+	return &oidcIDTokenVerifier{verifier: provider.Verifier(&oidc.Config{ClientID: clientID})}
+}
+
+func (v *oidcIDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*OAuthIDTokenClaims, error) {
+	idToken, err := v.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id token verification failed: %w", err)
+	}
+	return &OAuthIDTokenClaims{Subject: idToken.Subject, Nonce: idToken.Nonce}, nil
+}
+
+// oidcUserInfoFetcher fetches a discovery provider's userinfo endpoint via
+// go-oidc, which exposes it directly rather than requiring a hand-rolled
+// URL and response shape like httpOAuthUserInfoFetcher does for Google/
+// GitHub.
+type oidcUserInfoFetcher struct {
+	provider *oidc.Provider
+}
+
+// NewOIDCUserInfoFetcher builds an OAuthUserInfoFetcher backed by
+// provider's userinfo endpoint.
+func NewOIDCUserInfoFetcher(provider *oidc.Provider) OAuthUserInfoFetcher {
+	return &oidcUserInfoFetcher{provider: provider}
+}
+
+func (f *oidcUserInfoFetcher) FetchUserInfo(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (*OAuthUserInfo, error) {
+	userInfo, err := f.provider.UserInfo(ctx, config.TokenSource(ctx, token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch oidc userinfo: %w", err)
+	}
+
+	var claims struct {
+		Email      string `json:"email"`
+		GivenName  string `json:"given_name"`
+		FamilyName string `json:"family_name"`
+	}
+	if err := userInfo.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &OAuthUserInfo{
+		SubjectID: userInfo.Subject,
+		Email:     claims.Email,
+		FirstName: claims.GivenName,
+		LastName:  claims.FamilyName,
+	}, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrOAuthClientNotFound      = errors.New("oauth client not found or revoked")
+	ErrInvalidRedirectURI       = errors.New("redirect_uri is not registered for this client")
+	ErrUnsupportedGrantType     = errors.New("grant_type not allowed for this client")
+	ErrAuthorizationCodeInvalid = errors.New("authorization code is invalid, expired, or already redeemed")
+	ErrPKCEVerificationFailed   = errors.New("code_verifier does not match the code_challenge issued for this code")
+)
+
+// authCodeTTL bounds how long an authorization code issued by Authorize
+// stays valid for a matching call to ExchangeCode, per RFC 6749's guidance
+// that codes should expire quickly since they pass through a browser
+// redirect and are easily logged or leaked.
+const authCodeTTL = 60 * time.Second
+
+// authCodePayload is what Authorize stashes under the issued code for
+// ExchangeCode to recover and verify against the token request.
+type authCodePayload struct {
+	ClientID            string   `json:"client_id"`
+	UserID              uint     `json:"user_id"`
+	RedirectURI         string   `json:"redirect_uri"`
+	Scopes              []string `json:"scopes"`
+	Nonce               string   `json:"nonce"`
+	CodeChallenge       string   `json:"code_challenge"`
+	CodeChallengeMethod string   `json:"code_challenge_method"`
+}
+
+// OIDCProvider implements the authorization_code (with PKCE), refresh_token
+// and client_credentials grants for EchoAuth acting as its own OpenID
+// Connect provider. It's backed by redis for the short-lived authorization
+// code, the same way ConnectorManager backs its oauth state - and by
+// clients for the registered OAuth client metadata.
+type OIDCProvider struct {
+	redis   *redis.Client
+	clients repositories.ServiceClientRepository
+	auth    *AuthService
+}
+
+// NewOIDCProvider builds an OIDCProvider.
+func NewOIDCProvider(redis *redis.Client, clients repositories.ServiceClientRepository, auth *AuthService) *OIDCProvider {
+	return &OIDCProvider{redis: redis, clients: clients, auth: auth}
+}
+
+// Authorize issues a short-lived authorization code for an already
+// authenticated userID, to be redeemed by ExchangeCode. EchoAuth has no
+// browser-session login, so unlike a typical /authorize endpoint this one
+// doesn't render a consent screen itself - it's a bearer-protected endpoint,
+// and the caller has already authenticated userID by the time this runs.
+func (p *OIDCProvider) Authorize(ctx context.Context, clientID, redirectURI string, scopes []string, nonce, codeChallenge, codeChallengeMethod string, userID uint) (code string, err error) {
+	client, err := p.lookupClient(clientID)
+	if err != nil {
+		return "", err
+	}
+	if !client.AllowsGrantType("authorization_code") {
+		return "", ErrUnsupportedGrantType
+	}
+	if !client.AllowsRedirectURI(redirectURI) {
+		return "", ErrInvalidRedirectURI
+	}
+	for _, scope := range scopes {
+		if !client.AllowsScope(scope) {
+			return "", ErrInvalidScope
+		}
+	}
+
+	code, err = randomURLSafeString(32)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(authCodePayload{
+		ClientID:            clientID,
+		UserID:              userID,
+		RedirectURI:         redirectURI,
+		Scopes:              scopes,
+		Nonce:               nonce,
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := p.redis.Set(ctx, authCodeKey(code), payload, authCodeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store authorization code: %w", err)
+	}
+
+	return code, nil
+}
+
+// ExchangeCode implements the authorization_code grant: it redeems code
+// (consuming it, so it can't be replayed), verifies clientID/redirectURI
+// match what Authorize issued it for, verifies codeVerifier against the
+// PKCE challenge when one was supplied, and issues a fresh token pair plus
+// an ID token carrying the original nonce.
+func (p *OIDCProvider) ExchangeCode(ctx context.Context, clientID, redirectURI, code, codeVerifier, deviceInfo, ip string) (accessToken, refreshToken, idToken string, err error) {
+	raw, err := p.redis.GetDel(ctx, authCodeKey(code)).Bytes()
+	if err == redis.Nil {
+		return "", "", "", ErrAuthorizationCodeInvalid
+	}
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+
+	var stored authCodePayload
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return "", "", "", fmt.Errorf("failed to decode authorization code: %w", err)
+	}
+	if stored.ClientID != clientID || stored.RedirectURI != redirectURI {
+		return "", "", "", ErrAuthorizationCodeInvalid
+	}
+	if stored.CodeChallenge != "" && !pkceVerify(stored.CodeChallengeMethod, codeVerifier, stored.CodeChallenge) {
+		return "", "", "", ErrPKCEVerificationFailed
+	}
+
+	accessToken, refreshToken, err = p.auth.issueTokens(ctx, stored.UserID, deviceInfo, ip, stored.Scopes)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	idToken, err = p.auth.GenerateIDToken(stored.UserID, stored.Nonce)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return accessToken, refreshToken, idToken, nil
+}
+
+// ClientCredentials implements the client_credentials grant: clientID and
+// clientSecret authenticate the client itself rather than a user, so the
+// resulting access token carries ClientClaims instead of TokenClaims.
+func (p *OIDCProvider) ClientCredentials(clientID, clientSecret string, scopes []string) (string, error) {
+	client, err := p.lookupClient(clientID)
+	if err != nil {
+		return "", err
+	}
+	if !client.CheckSecret(clientSecret) {
+		return "", ErrOAuthClientNotFound
+	}
+	if !client.AllowsGrantType("client_credentials") {
+		return "", ErrUnsupportedGrantType
+	}
+	for _, scope := range scopes {
+		if !client.AllowsScope(scope) {
+			return "", ErrInvalidScope
+		}
+	}
+
+	return p.auth.IssueClientCredentialsToken(clientID, scopes)
+}
+
+func (p *OIDCProvider) lookupClient(clientID string) (*models.ServiceClient, error) {
+	client, err := p.clients.FindByClientID(clientID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+	if client.Revoked() {
+		return nil, ErrOAuthClientNotFound
+	}
+	return client, nil
+}
+
+func authCodeKey(code string) string {
+	return "oauth_code:" + code
+}
+
+// pkceVerify checks verifier against the code_challenge issued for an
+// authorization code, per RFC 7636: "S256" hashes verifier before
+// comparing, "plain" (and an omitted method, which defaults to "plain")
+// compares it directly. Both branches use a constant-time comparison since
+// challenge is attacker-reachable via the token endpoint.
+func pkceVerify(method, verifier, challenge string) bool {
+	if method == "S256" {
+		return subtle.ConstantTimeCompare([]byte(pkceChallengeS256(verifier)), []byte(challenge)) == 1
+	}
+	return subtle.ConstantTimeCompare([]byte(verifier), []byte(challenge)) == 1
+}
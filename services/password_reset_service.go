@@ -1,28 +1,46 @@
 package services
 
 import (
+	"EchoAuth/mailer"
 	"EchoAuth/models"
 	"EchoAuth/repositories"
 	"EchoAuth/utils/validator"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 )
 
+const defaultPasswordHistoryDepth = 5
+
 type PasswordResetService struct {
-	userRepo     repositories.UserRepository
-	emailService EmailService
+	userRepo        repositories.UserRepository
+	actionTokenRepo repositories.ActionTokenRepository
+	mailer          mailer.Mailer
+	publicURL       string
+	historyRepo     repositories.PasswordHistoryRepository
+	passwordMaxAge  time.Duration
 }
 
-func NewPasswordResetService(userRepo repositories.UserRepository, emailService EmailService) *PasswordResetService {
+func NewPasswordResetService(userRepo repositories.UserRepository, actionTokenRepo repositories.ActionTokenRepository, mailer mailer.Mailer, publicURL string) *PasswordResetService {
 	return &PasswordResetService{
-		userRepo:     userRepo,
-		emailService: emailService,
+		userRepo:        userRepo,
+		actionTokenRepo: actionTokenRepo,
+		mailer:          mailer,
+		publicURL:       publicURL,
 	}
 }
 
+// SetPasswordPolicy enables password-expiration tracking and reuse
+// prevention on ResetPassword. It is optional: without it, resets still
+// work but history is not checked and PasswordExpiresAt is left unset.
+func (s *PasswordResetService) SetPasswordPolicy(historyRepo repositories.PasswordHistoryRepository, maxAge time.Duration) {
+	s.historyRepo = historyRepo
+	s.passwordMaxAge = maxAge
+}
+
 // GenerateResetToken creates a reset token for the user with the given email
 func (s *PasswordResetService) GenerateResetToken(email string) (string, error) {
 	// Validate email format
@@ -42,17 +60,19 @@ func (s *PasswordResetService) GenerateResetToken(email string) (string, error)
 	}
 	token := hex.EncodeToString(b)
 
-	// Set token expiration (24 hours from now)
-	expiresAt := time.Now().Add(24 * time.Hour)
-	user.PasswordResetToken = token
-	user.ResetTokenExpiresAt = expiresAt
-
-	if err := s.userRepo.Update(user); err != nil {
+	actionToken := &models.ActionToken{
+		UserID:    user.ID,
+		Token:     token,
+		Purpose:   models.TokenPurposeReset,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	if err := s.actionTokenRepo.Create(actionToken); err != nil {
 		return "", err
 	}
 
 	// Send reset email
-	if err := s.emailService.SendPasswordResetEmail(email, token); err != nil {
+	resetURL := fmt.Sprintf("%s/reset-password?token=%s", s.publicURL, token)
+	if err := s.mailer.SendPasswordReset(email, resetURL); err != nil {
 		// Log the error but don't return it to avoid revealing user existence
 		log.Printf("Failed to send password reset email: %v", err)
 	}
@@ -66,38 +86,69 @@ func (s *PasswordResetService) ValidateResetToken(token string) (*models.User, e
 		return nil, errors.New("invalid token")
 	}
 
-	user, err := s.userRepo.FindByResetToken(token)
+	actionToken, err := s.actionTokenRepo.FindByToken(token, models.TokenPurposeReset)
 	if err != nil {
 		return nil, errors.New("invalid token")
 	}
 
-	if user.ResetTokenExpiresAt.IsZero() || user.ResetTokenExpiresAt.Before(time.Now()) {
+	if actionToken.Expired(time.Now()) {
 		return nil, errors.New("token expired")
 	}
 
+	user, err := s.userRepo.FindByID(actionToken.UserID)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
 	return user, nil
 }
 
-// ResetPassword changes the user's password and invalidates the reset token
+// ResetPassword changes the user's password and invalidates the reset token.
+// The token is consumed atomically before the password is touched, so two
+// concurrent requests racing on the same link can't both succeed.
 func (s *PasswordResetService) ResetPassword(token, newPassword string) error {
 	// Validate new password first
 	if err := validator.ValidatePassword(newPassword); err != nil {
 		return err
 	}
 
-	// Then validate token
-	user, err := s.ValidateResetToken(token)
+	actionToken, err := s.actionTokenRepo.Consume(token, models.TokenPurposeReset)
 	if err != nil {
-		return err
+		return errors.New("invalid token")
+	}
+	if actionToken.Expired(time.Now()) {
+		return errors.New("token expired")
+	}
+
+	user, err := s.userRepo.FindByID(actionToken.UserID)
+	if err != nil {
+		return errors.New("invalid token")
+	}
+
+	if s.historyRepo != nil {
+		previousHashes, err := s.historyRepo.Recent(user.ID, defaultPasswordHistoryDepth)
+		if err != nil {
+			return err
+		}
+		if err := validator.ValidatePasswordAgainstHistory(newPassword, previousHashes); err != nil {
+			return err
+		}
 	}
 
 	if err := user.HashPassword(newPassword); err != nil {
 		return err
 	}
+	user.RecordPasswordChange(time.Now(), s.passwordMaxAge)
 
-	// Clear reset token
-	user.PasswordResetToken = ""
-	user.ResetTokenExpiresAt = time.Time{}
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if s.historyRepo != nil {
+		if err := s.historyRepo.Add(user.ID, user.Password); err != nil {
+			log.Printf("failed to record password history for user %d: %v", user.ID, err)
+		}
+	}
 
-	return s.userRepo.Update(user)
+	return nil
 }
@@ -2,12 +2,22 @@ package services
 
 import (
 	"EchoAuth/models"
+	"EchoAuth/repositories"
 	"EchoAuth/utils/validator"
 	"errors"
 	"testing"
 	"time"
 )
 
+type mockMailer struct{}
+
+func (m *mockMailer) SendPasswordReset(to, resetURL string) error      { return nil }
+func (m *mockMailer) SendEmailVerification(to, verifyURL string) error { return nil }
+func (m *mockMailer) SendInvitation(to, inviteURL string) error        { return nil }
+func (m *mockMailer) SendLoginNotification(to, deviceInfo, ip string, loginAt time.Time) error {
+	return nil
+}
+
 type mockResetRepo struct {
 	users map[string]*models.User
 }
@@ -39,15 +49,6 @@ func (m *mockResetRepo) FindByID(id uint) (*models.User, error) {
 	return nil, errors.New("user not found")
 }
 
-func (m *mockResetRepo) FindByResetToken(token string) (*models.User, error) {
-	for _, user := range m.users {
-		if user.PasswordResetToken == token {
-			return user, nil
-		}
-	}
-	return nil, errors.New("token not found")
-}
-
 func (m *mockResetRepo) Update(user *models.User) error {
 	if _, exists := m.users[user.Email]; !exists {
 		return errors.New("user not found")
@@ -66,9 +67,65 @@ func (m *mockResetRepo) Delete(id uint) error {
 	return errors.New("user not found")
 }
 
+type mockActionTokenRepo struct {
+	tokens map[string]*models.ActionToken
+}
+
+func newMockActionTokenRepo() *mockActionTokenRepo {
+	return &mockActionTokenRepo{
+		tokens: make(map[string]*models.ActionToken),
+	}
+}
+
+func (m *mockActionTokenRepo) Create(token *models.ActionToken) error {
+	for key, existing := range m.tokens {
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose {
+			delete(m.tokens, key)
+		}
+	}
+	m.tokens[token.Token] = token
+	return nil
+}
+
+func (m *mockActionTokenRepo) FindByToken(token string, purpose models.TokenPurpose) (*models.ActionToken, error) {
+	if t, exists := m.tokens[token]; exists && t.Purpose == purpose {
+		return t, nil
+	}
+	return nil, repositories.ErrNotFound
+}
+
+func (m *mockActionTokenRepo) Consume(token string, purpose models.TokenPurpose) (*models.ActionToken, error) {
+	t, exists := m.tokens[token]
+	if !exists || t.Purpose != purpose || t.Consumed() {
+		return nil, repositories.ErrNotFound
+	}
+	now := time.Now()
+	t.ConsumedAt = &now
+	return t, nil
+}
+
+func (m *mockActionTokenRepo) DeleteByUserAndPurpose(userID uint, purpose models.TokenPurpose) error {
+	for key, t := range m.tokens {
+		if t.UserID == userID && t.Purpose == purpose {
+			delete(m.tokens, key)
+		}
+	}
+	return nil
+}
+
+func (m *mockActionTokenRepo) Delete(token string) error {
+	delete(m.tokens, token)
+	return nil
+}
+
+func (m *mockActionTokenRepo) CleanupExpired() error {
+	return nil
+}
+
 func TestPasswordResetService_GenerateResetToken(t *testing.T) {
 	repo := newMockResetRepo()
-	service := NewPasswordResetService(repo, &mockEmailService{})
+	actionTokenRepo := newMockActionTokenRepo()
+	service := NewPasswordResetService(repo, actionTokenRepo, &mockMailer{}, "https://example.com")
 
 	// Create test user
 	user := &models.User{
@@ -125,25 +182,36 @@ func TestPasswordResetService_GenerateResetToken(t *testing.T) {
 
 func TestPasswordResetService_ValidateResetToken(t *testing.T) {
 	repo := newMockResetRepo()
-	service := NewPasswordResetService(repo, &mockEmailService{})
+	actionTokenRepo := newMockActionTokenRepo()
+	service := NewPasswordResetService(repo, actionTokenRepo, &mockMailer{}, "https://example.com")
 
 	// Create test user with valid token
 	user := &models.User{
-		Email:               "test@example.com",
-		Password:            "old_password",
-		PasswordResetToken:  "valid-token",
-		ResetTokenExpiresAt: time.Now().Add(time.Hour),
+		ID:       1,
+		Email:    "test@example.com",
+		Password: "old_password",
 	}
 	repo.Create(user)
+	actionTokenRepo.tokens["valid-token"] = &models.ActionToken{
+		UserID:    user.ID,
+		Token:     "valid-token",
+		Purpose:   models.TokenPurposeReset,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
 
 	// Create user with expired token
 	expiredUser := &models.User{
-		Email:               "expired@example.com",
-		Password:            "old_password",
-		PasswordResetToken:  "expired-token",
-		ResetTokenExpiresAt: time.Now().Add(-time.Hour),
+		ID:       2,
+		Email:    "expired@example.com",
+		Password: "old_password",
 	}
 	repo.Create(expiredUser)
+	actionTokenRepo.tokens["expired-token"] = &models.ActionToken{
+		UserID:    expiredUser.ID,
+		Token:     "expired-token",
+		Purpose:   models.TokenPurposeReset,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
 
 	tests := []struct {
 		name    string
@@ -188,16 +256,22 @@ func TestPasswordResetService_ValidateResetToken(t *testing.T) {
 
 func TestPasswordResetService_ResetPassword(t *testing.T) {
 	repo := newMockResetRepo()
-	service := NewPasswordResetService(repo, &mockEmailService{})
+	actionTokenRepo := newMockActionTokenRepo()
+	service := NewPasswordResetService(repo, actionTokenRepo, &mockMailer{}, "https://example.com")
 
 	// Create test user with valid token
 	user := &models.User{
-		Email:               "test@example.com",
-		Password:            "old_password",
-		PasswordResetToken:  "valid-token",
-		ResetTokenExpiresAt: time.Now().Add(time.Hour),
+		ID:       1,
+		Email:    "test@example.com",
+		Password: "old_password",
 	}
 	repo.Create(user)
+	actionTokenRepo.tokens["valid-token"] = &models.ActionToken{
+		UserID:    user.ID,
+		Token:     "valid-token",
+		Purpose:   models.TokenPurposeReset,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
 
 	tests := []struct {
 		name        string
@@ -208,13 +282,13 @@ func TestPasswordResetService_ResetPassword(t *testing.T) {
 		{
 			name:        "Valid reset",
 			token:       "valid-token",
-			newPassword: "NewPassword123!",
+			newPassword: "mR7!kTy93Xz&qLp2",
 			wantErr:     nil,
 		},
 		{
 			name:        "Invalid token",
 			token:       "invalid-token",
-			newPassword: "NewPassword123!",
+			newPassword: "mR7!kTy93Xz&qLp2",
 			wantErr:     errors.New("invalid token"),
 		},
 		{
@@ -232,7 +306,7 @@ func TestPasswordResetService_ResetPassword(t *testing.T) {
 		{
 			name:        "Common password",
 			token:       "valid-token",
-			newPassword: "password123",
+			newPassword: "Password1!",
 			wantErr:     validator.ErrPasswordCommon,
 		},
 	}
@@ -240,7 +314,13 @@ func TestPasswordResetService_ResetPassword(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			err := service.ResetPassword(tt.token, tt.newPassword)
-			if err != nil && err.Error() != tt.wantErr.Error() {
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("ResetPassword() error = %v, wantErr nil", err)
+				}
+				return
+			}
+			if err == nil || (!errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()) {
 				t.Errorf("ResetPassword() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitStrategy selects which algorithm a RateLimiter enforces a
+// RateLimiterConfig with. They differ in burst tolerance and storage cost,
+// not in the limit they enforce.
+type RateLimitStrategy string
+
+const (
+	// StrategyFixedWindow counts attempts within the current clock-aligned
+	// window and resets abruptly at its boundary. Cheapest to store, but lets
+	// up to 2x the configured rate through in a burst that straddles a
+	// boundary.
+	StrategyFixedWindow RateLimitStrategy = "fixed_window"
+	// StrategySlidingWindow keeps a log of every attempt's timestamp and
+	// counts how many fall within the trailing Window, so the limit holds
+	// everywhere rather than just at a fixed boundary. This is the default.
+	StrategySlidingWindow RateLimitStrategy = "sliding_window"
+	// StrategyTokenBucket refills a bucket of MaxAttempts tokens continuously
+	// over Window and spends one per attempt, allowing a short burst up to
+	// the bucket size while still holding to the average rate long-term.
+	StrategyTokenBucket RateLimitStrategy = "token_bucket"
+)
+
+// Decision is the outcome of a RateLimiter.Allow call: not just whether the
+// attempt is allowed, but enough detail for a caller to set the standard
+// X-RateLimit-* and Retry-After response headers.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+}
+
+// RateLimiter decides whether another attempt identified by key should be
+// allowed right now, and lets a key's count be cleared early (e.g. after a
+// successful login).
+type RateLimiter interface {
+	Allow(key string) (Decision, error)
+	Reset(key string) error
+}
+
+// RateLimiterConfig bounds how many attempts a single key may make within
+// Window before Allow starts returning false, and which algorithm enforces
+// that bound. A zero Strategy defaults to StrategySlidingWindow. Burst only
+// applies to StrategyTokenBucket, where it sets the bucket's capacity
+// separately from MaxAttempts/Window's average refill rate, so a caller can
+// allow a short burst larger than the steady-state rate (e.g. "5/30m,
+// burst:20" refills at 5 per 30m but lets 20 through back-to-back); a zero
+// Burst defaults to MaxAttempts, giving the bucket no extra burst headroom.
+type RateLimiterConfig struct {
+	MaxAttempts int
+	Window      time.Duration
+	Strategy    RateLimitStrategy
+	Burst       int
+}
+
+// RateLimitPolicy is a RateLimiterConfig as parsed from the "N/duration"
+// shorthand routes are configured with (see ParsePolicy), so call sites that
+// build one from a literal don't need to name both fields separately.
+type RateLimitPolicy = RateLimiterConfig
+
+// ParsePolicy parses the "N/duration" shorthand (e.g. "5/30m", "100/1m"),
+// with optional trailing ",burst:N" (e.g. "5/30m,burst:20") and
+// ",algo:NAME" segments (e.g. "5/30m,algo:token_bucket,burst:20"; NAME is
+// any RateLimitStrategy) in either order, into a RateLimitPolicy. burst sets
+// RateLimitPolicy.Burst for StrategyTokenBucket callers; algo defaults to
+// StrategySlidingWindow when omitted. duration is anything
+// time.ParseDuration accepts.
+func ParsePolicy(spec string) (RateLimitPolicy, error) {
+	parts := strings.Split(spec, ",")
+
+	n, window, found := strings.Cut(parts[0], "/")
+	if !found {
+		return RateLimitPolicy{}, fmt.Errorf("rate limit policy %q: want N/duration", spec)
+	}
+
+	maxAttempts, err := strconv.Atoi(n)
+	if err != nil {
+		return RateLimitPolicy{}, fmt.Errorf("rate limit policy %q: invalid attempt count: %w", spec, err)
+	}
+
+	dur, err := time.ParseDuration(window)
+	if err != nil {
+		return RateLimitPolicy{}, fmt.Errorf("rate limit policy %q: invalid duration: %w", spec, err)
+	}
+
+	policy := RateLimitPolicy{MaxAttempts: maxAttempts, Window: dur, Strategy: StrategySlidingWindow}
+
+	for _, part := range parts[1:] {
+		switch {
+		case strings.HasPrefix(part, "burst:"):
+			burst, err := strconv.Atoi(strings.TrimPrefix(part, "burst:"))
+			if err != nil {
+				return RateLimitPolicy{}, fmt.Errorf("rate limit policy %q: invalid burst count: %w", spec, err)
+			}
+			policy.Burst = burst
+		case strings.HasPrefix(part, "algo:"):
+			algo := RateLimitStrategy(strings.TrimPrefix(part, "algo:"))
+			switch algo {
+			case StrategyFixedWindow, StrategySlidingWindow, StrategyTokenBucket:
+				policy.Strategy = algo
+			default:
+				return RateLimitPolicy{}, fmt.Errorf("rate limit policy %q: unknown algo %q", spec, algo)
+			}
+		default:
+			return RateLimitPolicy{}, fmt.Errorf("rate limit policy %q: want burst:N or algo:NAME", spec)
+		}
+	}
+
+	return policy, nil
+}
+
+// MustParsePolicy is ParsePolicy for a spec that's a compile-time literal; it
+// panics on a malformed spec instead of returning an error.
+func MustParsePolicy(spec string) RateLimitPolicy {
+	policy, err := ParsePolicy(spec)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// NewRateLimiter returns a Redis-backed RateLimiter enforcing config via its
+// Strategy (StrategySlidingWindow if unset). If client is nil, it instead
+// returns an in-process token bucket with the same limits, so callers that
+// don't have a Redis connection wired up (e.g. unit tests) still get rate
+// limiting behavior rather than a nil dereference.
+func NewRateLimiter(client *redis.Client, config RateLimiterConfig) RateLimiter {
+	if client == nil {
+		return newInProcessRateLimiter(config)
+	}
+
+	switch config.Strategy {
+	case StrategyFixedWindow:
+		return &fixedWindowRateLimiter{client: client, maxAttempts: config.MaxAttempts, window: config.Window}
+	case StrategyTokenBucket:
+		burst := config.Burst
+		if burst <= 0 {
+			burst = config.MaxAttempts
+		}
+		return &tokenBucketRateLimiter{client: client, maxAttempts: config.MaxAttempts, window: config.Window, burst: burst}
+	default:
+		return &slidingWindowRateLimiter{client: client, maxAttempts: config.MaxAttempts, window: config.Window}
+	}
+}
+
+// slidingWindowRateLimiter is a Redis sorted-set log of attempt timestamps:
+// it trims everything older than Window, then allows the attempt only if
+// fewer than MaxAttempts remain.
+type slidingWindowRateLimiter struct {
+	client      *redis.Client
+	maxAttempts int
+	window      time.Duration
+}
+
+var slidingWindowScript = `
+	redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, ARGV[1])
+
+	local count = redis.call('ZCARD', KEYS[1])
+	local allowed = 0
+	if count < tonumber(ARGV[3]) then
+		redis.call('ZADD', KEYS[1], ARGV[2], ARGV[2] .. ':' .. ARGV[5])
+		redis.call('EXPIRE', KEYS[1], ARGV[4])
+		count = count + 1
+		allowed = 1
+	end
+
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	local resetAt = ARGV[2]
+	if oldest[2] ~= nil then
+		resetAt = oldest[2]
+	end
+
+	return {allowed, count, resetAt}
+`
+
+func (r *slidingWindowRateLimiter) Allow(key string) (Decision, error) {
+	ctx := context.Background()
+	now := time.Now()
+	windowStart := now.Unix() - int64(r.window.Seconds())
+
+	result, err := r.client.Eval(ctx, slidingWindowScript, []string{key},
+		windowStart,
+		now.Unix(),
+		r.maxAttempts,
+		int(r.window.Seconds()),
+		now.UnixNano(),
+	).Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to execute rate limit check: %w", err)
+	}
+
+	allowed := result[0].(int64) == 1
+	count := result[1].(int64)
+	resetAt, _ := strconv.ParseInt(result[2].(string), 10, 64)
+
+	return Decision{
+		Allowed:   allowed,
+		Limit:     r.maxAttempts,
+		Remaining: maxInt(0, r.maxAttempts-int(count)),
+		ResetAt:   time.Unix(resetAt, 0).Add(r.window),
+	}, nil
+}
+
+func (r *slidingWindowRateLimiter) Reset(key string) error {
+	return r.client.Del(context.Background(), key).Err()
+}
+
+// fixedWindowRateLimiter counts attempts in the current window-sized,
+// window-aligned bucket (e.g. the current clock minute for a 1m window) and
+// resets all at once at the boundary, trading perfect smoothness for a
+// single INCR instead of a growing sorted set.
+type fixedWindowRateLimiter struct {
+	client      *redis.Client
+	maxAttempts int
+	window      time.Duration
+}
+
+var fixedWindowScript = `
+	local count = redis.call('INCR', KEYS[1])
+	if count == 1 then
+		redis.call('EXPIRE', KEYS[1], ARGV[1])
+	end
+	local ttl = redis.call('TTL', KEYS[1])
+	if ttl < 0 then
+		ttl = tonumber(ARGV[1])
+	end
+	return {count, ttl}
+`
+
+func (r *fixedWindowRateLimiter) Allow(key string) (Decision, error) {
+	windowSeconds := int64(r.window.Seconds())
+	if windowSeconds <= 0 {
+		windowSeconds = 1
+	}
+	bucket := time.Now().Unix() / windowSeconds
+	bucketKey := fmt.Sprintf("%s:fw:%d", key, bucket)
+
+	result, err := r.client.Eval(context.Background(), fixedWindowScript, []string{bucketKey}, windowSeconds).Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to execute rate limit check: %w", err)
+	}
+
+	count := result[0].(int64)
+	ttl := result[1].(int64)
+
+	return Decision{
+		Allowed:   count <= int64(r.maxAttempts),
+		Limit:     r.maxAttempts,
+		Remaining: maxInt(0, r.maxAttempts-int(count)),
+		ResetAt:   time.Now().Add(time.Duration(ttl) * time.Second),
+	}, nil
+}
+
+func (r *fixedWindowRateLimiter) Reset(key string) error {
+	windowSeconds := int64(r.window.Seconds())
+	if windowSeconds <= 0 {
+		windowSeconds = 1
+	}
+	bucket := time.Now().Unix() / windowSeconds
+	return r.client.Del(context.Background(), fmt.Sprintf("%s:fw:%d", key, bucket)).Err()
+}
+
+// tokenBucketRateLimiter stores a bucket of fractional tokens per key in a
+// Redis hash, refilling it continuously (MaxAttempts tokens per Window) and
+// spending one token per allowed attempt, so a caller can burst up to burst
+// tokens at once (MaxAttempts when Burst is unset) and then trickles back in
+// at the configured rate.
+type tokenBucketRateLimiter struct {
+	client      *redis.Client
+	maxAttempts int
+	window      time.Duration
+	burst       int
+}
+
+var tokenBucketScript = `
+	local tokens = tonumber(redis.call('HGET', KEYS[1], 'tokens'))
+	local ts = tonumber(redis.call('HGET', KEYS[1], 'ts'))
+	local now = tonumber(ARGV[1])
+	local max = tonumber(ARGV[2])
+	local refillRate = tonumber(ARGV[3])
+
+	if tokens == nil then
+		tokens = max
+		ts = now
+	end
+
+	local delta = now - ts
+	if delta > 0 then
+		tokens = math.min(max, tokens + delta * refillRate)
+	end
+
+	local allowed = 0
+	if tokens >= 1 then
+		tokens = tokens - 1
+		allowed = 1
+	end
+
+	redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+	redis.call('EXPIRE', KEYS[1], ARGV[4])
+
+	return {allowed, tostring(tokens)}
+`
+
+func (r *tokenBucketRateLimiter) Allow(key string) (Decision, error) {
+	refillRate := float64(r.maxAttempts) / r.window.Seconds()
+	now := float64(time.Now().UnixNano()) / float64(time.Second)
+
+	result, err := r.client.Eval(context.Background(), tokenBucketScript, []string{key},
+		strconv.FormatFloat(now, 'f', -1, 64),
+		r.burst,
+		strconv.FormatFloat(refillRate, 'f', -1, 64),
+		int(r.window.Seconds()*2),
+	).Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to execute rate limit check: %w", err)
+	}
+
+	allowed := result[0].(int64) == 1
+	tokens, _ := strconv.ParseFloat(result[1].(string), 64)
+	remaining := int(tokens)
+
+	resetIn := time.Duration(0)
+	if remaining < r.burst && refillRate > 0 {
+		resetIn = time.Duration(float64(r.burst-remaining) / refillRate * float64(time.Second))
+	}
+
+	return Decision{
+		Allowed:   allowed,
+		Limit:     r.maxAttempts,
+		Remaining: remaining,
+		ResetAt:   time.Now().Add(resetIn),
+	}, nil
+}
+
+func (r *tokenBucketRateLimiter) Reset(key string) error {
+	return r.client.Del(context.Background(), key).Err()
+}
+
+// inProcessRateLimiter is a token-bucket RateLimiter with no external
+// dependencies, built on golang.org/x/time/rate: each key gets its own
+// *rate.Limiter with a burst of maxAttempts tokens that refills continuously
+// over window, so it behaves like the Redis token bucket strategy without
+// needing Redis. It's the fallback NewRateLimiter returns regardless of the
+// requested Strategy when no Redis client is available.
+type inProcessRateLimiter struct {
+	mu          sync.Mutex
+	maxAttempts int
+	window      time.Duration
+	burst       int
+	limiters    map[string]*rate.Limiter
+}
+
+func newInProcessRateLimiter(config RateLimiterConfig) *inProcessRateLimiter {
+	burst := config.Burst
+	if burst <= 0 {
+		burst = config.MaxAttempts
+	}
+	return &inProcessRateLimiter{
+		maxAttempts: config.MaxAttempts,
+		window:      config.Window,
+		burst:       burst,
+		limiters:    make(map[string]*rate.Limiter),
+	}
+}
+
+func (r *inProcessRateLimiter) limiterFor(key string) *rate.Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.limiters[key]
+	if !ok {
+		refillRate := rate.Limit(float64(r.maxAttempts) / r.window.Seconds())
+		l = rate.NewLimiter(refillRate, r.burst)
+		r.limiters[key] = l
+	}
+	return l
+}
+
+func (r *inProcessRateLimiter) Allow(key string) (Decision, error) {
+	if r.maxAttempts <= 0 || r.window <= 0 {
+		return Decision{Allowed: true, Limit: r.maxAttempts}, nil
+	}
+
+	l := r.limiterFor(key)
+	now := time.Now()
+	allowed := l.AllowN(now, 1)
+	tokens := l.Tokens()
+
+	refillRate := float64(r.maxAttempts) / r.window.Seconds()
+	resetIn := time.Duration(0)
+	if tokens < float64(r.burst) && refillRate > 0 {
+		resetIn = time.Duration((float64(r.burst)-tokens) / refillRate * float64(time.Second))
+	}
+
+	return Decision{
+		Allowed:   allowed,
+		Limit:     r.maxAttempts,
+		Remaining: maxInt(0, int(tokens)),
+		ResetAt:   now.Add(resetIn),
+	}, nil
+}
+
+func (r *inProcessRateLimiter) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.limiters, key)
+	return nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
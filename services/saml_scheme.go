@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+)
+
+// ErrSAMLNotImplemented is returned by SAMLScheme until real assertion
+// validation is wired in; it exists so the scheme can be registered and
+// routed to today without pretending to authenticate anyone.
+var ErrSAMLNotImplemented = errors.New("SAML assertion validation is not yet implemented")
+
+// SAMLAssertion is the credentials type SAMLScheme expects: the fields a
+// real implementation would extract from a validated <saml:Assertion>.
+type SAMLAssertion struct {
+	SubjectID string
+	Email     string
+}
+
+// SAMLScheme is a stub for SAML 2.0 SP-initiated login. It establishes the
+// RemoteIdentity-backed account linking that a real implementation would
+// use, but does not yet validate assertion signatures, so Login always
+// fails closed with ErrSAMLNotImplemented.
+type SAMLScheme struct {
+	userRepo           repositories.UserRepository
+	remoteIdentityRepo repositories.RemoteIdentityRepository
+}
+
+func NewSAMLScheme(userRepo repositories.UserRepository, remoteIdentityRepo repositories.RemoteIdentityRepository) *SAMLScheme {
+	return &SAMLScheme{
+		userRepo:           userRepo,
+		remoteIdentityRepo: remoteIdentityRepo,
+	}
+}
+
+func (s *SAMLScheme) Name() string {
+	return "saml"
+}
+
+// Create links a SAMLAssertion to a new user with an empty password, since
+// a SAML-only account never authenticates with one.
+func (s *SAMLScheme) Create(user *models.User, credentials interface{}) (*models.User, error) {
+	assertion, ok := credentials.(SAMLAssertion)
+	if !ok {
+		return nil, ErrInvalidCredentialsType
+	}
+
+	user.Email = assertion.Email
+	user.Password = ""
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.remoteIdentityRepo.Create(&models.RemoteIdentity{
+		UserID:    user.ID,
+		Provider:  s.Name(),
+		SubjectID: assertion.SubjectID,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *SAMLScheme) Login(ctx context.Context, credentials interface{}) (*models.User, error) {
+	return nil, ErrSAMLNotImplemented
+}
+
+func (s *SAMLScheme) Remove(user *models.User) error {
+	return s.remoteIdentityRepo.Delete(user.ID, s.Name())
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"EchoAuth/models"
+)
+
+var (
+	// ErrSchemeNotFound is returned by SchemeRegistry.Get for an unregistered
+	// scheme name.
+	ErrSchemeNotFound = errors.New("authentication scheme not found")
+	// ErrInvalidCredentialsType is returned by a Scheme when the concrete
+	// type of the credentials it received doesn't match what it expects.
+	ErrInvalidCredentialsType = errors.New("credentials are not of the type this scheme expects")
+)
+
+// Scheme is an authentication mechanism: native password login, OAuth,
+// SAML, etc. Each scheme owns how a user is created, authenticated, and
+// unlinked for that mechanism; credentials is scheme-specific and type
+// switched by the implementation.
+type Scheme interface {
+	Name() string
+	Create(user *models.User, credentials interface{}) (*models.User, error)
+	Login(ctx context.Context, credentials interface{}) (*models.User, error)
+	Remove(user *models.User) error
+}
+
+// SchemeRegistry looks up a Scheme by name so HTTP handlers can dispatch to
+// the one configured for a given route.
+type SchemeRegistry struct {
+	mu      sync.RWMutex
+	schemes map[string]Scheme
+}
+
+func NewSchemeRegistry() *SchemeRegistry {
+	return &SchemeRegistry{
+		schemes: make(map[string]Scheme),
+	}
+}
+
+// Register adds a scheme under its own Name(), replacing any existing
+// scheme registered under the same name.
+func (r *SchemeRegistry) Register(scheme Scheme) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemes[scheme.Name()] = scheme
+}
+
+// Get returns the scheme registered under name.
+func (r *SchemeRegistry) Get(name string) (Scheme, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	scheme, ok := r.schemes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSchemeNotFound, name)
+	}
+	return scheme, nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"EchoAuth/models"
+	"EchoAuth/repositories"
+
+	"github.com/google/uuid"
+)
+
+// SessionService exposes a user's active refresh-token sessions for a
+// "logged-in devices" UI: listing them, revoking one, and revoking every
+// other session at once when a user suspects one of their devices is
+// compromised.
+type SessionService struct {
+	tokenRepo repositories.TokenRepositoryInterface
+}
+
+func NewSessionService(tokenRepo repositories.TokenRepositoryInterface) *SessionService {
+	return &SessionService{tokenRepo: tokenRepo}
+}
+
+// ListActiveSessions returns a user's active refresh tokens as sessions.
+func (s *SessionService) ListActiveSessions(userID uint) ([]*models.Session, error) {
+	return s.tokenRepo.ListActiveSessions(userID)
+}
+
+// RevokeSession revokes a single session belonging to userID.
+func (s *SessionService) RevokeSession(userID uint, sessionID uuid.UUID) error {
+	return s.tokenRepo.RevokeSession(userID, sessionID)
+}
+
+// RevokeOtherSessions revokes every active session for userID except the one
+// backed by currentRefreshToken, so a user can end every other logged-in
+// device while staying logged in on the one making this request.
+func (s *SessionService) RevokeOtherSessions(userID uint, currentRefreshToken string) error {
+	return s.tokenRepo.RevokeAllExcept(userID, currentRefreshToken)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// revocationCacheTTL is how long a negative IsRevoked lookup is trusted
+// before it is re-checked against Redis, keeping the auth middleware's hot
+// path off the network on every request.
+const revocationCacheTTL = 30 * time.Second
+
+type revocationCacheEntry struct {
+	revoked   bool
+	checkedAt time.Time
+}
+
+// TokenRevocationService lets still-valid JWT access tokens be invalidated
+// before their exp by jti, without needing the token itself. Revocations are
+// stored in Redis with a TTL matching the token's remaining lifetime, so the
+// set prunes itself as tokens would have expired anyway.
+type TokenRevocationService struct {
+	redis *redis.Client
+
+	mu    sync.Mutex
+	cache map[string]revocationCacheEntry
+}
+
+func NewTokenRevocationService(redis *redis.Client) *TokenRevocationService {
+	return &TokenRevocationService{
+		redis: redis,
+		cache: make(map[string]revocationCacheEntry),
+	}
+}
+
+// Revoke invalidates the access token identified by jti until expiresAt.
+func (s *TokenRevocationService) Revoke(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil // already expired, nothing to revoke
+	}
+
+	ctx := context.Background()
+	if err := s.redis.Set(ctx, revokedJTIKey(jti), true, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[jti] = revocationCacheEntry{revoked: true, checkedAt: time.Now()}
+	s.mu.Unlock()
+
+	return nil
+}
+
+// IsRevoked reports whether the access token identified by jti has been
+// revoked. Results are cached in-process for revocationCacheTTL to avoid a
+// Redis round trip on every request.
+func (s *TokenRevocationService) IsRevoked(jti string) (bool, error) {
+	s.mu.Lock()
+	entry, ok := s.cache[jti]
+	s.mu.Unlock()
+	if ok && time.Since(entry.checkedAt) < revocationCacheTTL {
+		return entry.revoked, nil
+	}
+
+	ctx := context.Background()
+	exists, err := s.redis.Exists(ctx, revokedJTIKey(jti)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	revoked := exists == 1
+
+	s.mu.Lock()
+	s.cache[jti] = revocationCacheEntry{revoked: revoked, checkedAt: time.Now()}
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+// TrackIssued records that jti was issued to userID and expires at
+// expiresAt, so a later RevokeAllForUser can find and revoke it even though
+// it was never individually presented for revocation.
+func (s *TokenRevocationService) TrackIssued(userID uint, jti string, expiresAt time.Time) error {
+	ctx := context.Background()
+	key := userJTIsKey(userID)
+
+	pipe := s.redis.Pipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(expiresAt.Unix()), Member: jti})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("%d", time.Now().Unix()))
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to track issued token: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every access token still outstanding for userID.
+// It also advances userID's revoked-before cutoff to now, so a token this
+// sweep missed - one issued between TrackIssued and this call, or one from
+// before this service was ever wired in - is still caught by
+// IsRevokedSince's single O(1) lookup rather than depending on every jti
+// having been tracked individually.
+func (s *TokenRevocationService) RevokeAllForUser(userID uint) error {
+	ctx := context.Background()
+	key := userJTIsKey(userID)
+
+	entries, err := s.redis.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min: fmt.Sprintf("%d", time.Now().Unix()),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list outstanding tokens: %w", err)
+	}
+
+	for _, entry := range entries {
+		jti, ok := entry.Member.(string)
+		if !ok {
+			continue
+		}
+		if err := s.Revoke(jti, time.Unix(int64(entry.Score), 0)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.redis.Del(ctx, key).Err(); err != nil {
+		return err
+	}
+
+	return s.redis.Set(ctx, revokedBeforeKey(userID), time.Now().Unix(), 0).Err()
+}
+
+// IsRevokedSince reports whether userID's access tokens were revoked at or
+// after issuedAt - i.e. whether issuedAt predates the cutoff RevokeAllForUser
+// last set. A user who has never had all their tokens revoked has no
+// cutoff key at all, so this is false without ever touching the per-jti
+// revocation set.
+func (s *TokenRevocationService) IsRevokedSince(userID uint, issuedAt time.Time) (bool, error) {
+	ctx := context.Background()
+	cutoff, err := s.redis.Get(ctx, revokedBeforeKey(userID)).Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked-before cutoff: %w", err)
+	}
+	return !issuedAt.After(time.Unix(cutoff, 0)), nil
+}
+
+func revokedJTIKey(jti string) string {
+	return "revoked_jti:" + jti
+}
+
+func userJTIsKey(userID uint) string {
+	return fmt.Sprintf("access_jtis:%d", userID)
+}
+
+func revokedBeforeKey(userID uint) string {
+	return fmt.Sprintf("revoked_before:%d", userID)
+}
@@ -0,0 +1,55 @@
+package tls
+
+import (
+	"EchoAuth/database"
+	"context"
+	"database/sql"
+	"errors"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// DBCache persists autocert certificates and account keys in the
+// tls_certificates table via the existing database.DB, so a freshly
+// started instance doesn't have to re-issue certificates it already
+// holds. It's an alternative to autocert.DirCache for deployments that
+// don't have (or want) a writable local disk.
+type DBCache struct {
+	db *database.DB
+}
+
+// NewDBCache builds a DBCache backed by db.
+func NewDBCache(db *database.DB) *DBCache {
+	return &DBCache{db: db}
+}
+
+var _ autocert.Cache = (*DBCache)(nil)
+
+// Get implements autocert.Cache.
+func (c *DBCache) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := c.db.QueryRowContext(ctx, `SELECT data FROM tls_certificates WHERE cache_key = $1`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, autocert.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Put implements autocert.Cache.
+func (c *DBCache) Put(ctx context.Context, key string, data []byte) error {
+	_, err := c.db.ExecContext(ctx, `
+		INSERT INTO tls_certificates (cache_key, data, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (cache_key) DO UPDATE SET data = EXCLUDED.data, updated_at = EXCLUDED.updated_at`,
+		key, data)
+	return err
+}
+
+// Delete implements autocert.Cache.
+func (c *DBCache) Delete(ctx context.Context, key string) error {
+	_, err := c.db.ExecContext(ctx, `DELETE FROM tls_certificates WHERE cache_key = $1`, key)
+	return err
+}
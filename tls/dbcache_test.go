@@ -0,0 +1,65 @@
+package tls
+
+import (
+	"EchoAuth/database"
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestDBCacheGetMiss(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	cache := NewDBCache(&database.DB{DB: mockDB})
+
+	mock.ExpectQuery("SELECT data FROM tls_certificates").
+		WithArgs("example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"data"}))
+
+	_, err = cache.Get(context.Background(), "example.com")
+	assert.ErrorIs(t, err, autocert.ErrCacheMiss)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBCacheGetHit(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	cache := NewDBCache(&database.DB{DB: mockDB})
+
+	want := []byte("certificate-bytes")
+	mock.ExpectQuery("SELECT data FROM tls_certificates").
+		WithArgs("example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow(want))
+
+	got, err := cache.Get(context.Background(), "example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBCachePutThenDelete(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	cache := NewDBCache(&database.DB{DB: mockDB})
+
+	mock.ExpectExec("INSERT INTO tls_certificates").
+		WithArgs("example.com", []byte("cert")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	assert.NoError(t, cache.Put(context.Background(), "example.com", []byte("cert")))
+
+	mock.ExpectExec("DELETE FROM tls_certificates").
+		WithArgs("example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, cache.Delete(context.Background(), "example.com"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,42 @@
+// Package tls decides how the server terminates TLS: no TLS at all for
+// local development, a static certificate/key pair, or automatic
+// certificate issuance and renewal via ACME (e.g. Let's Encrypt) for a
+// known set of domains.
+package tls
+
+import (
+	"EchoAuth/database"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Mode selects how the server terminates TLS.
+type Mode string
+
+const (
+	// ModeOff serves plain HTTP, the local development default.
+	ModeOff Mode = "off"
+	// ModeFiles serves HTTPS from a static certificate/key pair on disk.
+	ModeFiles Mode = "files"
+	// ModeAutocert obtains and renews certificates automatically from an
+	// ACME certificate authority for a known set of domains.
+	ModeAutocert Mode = "autocert"
+)
+
+// NewManager builds an autocert.Manager restricted to domains. Issued
+// certificates are cached on disk under cacheDir if it's non-empty,
+// otherwise in the tls_certificates table via db.
+func NewManager(domains []string, cacheDir string, db *database.DB) *autocert.Manager {
+	var cache autocert.Cache
+	if cacheDir != "" {
+		cache = autocert.DirCache(cacheDir)
+	} else {
+		cache = NewDBCache(db)
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      cache,
+	}
+}
@@ -0,0 +1,82 @@
+package tls
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestNewManagerUsesDirCacheWhenCacheDirSet(t *testing.T) {
+	mgr := NewManager([]string{"example.com"}, t.TempDir(), nil)
+
+	_, ok := mgr.Cache.(autocert.DirCache)
+	assert.True(t, ok, "expected a DirCache when cacheDir is set")
+}
+
+func TestNewManagerUsesDBCacheWhenCacheDirEmpty(t *testing.T) {
+	mgr := NewManager([]string{"example.com"}, "", nil)
+
+	_, ok := mgr.Cache.(*DBCache)
+	assert.True(t, ok, "expected a DBCache when cacheDir is empty")
+}
+
+// TestManagerRejectsUnlistedHostWithoutContactingCA verifies the
+// HostPolicy is enforced before any ACME network call is made: a
+// ClientHello for a domain outside the whitelist must be rejected
+// locally, never reaching the CA.
+func TestManagerRejectsUnlistedHostWithoutContactingCA(t *testing.T) {
+	var caHits int32
+	ca := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&caHits, 1)
+		w.WriteHeader(http.StatusNotImplemented)
+	}))
+	defer ca.Close()
+
+	mgr := NewManager([]string{"example.com"}, t.TempDir(), nil)
+	mgr.Client = &acme.Client{DirectoryURL: ca.URL}
+
+	_, err := mgr.GetCertificate(&tls.ClientHelloInfo{ServerName: "not-allowed.example"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&caHits), "HostPolicy rejection must not contact the CA")
+}
+
+// TestManagerFetchesDirectoryForAllowedHost stands up a fake ACME
+// directory endpoint and verifies that, for an allowed domain with
+// nothing cached yet, the manager actually reaches out to it. Fully
+// simulating certificate issuance would require implementing the whole
+// ACME order/authorization/finalize flow, which is out of scope for this
+// unit test; fetching the directory is the first step of that flow and is
+// enough to prove the manager is wired to the configured CA.
+func TestManagerFetchesDirectoryForAllowedHost(t *testing.T) {
+	var directoryFetched int32
+	ca := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/directory" {
+			atomic.AddInt32(&directoryFetched, 1)
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"newNonce":   "",
+				"newAccount": "",
+				"newOrder":   "",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNotImplemented)
+	}))
+	defer ca.Close()
+
+	mgr := NewManager([]string{"example.com"}, t.TempDir(), nil)
+	mgr.Client = &acme.Client{DirectoryURL: ca.URL + "/directory"}
+
+	// The fake CA can't complete an actual issuance, so this is expected
+	// to fail once the manager moves past directory discovery.
+	_, _ = mgr.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+
+	assert.Greater(t, atomic.LoadInt32(&directoryFetched), int32(0), "expected the manager to fetch the ACME directory for an allowed host")
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"os"
 	"time"
 
@@ -17,8 +18,17 @@ func Init() {
 		})
 	}
 
-	// Set global log level
-	level := os.Getenv("LOG_LEVEL")
+	SetLevel(os.Getenv("LOG_LEVEL"))
+
+	// Add timestamp to all logs
+	zerolog.TimeFieldFormat = time.RFC3339
+}
+
+// SetLevel sets zerolog's global level from a LOG_LEVEL-style string
+// ("debug", "info", "warn", "error"), defaulting to info for anything else.
+// It's also called from config.Loader.Watch's reload loop, so LOG_LEVEL can
+// be turned up for an incident without restarting the process.
+func SetLevel(level string) {
 	switch level {
 	case "debug":
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
@@ -31,12 +41,25 @@ func Init() {
 	default:
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	}
-
-	// Add timestamp to all logs
-	zerolog.TimeFieldFormat = time.RFC3339
 }
 
 // GetLogger returns a logger with the given component name
 func GetLogger(component string) zerolog.Logger {
 	return log.With().Str("component", component).Logger()
 }
+
+// FromContext returns a logger carrying the request_id middlewares.CorrelationID
+// attached to ctx, so a service method handling one HTTP or gRPC call can emit
+// log lines an operator can correlate back to that request - and to the
+// audit.Event the same request stamps with the same ID. ctx carrying no
+// correlation_id (a background job, a test) falls back to the unscoped
+// default logger.
+func FromContext(ctx context.Context) zerolog.Logger {
+	if ctx == nil {
+		return log.Logger
+	}
+	if requestID, ok := ctx.Value("correlation_id").(string); ok && requestID != "" {
+		return log.With().Str("request_id", requestID).Logger()
+	}
+	return log.Logger
+}
@@ -5,16 +5,26 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
+	// RequestDuration is labeled by route template (see RouteTemplate), not
+	// raw path, so /api/users/123 and /api/users/456 collapse to one series
+	// instead of one per ID. NativeHistogramBucketFactor turns on Prometheus
+	// native (sparse, exponential) histograms alongside the classic buckets,
+	// so a single series can support both coarse dashboards and precise
+	// quantile queries without operators having to pick bucket boundaries
+	// per route up front.
 	RequestDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "Duration of HTTP requests in seconds",
-			Buckets: prometheus.DefBuckets,
+			Name:                        "http_request_duration_seconds",
+			Help:                        "Duration of HTTP requests in seconds",
+			Buckets:                     prometheus.DefBuckets,
+			NativeHistogramBucketFactor: 1.1,
 		},
 		[]string{"path", "method", "status_code"},
 	)
@@ -27,26 +37,68 @@ var (
 		[]string{"operation", "status"},
 	)
 
+	// AuthenticationAttempts is labeled by provider ("local" for native
+	// email+password login, or an OAuth/OIDC scheme name like "google" or
+	// "keycloak") so a dashboard can tell whether a spike in failures is
+	// coming from one federated identity provider rather than local
+	// credential stuffing.
 	AuthenticationAttempts = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "authentication_attempts_total",
-			Help: "Total number of authentication attempts",
+			Help: "Total number of authentication attempts, by provider",
 		},
-		[]string{"status"},
+		[]string{"status", "provider"},
 	)
 
+	// ActiveTokens counts distinct active refresh-token families (i.e.
+	// logged-in sessions) rather than individual refresh tokens, since every
+	// rotation mints a new token row for the same session - counting rows
+	// would inflate with rotation frequency instead of reflecting how many
+	// sessions are actually open.
 	ActiveTokens = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "active_tokens",
-			Help: "Number of currently active tokens",
+			Help: "Number of currently active refresh-token families (sessions)",
 		},
 	)
 
-	RateLimitHits = prometheus.NewCounter(
+	RateLimitHits = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "rate_limit_hits_total",
-			Help: "Total number of rate limit hits",
+			Help: "Total number of rate limit hits, by the rule that rejected the request",
+		},
+		[]string{"rule"},
+	)
+
+	AuthAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "auth_attempts_total",
+			Help: "Total number of authentication-related attempts, by flow type and result",
+		},
+		[]string{"type", "result"},
+	)
+
+	RPCDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "rpc_request_duration_seconds",
+			Help:    "Duration of gRPC requests in seconds, the rpc_* counterpart of http_request_duration_seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "code"},
+	)
+
+	// RefreshTokenReuseDetected counts confirmed refresh-token replays (see
+	// repositories.TokenRepository.DetectReuse), labeled by a coarse,
+	// low-cardinality bucket of the affected user ID rather than the ID
+	// itself, so a dashboard can show whether reuse is concentrated in a
+	// narrow band of accounts without this series' cardinality tracking the
+	// size of the user table.
+	RefreshTokenReuseDetected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "refresh_token_reuse_detected_total",
+			Help: "Total number of detected refresh token reuse (replay) events, by affected user ID bucket",
 		},
+		[]string{"user_id_class"},
 	)
 )
 
@@ -56,19 +108,50 @@ func init() {
 	prometheus.MustRegister(AuthenticationAttempts)
 	prometheus.MustRegister(ActiveTokens)
 	prometheus.MustRegister(RateLimitHits)
+	prometheus.MustRegister(AuthAttempts)
+	prometheus.MustRegister(RPCDuration)
+	prometheus.MustRegister(RefreshTokenReuseDetected)
 }
 
-// RecordRequestDuration is middleware that records the duration of HTTP requests
+// RecordRequestDuration is middleware that records the duration of HTTP
+// requests. When r carries a valid OpenTelemetry span (e.g. attached by
+// middlewares.Tracing upstream of this one), the observation is attached as
+// a Prometheus exemplar pointing at that trace, so an operator looking at a
+// slow bucket in Grafana can jump straight to the trace that landed there.
 func RecordRequestDuration(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		rec := &statusRecorder{ResponseWriter: w, Status: http.StatusOK}
 		next.ServeHTTP(rec, r)
 		duration := time.Since(start).Seconds()
-		RequestDuration.WithLabelValues(r.URL.Path, r.Method, strconv.Itoa(rec.Status)).Observe(duration)
+
+		observer := RequestDuration.WithLabelValues(RouteTemplate(r), r.Method, strconv.Itoa(rec.Status))
+		if spanCtx := trace.SpanContextFromContext(r.Context()); spanCtx.IsValid() {
+			observer.(prometheus.ExemplarObserver).ObserveWithExemplar(duration, prometheus.Labels{
+				"trace_id": spanCtx.TraceID().String(),
+				"span_id":  spanCtx.SpanID().String(),
+			})
+			return
+		}
+		observer.Observe(duration)
 	})
 }
 
+// RouteTemplate returns the normalized route pattern the gorilla/mux router
+// matched for r (e.g. "/api/EchoAuth/invitations/{token}"), so metrics and
+// traces for /api/EchoAuth/invitations/abc and .../xyz collapse to one
+// series instead of one per token. Falls back to the raw path when r wasn't
+// routed through mux (e.g. unit tests calling a handler directly), or for
+// requests that never matched a route at all (404s).
+func RouteTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return r.URL.Path
+}
+
 // statusRecorder wraps http.ResponseWriter to capture status code
 type statusRecorder struct {
 	http.ResponseWriter
@@ -85,23 +168,61 @@ func RecordDatabaseOperation(operation, status string) {
 	DatabaseOperations.WithLabelValues(operation, status).Inc()
 }
 
-// RecordAuthenticationAttempt records an authentication attempt
-func RecordAuthenticationAttempt(success bool) {
+// RecordAuthenticationAttempt records an authentication attempt against the
+// named provider ("local" for native email+password login).
+func RecordAuthenticationAttempt(success bool, provider string) {
 	status := "success"
 	if !success {
 		status = "failure"
 	}
-	AuthenticationAttempts.WithLabelValues(status).Inc()
+	AuthenticationAttempts.WithLabelValues(status, provider).Inc()
 }
 
-// RecordActiveTokens sets the current number of active tokens
+// RecordActiveTokens sets the current number of active refresh-token
+// families (see ActiveTokens).
 func RecordActiveTokens(count int) {
 	ActiveTokens.Set(float64(count))
 }
 
-// RecordRateLimitHit increments the rate limit hits counter
-func RecordRateLimitHit() {
-	RateLimitHits.Inc()
+// RecordRateLimitHit increments the rate limit hits counter for the rule
+// that rejected the request (e.g. "login_by_ip", "login_by_email").
+func RecordRateLimitHit(rule string) {
+	RateLimitHits.WithLabelValues(rule).Inc()
+}
+
+// RecordAuthAttempt increments the auth attempts counter for a given flow
+// type (e.g. "login", "refresh") and result (e.g. "success", "reuse_detected")
+func RecordAuthAttempt(flowType, result string) {
+	AuthAttempts.WithLabelValues(flowType, result).Inc()
+}
+
+// userIDClassWidth is the bucket width RecordRefreshTokenReuse groups user
+// IDs into, trading off granularity (did reuse cluster around one ID?)
+// against cardinality (one series per ID would grow with the user table).
+const userIDClassWidth = 1000
+
+// userIDClass buckets userID into a fixed-width range (e.g. "0-999",
+// "1000-1999"), for counters that need to vary by user without a
+// label cardinality proportional to the number of users.
+func userIDClass(userID uint) string {
+	bucket := userID / userIDClassWidth
+	lower := bucket * userIDClassWidth
+	upper := lower + userIDClassWidth - 1
+	return strconv.FormatUint(uint64(lower), 10) + "-" + strconv.FormatUint(uint64(upper), 10)
+}
+
+// RecordRefreshTokenReuse increments RefreshTokenReuseDetected for the
+// bucket containing userID, the account a replayed refresh token belonged
+// to.
+func RecordRefreshTokenReuse(userID uint) {
+	RefreshTokenReuseDetected.WithLabelValues(userIDClass(userID)).Inc()
+}
+
+// RecordRPCDuration records how long a gRPC method took to handle and the
+// status code it finished with, the rpc_* counterpart of
+// RecordRequestDuration for the HTTP router.
+func RecordRPCDuration(method, code string, duration time.Duration) {
+	RPCDuration.WithLabelValues(method, code).Observe(duration.Seconds())
 }
 
 // Handler returns an HTTP handler for the metrics endpoint
@@ -160,14 +160,14 @@ func TestRecordAuthenticationAttempt(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Record authentication attempt
-			RecordAuthenticationAttempt(tt.success)
+			RecordAuthenticationAttempt(tt.success, "local")
 
 			// Verify metric was recorded with correct labels and value
 			status := "success"
 			if !tt.success {
 				status = "failure"
 			}
-			count := testutil.ToFloat64(AuthenticationAttempts.WithLabelValues(status))
+			count := testutil.ToFloat64(AuthenticationAttempts.WithLabelValues(status, "local"))
 			if count != tt.wantCount {
 				t.Errorf("AuthenticationAttempts count = %v, want %v", count, tt.wantCount)
 			}
@@ -175,6 +175,123 @@ func TestRecordAuthenticationAttempt(t *testing.T) {
 	}
 }
 
+func TestRecordAuthAttempt(t *testing.T) {
+	// Reset metrics before test
+	prometheus.Unregister(AuthAttempts)
+	prometheus.MustRegister(AuthAttempts)
+
+	tests := []struct {
+		name      string
+		flowType  string
+		result    string
+		wantCount float64
+	}{
+		{
+			name:      "Successful refresh",
+			flowType:  "refresh",
+			result:    "success",
+			wantCount: 1,
+		},
+		{
+			name:      "Refresh token reuse detected",
+			flowType:  "refresh",
+			result:    "reuse_detected",
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RecordAuthAttempt(tt.flowType, tt.result)
+
+			count := testutil.ToFloat64(AuthAttempts.WithLabelValues(tt.flowType, tt.result))
+			if count != tt.wantCount {
+				t.Errorf("AuthAttempts count = %v, want %v", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestRecordRateLimitHit(t *testing.T) {
+	// Reset metrics before test
+	prometheus.Unregister(RateLimitHits)
+	prometheus.MustRegister(RateLimitHits)
+
+	tests := []struct {
+		name      string
+		rule      string
+		wantCount float64
+	}{
+		{
+			name:      "Login by email hit",
+			rule:      "login_by_email",
+			wantCount: 1,
+		},
+		{
+			name:      "Refresh by user hit",
+			rule:      "refresh_by_user",
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RecordRateLimitHit(tt.rule)
+
+			count := testutil.ToFloat64(RateLimitHits.WithLabelValues(tt.rule))
+			if count != tt.wantCount {
+				t.Errorf("RateLimitHits count = %v, want %v", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestRecordRefreshTokenReuse(t *testing.T) {
+	// Reset metrics before test
+	prometheus.Unregister(RefreshTokenReuseDetected)
+	prometheus.MustRegister(RefreshTokenReuseDetected)
+
+	tests := []struct {
+		name       string
+		userID     uint
+		wantBucket string
+	}{
+		{
+			name:       "low ID falls in the first bucket",
+			userID:     42,
+			wantBucket: "0-999",
+		},
+		{
+			name:       "higher ID falls in a later bucket",
+			userID:     1500,
+			wantBucket: "1000-1999",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RecordRefreshTokenReuse(tt.userID)
+
+			count := testutil.ToFloat64(RefreshTokenReuseDetected.WithLabelValues(tt.wantBucket))
+			if count != 1 {
+				t.Errorf("RefreshTokenReuseDetected{user_id_class=%q} = %v, want 1", tt.wantBucket, count)
+			}
+		})
+	}
+}
+
+func TestRecordRPCDuration(t *testing.T) {
+	// Reset metrics before test
+	prometheus.Unregister(RPCDuration)
+	prometheus.MustRegister(RPCDuration)
+
+	RecordRPCDuration("/echoauth.v1.AuthService/Login", "OK", 10*time.Millisecond)
+
+	if testutil.CollectAndCount(RPCDuration) == 0 {
+		t.Error("Expected rpc duration metric to be recorded")
+	}
+}
+
 func TestRecordActiveTokens(t *testing.T) {
 	// Reset metrics before test
 	prometheus.Unregister(ActiveTokens)
@@ -218,7 +335,7 @@ func TestMetricsEndpoint(t *testing.T) {
 
 	// Record some test metrics
 	RecordDatabaseOperation("create", "success")
-	RecordAuthenticationAttempt(true)
+	RecordAuthenticationAttempt(true, "local")
 	RecordActiveTokens(3)
 
 	// Call metrics endpoint
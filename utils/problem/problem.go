@@ -0,0 +1,168 @@
+// Package problem builds RFC 7807 ("Problem Details for HTTP APIs")
+// response bodies, so a client can distinguish failure modes (e.g.
+// "invalid credentials" from "account locked") by a stable machine-readable
+// "code" instead of parsing the "error" string utils/response.JSONError
+// produces.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"EchoAuth/repositories"
+	"EchoAuth/services"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ContentType is the media type RFC 7807 section 6.1 reserves for Problem
+// Details responses.
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 Problem Details body. Extensions holds any
+// additional, problem-type-specific members (e.g. "code", "invalid_params")
+// and is flattened into the top-level JSON object by MarshalJSON, the way
+// RFC 7807 section 3.2 describes extension members being used.
+type Problem struct {
+	Type       string         `json:"type,omitempty"`
+	Title      string         `json:"title"`
+	Status     int            `json:"status"`
+	Detail     string         `json:"detail,omitempty"`
+	Instance   string         `json:"instance,omitempty"`
+	Extensions map[string]any `json:"-"`
+}
+
+// MarshalJSON flattens Extensions alongside the RFC 7807 members instead of
+// nesting them under their own key, so a client that only understands
+// "code" doesn't also need to know to look inside "extensions" for it.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type alias Problem
+	base, err := json.Marshal((*alias)(p))
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Extensions) == 0 {
+		return base, nil
+	}
+
+	merged := make(map[string]any, len(p.Extensions)+5)
+	if err := json.Unmarshal(base, &merged); err != nil {
+		return nil, err
+	}
+	for k, v := range p.Extensions {
+		merged[k] = v
+	}
+	return json.Marshal(merged)
+}
+
+// New builds a Problem with Type defaulting to "about:blank", RFC 7807's
+// default for a problem that doesn't define its own type URI.
+func New(status int, title, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  title,
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Write sends p as an application/problem+json response.
+func Write(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// InvalidParam is one field-level validation failure, reported as an
+// element of the Problem's "invalid_params" extension array.
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Validation turns go-playground/validator field errors into a Problem
+// whose "invalid_params" extension lists one InvalidParam per failed field,
+// so a client can highlight the specific fields that were rejected instead
+// of pattern-matching validator's free-text message.
+func Validation(fieldErrors validator.ValidationErrors) *Problem {
+	params := make([]InvalidParam, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		params = append(params, InvalidParam{
+			Name:   fe.Field(),
+			Reason: validationReason(fe),
+		})
+	}
+
+	p := New(http.StatusBadRequest, "Validation Failed", "One or more fields failed validation")
+	p.Extensions = map[string]any{"invalid_params": params}
+	return p
+}
+
+// validationReason renders a FieldError's tag as a human-readable reason.
+// An unrecognized tag still names itself rather than producing a blank
+// reason, since validator.New accepts custom tags this switch won't know
+// about.
+func validationReason(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of [%s]", fe.Param())
+	default:
+		return fmt.Sprintf("failed %q validation", fe.Tag())
+	}
+}
+
+// knownError is the status/title/code From reports for one sentinel error
+// exported by repositories or services.
+type knownError struct {
+	status int
+	title  string
+	code   string
+}
+
+// knownErrors maps the sentinel errors repositories and services export to
+// the Problem fields From should report for them. An error not listed here
+// still produces a Problem (see From's fallback) rather than requiring
+// every caller to handle an unmapped error itself.
+var knownErrors = map[error]knownError{
+	repositories.ErrNotFound:           {http.StatusNotFound, "Not Found", "NOT_FOUND"},
+	services.ErrInvalidCredentials:     {http.StatusUnauthorized, "Invalid Credentials", "INVALID_CREDENTIALS"},
+	services.ErrUserExists:             {http.StatusConflict, "User Already Exists", "USER_EXISTS"},
+	services.ErrAccountLocked:          {http.StatusLocked, "Account Locked", "ACCOUNT_LOCKED"},
+	services.ErrTokenBlacklisted:       {http.StatusUnauthorized, "Token Blacklisted", "TOKEN_BLACKLISTED"},
+	services.ErrPasswordExpired:        {http.StatusForbidden, "Password Expired", "PASSWORD_EXPIRED"},
+	services.ErrInvalidScope:           {http.StatusBadRequest, "Invalid Scope", "INVALID_SCOPE"},
+	services.ErrEmailNotVerified:       {http.StatusForbidden, "Email Not Verified", "EMAIL_NOT_VERIFIED"},
+	services.ErrRefreshTokenIdle:       {http.StatusUnauthorized, "Refresh Token Idle", "REFRESH_TOKEN_IDLE"},
+	services.ErrRefreshTokenReused:     {http.StatusUnauthorized, "Refresh Token Reused", "REFRESH_TOKEN_REUSED"},
+	services.ErrAlreadyInvited:         {http.StatusConflict, "Already Invited", "ALREADY_INVITED"},
+	services.ErrSAMLNotImplemented:     {http.StatusNotImplemented, "Not Implemented", "SAML_NOT_IMPLEMENTED"},
+	services.ErrSchemeNotFound:         {http.StatusBadRequest, "Unknown Scheme", "SCHEME_NOT_FOUND"},
+	services.ErrInvalidCredentialsType: {http.StatusBadRequest, "Invalid Credentials Type", "INVALID_CREDENTIALS_TYPE"},
+}
+
+// From unwraps err against the sentinel errors repositories and services
+// export and returns the Problem to report for it, with a "code" extension
+// set to a stable machine-readable string a client can switch on. An
+// unrecognized err still produces a Problem - status 500, title "Internal
+// Server Error" - rather than leaving the caller to invent its own
+// fallback.
+func From(err error) *Problem {
+	for sentinel, ke := range knownErrors {
+		if errors.Is(err, sentinel) {
+			p := New(ke.status, ke.title, err.Error())
+			p.Extensions = map[string]any{"code": ke.code}
+			return p
+		}
+	}
+	return New(http.StatusInternalServerError, "Internal Server Error", err.Error())
+}
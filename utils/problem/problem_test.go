@@ -0,0 +1,119 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"EchoAuth/repositories"
+	"EchoAuth/services"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestProblemMarshalJSON(t *testing.T) {
+	p := New(http.StatusBadRequest, "Validation Failed", "one or more fields failed validation")
+	p.Extensions = map[string]any{"code": "VALIDATION_FAILED", "retry_after": 5}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+
+	if got["title"] != "Validation Failed" || got["status"] != float64(http.StatusBadRequest) {
+		t.Errorf("core RFC 7807 fields missing or wrong: %v", got)
+	}
+	if got["code"] != "VALIDATION_FAILED" {
+		t.Errorf("expected Extensions to be flattened to the top level, got %v", got)
+	}
+	if got["retry_after"] != float64(5) {
+		t.Errorf("expected retry_after extension flattened, got %v", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	Write(w, New(http.StatusNotFound, "Not Found", "no such resource"))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("expected Content-Type %q, got %q", ContentType, ct)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	type request struct {
+		Email    string `validate:"required,email"`
+		Password string `validate:"required,min=8"`
+	}
+
+	err := validator.New().Struct(request{Email: "not-an-email", Password: "short"})
+	fieldErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		t.Fatalf("expected validator.ValidationErrors, got %T", err)
+	}
+
+	p := Validation(fieldErrors)
+
+	if p.Status != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, p.Status)
+	}
+
+	params, ok := p.Extensions["invalid_params"].([]InvalidParam)
+	if !ok {
+		t.Fatalf("expected invalid_params to be []InvalidParam, got %T", p.Extensions["invalid_params"])
+	}
+	if len(params) != 2 {
+		t.Errorf("expected 2 invalid params, got %d", len(params))
+	}
+}
+
+func TestFromKnownError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"not found", repositories.ErrNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{"invalid credentials", services.ErrInvalidCredentials, http.StatusUnauthorized, "INVALID_CREDENTIALS"},
+		{"account locked", services.ErrAccountLocked, http.StatusLocked, "ACCOUNT_LOCKED"},
+		{"refresh token reused", services.ErrRefreshTokenReused, http.StatusUnauthorized, "REFRESH_TOKEN_REUSED"},
+		{"wrapped known error", errors.New("wrapping not supported here"), http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := From(tt.err)
+			if p.Status != tt.wantStatus {
+				t.Errorf("Status = %d, want %d", p.Status, tt.wantStatus)
+			}
+			if tt.wantCode != "" && p.Extensions["code"] != tt.wantCode {
+				t.Errorf("code = %v, want %q", p.Extensions["code"], tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFromUnknownError(t *testing.T) {
+	p := From(errors.New("something went wrong"))
+
+	if p.Status != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, p.Status)
+	}
+	if p.Detail != "something went wrong" {
+		t.Errorf("expected detail to carry the error message, got %q", p.Detail)
+	}
+	if _, hasCode := p.Extensions["code"]; hasCode {
+		t.Errorf("unrecognized errors should not get a code extension")
+	}
+}
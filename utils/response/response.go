@@ -0,0 +1,29 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JSONResponse writes data to w as JSON with the given status code.
+func JSONResponse(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// JSONError writes a {"error": message} JSON body to w with the given
+// status code, plus the RFC 7807 Problem Details fields ("type", "title",
+// "status", "detail") inline in the same object, so a client written
+// against either schema gets one it understands during the migration to
+// the problem package. New code should prefer problem.Write over calling
+// this directly.
+func JSONError(w http.ResponseWriter, message string, status int) {
+	JSONResponse(w, map[string]interface{}{
+		"error":  message,
+		"type":   "about:blank",
+		"title":  http.StatusText(status),
+		"status": status,
+		"detail": message,
+	}, status)
+}
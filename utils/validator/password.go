@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrPasswordTooShort  = errors.New("password must be at least 8 characters long")
+	ErrPasswordTooSimple = errors.New("password must contain at least one uppercase letter, one lowercase letter, one number, and one special character")
+	ErrPasswordCommon    = errors.New("password is too common or easily guessable")
+)
+
+// WeakPasswordError is returned when a password meets the composition rule
+// but EstimateStrength still finds it too easily guessed. Feedback explains
+// why, in a form the API layer can pass straight through to the client.
+type WeakPasswordError struct {
+	Score    int
+	Feedback []string
+}
+
+func (e *WeakPasswordError) Error() string {
+	return ErrPasswordCommon.Error() + ": " + strings.Join(e.Feedback, "; ")
+}
+
+func (e *WeakPasswordError) Unwrap() error {
+	return ErrPasswordCommon
+}
+
+// ValidatePassword checks if a password meets security requirements: a
+// minimum length, a mix of character classes, and an estimated strength
+// score (see EstimateStrength) of at least minAcceptableScore.
+func ValidatePassword(password string) error {
+	if err := validateComposition(password); err != nil {
+		return err
+	}
+	return validateStrength(password, nil)
+}
+
+// ValidatePasswordForUser is ValidatePassword plus a strength check against
+// the account's own details, so a password built from the user's email or
+// name is rejected even though it passes the composition rule.
+func ValidatePasswordForUser(password string, userInputs []string) error {
+	if err := validateComposition(password); err != nil {
+		return err
+	}
+	return validateStrength(password, userInputs)
+}
+
+func validateComposition(password string) error {
+	if len(password) < 8 {
+		return ErrPasswordTooShort
+	}
+
+	var (
+		hasUpper   bool
+		hasLower   bool
+		hasNumber  bool
+		hasSpecial bool
+	)
+
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsNumber(char):
+			hasNumber = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char):
+			hasSpecial = true
+		}
+	}
+
+	if !hasUpper || !hasLower || !hasNumber || !hasSpecial {
+		return ErrPasswordTooSimple
+	}
+
+	return nil
+}
+
+func validateStrength(password string, userInputs []string) error {
+	score, feedback, err := EstimateStrength(password, userInputs)
+	if err != nil {
+		return err
+	}
+	if score < minAcceptableScore {
+		return &WeakPasswordError{Score: score, Feedback: feedback}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package validator
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrPasswordReused is returned when a candidate password matches one of a
+// user's previous bcrypt hashes.
+var ErrPasswordReused = errors.New("password has been used recently and cannot be reused")
+
+// ValidatePasswordAgainstHistory checks a new plaintext password against a
+// set of previously used bcrypt hashes, most recent first. It returns
+// ErrPasswordReused if any of them match.
+func ValidatePasswordAgainstHistory(password string, previousHashes []string) error {
+	for _, hash := range previousHashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
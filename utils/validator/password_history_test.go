@@ -0,0 +1,34 @@
+package validator
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func hashFor(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to generate test hash: %v", err)
+	}
+	return string(hash)
+}
+
+func TestValidatePasswordAgainstHistory(t *testing.T) {
+	history := []string{hashFor(t, "OldPass1!"), hashFor(t, "OlderPass2!")}
+
+	if err := ValidatePasswordAgainstHistory("OldPass1!", history); err != ErrPasswordReused {
+		t.Errorf("expected ErrPasswordReused for a reused password, got %v", err)
+	}
+
+	if err := ValidatePasswordAgainstHistory("BrandNewPass3!", history); err != nil {
+		t.Errorf("expected no error for a new password, got %v", err)
+	}
+}
+
+func TestValidatePasswordAgainstHistoryEmpty(t *testing.T) {
+	if err := ValidatePasswordAgainstHistory("AnyPass1!", nil); err != nil {
+		t.Errorf("expected no error with empty history, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"valid complex password", "Str0ng!Passw0rd", nil},
+		{"too short", "Sh0rt!", ErrPasswordTooShort},
+		{"missing uppercase", "weak1!weak", ErrPasswordTooSimple},
+		{"missing special char", "WeakPass1", ErrPasswordTooSimple},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePassword(tt.input)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("ValidatePassword(%q) = %v, want nil", tt.input, err)
+			}
+			if tt.wantErr != nil && err != tt.wantErr {
+				t.Errorf("ValidatePassword(%q) = %v, want %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePasswordCommon(t *testing.T) {
+	if err := ValidatePassword("password123"); !errors.Is(err, ErrPasswordCommon) {
+		t.Errorf("expected ErrPasswordCommon, got %v", err)
+	}
+}
+
+func TestValidatePasswordRejectsCommonPasswordWithCosmeticVariation(t *testing.T) {
+	// A real strength estimator should see past capitalization and a
+	// trailing symbol - this is still just "password123" underneath.
+	err := ValidatePassword("Password123!")
+	if !errors.Is(err, ErrPasswordCommon) {
+		t.Errorf("ValidatePassword(%q) = %v, want ErrPasswordCommon", "Password123!", err)
+	}
+	var weakErr *WeakPasswordError
+	if !errors.As(err, &weakErr) {
+		t.Fatalf("expected a *WeakPasswordError, got %T", err)
+	}
+	if len(weakErr.Feedback) == 0 {
+		t.Errorf("expected feedback explaining why the password is weak")
+	}
+}
+
+func TestValidatePasswordForUserRejectsPersonalInfo(t *testing.T) {
+	err := ValidatePasswordForUser("John1!John1!", []string{"john@example.com", "John", "Doe"})
+	if !errors.Is(err, ErrPasswordCommon) {
+		t.Errorf("ValidatePasswordForUser with personal info = %v, want ErrPasswordCommon", err)
+	}
+}
+
+func TestEstimateStrengthScoresRandomPasswordHigh(t *testing.T) {
+	score, feedback, err := EstimateStrength("xK9$mQ2#vL7&wP4!", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if score < minAcceptableScore {
+		t.Errorf("expected a high-entropy password to score >= %d, got %d (feedback: %v)", minAcceptableScore, score, feedback)
+	}
+}
+
+func TestEstimateStrengthFlagsKeyboardPattern(t *testing.T) {
+	score, feedback, err := EstimateStrength("qwertyuiop", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if score >= minAcceptableScore {
+		t.Errorf("expected a keyboard-walk password to score low, got %d", score)
+	}
+	if len(feedback) == 0 {
+		t.Errorf("expected feedback for a weak password")
+	}
+}
+
+func TestEstimateStrengthFlagsSequenceAndRepeat(t *testing.T) {
+	for _, pw := range []string{"abcdefgh", "aaaaaaaa", "12345678"} {
+		score, _, err := EstimateStrength(pw, nil)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", pw, err)
+		}
+		if score >= minAcceptableScore {
+			t.Errorf("expected %q to score low, got %d", pw, score)
+		}
+	}
+}
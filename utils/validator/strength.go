@@ -0,0 +1,353 @@
+package validator
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// commonPasswordData is a curated list of the passwords that show up most
+// often in real-world credential breaches, ordered roughly by how frequently
+// they're reused - the earlier a password appears, the fewer guesses an
+// attacker needs to try it.
+//
+//go:embed data/common_passwords.txt
+var commonPasswordData string
+
+var commonPasswordRank = buildRank(strings.Split(strings.TrimSpace(commonPasswordData), "\n"))
+
+// Score buckets, on a log10(guesses) scale. These are the same boundaries
+// zxcvbn uses: each step up represents roughly two more orders of magnitude
+// of guessing effort.
+const (
+	scoreThreshold0 = 1e3  // instantly guessed
+	scoreThreshold1 = 1e6  // guessed in seconds by an online attacker
+	scoreThreshold2 = 1e8  // guessed in hours by an offline attacker
+	scoreThreshold3 = 1e10 // guessed in months by a fast offline attacker
+)
+
+// minAcceptableScore is the lowest EstimateStrength score ValidatePassword
+// will accept.
+const minAcceptableScore = 3
+
+var keyboardRows = []string{
+	"qwertyuiop",
+	"asdfghjkl",
+	"zxcvbnm",
+	"1234567890",
+}
+
+// dateRegex matches bare four-digit years and all-numeric date-like runs
+// such as 01011990 or 19900101.
+var dateRegex = regexp.MustCompile(`(19|20)\d{2}|\d{6,8}`)
+
+// userInputSplitter breaks a user input like an email address into
+// individual guessable tokens.
+var userInputSplitter = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// match is one candidate explanation for the substring pw[start:end]:
+// "these characters are a known common password", "this is a keyboard
+// pattern", etc. guesses is how many attempts an attacker would need to
+// reach this substring specifically, assuming they tried patterns of this
+// kind before resorting to brute force.
+type match struct {
+	start, end int
+	guesses    float64
+	pattern    string
+}
+
+// EstimateStrength scores pw from 0 (guessed instantly) to 4 (not guessable
+// in any practical amount of time). It finds every common-pattern match in
+// the password - dictionary words, keyboard walks, sequences, repeats,
+// dates - and any substring not covered by a match is charged at brute-force
+// cost. The minimum-guesses way of explaining the whole string (found by
+// dynamic programming over the match graph, the same approach zxcvbn uses)
+// is converted to a score via scoreThreshold0-3.
+//
+// userInputs should include context the password shouldn't reuse, such as
+// the account's email address and name - "john@x.com" / "John1!" is
+// trivially guessable even though it passes a naive complexity check.
+func EstimateStrength(pw string, userInputs []string) (score int, feedback []string, err error) {
+	if pw == "" {
+		return 0, []string{"password must not be empty"}, nil
+	}
+
+	matches := findMatches(pw, userInputs)
+	guesses, used := minGuesses(pw, matches)
+	score = scoreFromGuesses(guesses)
+	feedback = buildFeedback(score, used)
+	return score, feedback, nil
+}
+
+func scoreFromGuesses(guesses float64) int {
+	switch {
+	case guesses < scoreThreshold0:
+		return 0
+	case guesses < scoreThreshold1:
+		return 1
+	case guesses < scoreThreshold2:
+		return 2
+	case guesses < scoreThreshold3:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func findMatches(pw string, userInputs []string) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatches(pw, commonPasswordRank)...)
+	if personal := buildRank(tokenizeUserInputs(userInputs)); len(personal) > 0 {
+		matches = append(matches, dictionaryMatches(pw, personal)...)
+	}
+	matches = append(matches, keyboardMatches(pw)...)
+	matches = append(matches, sequenceMatches(pw)...)
+	matches = append(matches, repeatMatches(pw)...)
+	matches = append(matches, dateMatches(pw)...)
+	return matches
+}
+
+// buildRank assigns each distinct, non-empty word a rank equal to its
+// position in the list (1-indexed), so earlier entries are cheaper guesses.
+func buildRank(words []string) map[string]int {
+	rank := make(map[string]int)
+	i := 0
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w == "" {
+			continue
+		}
+		if _, exists := rank[w]; exists {
+			continue
+		}
+		i++
+		rank[w] = i
+	}
+	return rank
+}
+
+// tokenizeUserInputs splits each user input on non-alphanumeric characters
+// so "john@x.com" contributes "john", "x" and "com" as individual guesses,
+// the same way an attacker would try a user's name and email fragments.
+func tokenizeUserInputs(userInputs []string) []string {
+	var tokens []string
+	for _, input := range userInputs {
+		for _, token := range userInputSplitter.Split(input, -1) {
+			if len(token) >= 3 {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// dictionaryMatches finds every substring of pw that exactly matches a word
+// in rank, charging its rank as the guess count.
+func dictionaryMatches(pw string, rank map[string]int) []match {
+	if len(rank) == 0 {
+		return nil
+	}
+	lower := strings.ToLower(pw)
+	var matches []match
+	for start := 0; start < len(lower); start++ {
+		for end := start + 3; end <= len(lower); end++ {
+			if r, ok := rank[lower[start:end]]; ok {
+				matches = append(matches, match{start, end, float64(r), "a common or personal word"})
+			}
+		}
+	}
+	return matches
+}
+
+// keyboardMatches finds runs of at least 4 characters that walk along a
+// keyboard row, forwards or backwards (e.g. "qwerty", "uiop", "1234").
+func keyboardMatches(pw string) []match {
+	lower := strings.ToLower(pw)
+	var matches []match
+	for _, row := range keyboardRows {
+		for _, candidate := range []string{row, reverseString(row)} {
+			for length := len(candidate); length >= 4; length-- {
+				for start := 0; start+length <= len(candidate); start++ {
+					walk := candidate[start : start+length]
+					if idx := strings.Index(lower, walk); idx >= 0 {
+						matches = append(matches, match{idx, idx + length, 10 * float64(length), "a keyboard pattern"})
+					}
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// sequenceMatches finds runs of at least 3 characters that increase or
+// decrease by exactly one code point per step, e.g. "abcd", "4321".
+func sequenceMatches(pw string) []match {
+	var matches []match
+	n := len(pw)
+	start := 0
+	for start < n-1 {
+		step := int(pw[start+1]) - int(pw[start])
+		if step != 1 && step != -1 {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < n-1 && int(pw[end+1])-int(pw[end]) == step {
+			end++
+		}
+		length := end - start + 1
+		if length >= 3 {
+			matches = append(matches, match{start, end + 1, 4 * float64(length), "a sequential pattern"})
+		}
+		start = end
+	}
+	return matches
+}
+
+// repeatMatches finds a single character repeated at least 3 times in a
+// row, e.g. "aaaa".
+func repeatMatches(pw string) []match {
+	var matches []match
+	n := len(pw)
+	start := 0
+	for start < n {
+		end := start + 1
+		for end < n && pw[end] == pw[start] {
+			end++
+		}
+		length := end - start
+		if length >= 3 {
+			matches = append(matches, match{start, end, 4 * float64(length), "a repeated character"})
+		}
+		start = end
+	}
+	return matches
+}
+
+// dateMatches finds bare years and numeric runs that look like a birthday
+// or anniversary - cheap to guess because they cluster in a narrow range.
+func dateMatches(pw string) []match {
+	var matches []match
+	for _, loc := range dateRegex.FindAllStringIndex(pw, -1) {
+		start, end := loc[0], loc[1]
+		guesses := 119.0 // ~ distinct years an attacker would try first
+		if end-start > 4 {
+			guesses = 36500 // ~ a century of daily dates
+		}
+		matches = append(matches, match{start, end, guesses, "a date"})
+	}
+	return matches
+}
+
+func reverseString(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// bruteforceCardinality estimates the size of the character set an attacker
+// would need to brute force, based on which classes actually appear in pw.
+func bruteforceCardinality(pw string) float64 {
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, c := range pw {
+		switch {
+		case c >= 'a' && c <= 'z':
+			hasLower = true
+		case c >= 'A' && c <= 'Z':
+			hasUpper = true
+		case c >= '0' && c <= '9':
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+	var cardinality float64
+	if hasLower {
+		cardinality += 26
+	}
+	if hasUpper {
+		cardinality += 26
+	}
+	if hasDigit {
+		cardinality += 10
+	}
+	if hasSymbol {
+		cardinality += 33
+	}
+	if cardinality == 0 {
+		cardinality = 1
+	}
+	return cardinality
+}
+
+// minGuesses finds the cheapest way to explain the whole password as a
+// sequence of matches (falling back to one brute-forced character at a
+// time) via dynamic programming, and returns the total guess count along
+// with the non-bruteforce matches used to reach it.
+func minGuesses(pw string, matches []match) (float64, []match) {
+	n := len(pw)
+	cardinality := bruteforceCardinality(pw)
+
+	dp := make([]float64, n+1)
+	via := make([]*match, n+1)
+	dp[0] = 1
+
+	byEnd := make(map[int][]match, n)
+	for _, m := range matches {
+		byEnd[m.end] = append(byEnd[m.end], m)
+	}
+
+	for i := 1; i <= n; i++ {
+		best := dp[i-1] * cardinality
+		var bestMatch *match
+		for _, m := range byEnd[i] {
+			candidate := dp[m.start] * m.guesses
+			if candidate < best {
+				best = candidate
+				mCopy := m
+				bestMatch = &mCopy
+			}
+		}
+		dp[i] = best
+		via[i] = bestMatch
+	}
+
+	var used []match
+	for i := n; i > 0; {
+		if via[i] != nil {
+			used = append(used, *via[i])
+			i = via[i].start
+		} else {
+			i--
+		}
+	}
+	for i, j := 0, len(used)-1; i < j; i, j = i+1, j-1 {
+		used[i], used[j] = used[j], used[i]
+	}
+
+	return dp[n], used
+}
+
+func buildFeedback(score int, used []match) []string {
+	if score >= minAcceptableScore {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var feedback []string
+	for _, m := range used {
+		if seen[m.pattern] {
+			continue
+		}
+		seen[m.pattern] = true
+		feedback = append(feedback, fmt.Sprintf("password contains %s", m.pattern))
+	}
+	if len(feedback) == 0 {
+		feedback = append(feedback, "password is too short or predictable")
+	}
+	feedback = append(feedback, "add more unrelated words or characters to make it harder to guess")
+	return feedback
+}